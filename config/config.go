@@ -4,18 +4,55 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"personaltask/utils"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// 构建信息，发布构建时通过 -ldflags 注入，例如：
+// go build -ldflags "-X personaltask/config.Version=1.2.0 -X personaltask/config.Commit=$(git rev-parse --short HEAD) -X personaltask/config.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   = "dev"
+	Commit    = "dev"
+	BuildTime = "dev"
+)
+
+// JWT密钥默认值及最小长度，生产环境禁止使用默认值
+const (
+	defaultJWTSecret   = "your-super-secret-key"
+	minJWTSecretLength = 32
+)
+
 type Config struct {
-	Environment string
-	ServerPort  string
-	Database    DatabaseConfig
-	JWT         JWTConfig
+	Environment   string
+	ServerPort    string
+	EnableAPIDocs bool
+	Server        ServerConfig
+	Database      DatabaseConfig
+	JWT           JWTConfig
+	Login         LoginConfig
+	Security      SecurityConfig
+	Password      PasswordConfig
+	Upload        UploadConfig
+	RateLimit     RateLimitConfig
+	Pagination    PaginationConfig
+	Task          TaskConfig
+	Purge         PurgeConfig
+	Response      ResponseConfig
+}
+
+type ServerConfig struct {
+	ReadTimeout    time.Duration // 读取完整请求（含请求体）的超时
+	WriteTimeout   time.Duration // 写出响应的超时
+	IdleTimeout    time.Duration // keep-alive 空闲连接超时
+	TrustedProxies []string      // 可信代理的IP或CIDR，仅信任这些来源的 X-Forwarded-For；为空时不信任任何代理
 }
 
 type DatabaseConfig struct {
@@ -31,6 +68,67 @@ type JWTConfig struct {
 	ExpiresIn int // 小时
 }
 
+type LoginConfig struct {
+	MaxAttempts  int           // 窗口期内允许的连续失败次数，0表示不限制
+	Window       time.Duration // 失败计数窗口
+	LockDuration time.Duration // 锁定时长
+	ResetTTL     time.Duration // 密码重置令牌有效期
+}
+
+type SecurityConfig struct {
+	EnableCSP             bool
+	ContentSecurityPolicy string
+	EnableHSTS            bool // 仅在生产环境且通过TLS访问时生效
+	HSTSMaxAge            int  // 秒
+	BcryptCost            int  // 密码哈希成本，需在 bcrypt.MinCost 与 bcrypt.MaxCost 之间
+	HideResourceExistence bool // 资源不存在时也返回403，避免通过ID枚举资源
+}
+
+type PasswordConfig struct {
+	MinLength      int  // 密码最小长度
+	RequireDigit   bool // 必须包含数字
+	RequireUpper   bool // 必须包含大写字母
+	RequireSpecial bool // 必须包含特殊字符
+}
+
+type UploadConfig struct {
+	Dir          string   // 附件存储目录
+	MaxSize      int64    // 单个文件大小上限（字节）
+	AllowedTypes []string // 允许的内容类型
+}
+
+type RateLimitConfig struct {
+	IPLimit   int           // 未认证请求按IP计数的窗口配额，0表示不限制
+	UserLimit int           // 已认证请求按用户计数的窗口配额，0表示不限制
+	Window    time.Duration // 计数窗口
+	// 不计入限流的路由（按匹配到的路由模板精确比较，如 /api/tasks/:id），默认为空
+	// 只对经过限流中间件的 /api/auth 和需认证的 /api 路由有效；健康检查端点本身不限流，无需列出
+	ExemptPaths []string
+}
+
+type PurgeConfig struct {
+	Enabled   bool          // 是否定期彻底删除软删除的任务
+	Retention time.Duration // 软删除后保留的时长，超过后彻底删除
+	Interval  time.Duration // 清理任务的执行间隔
+}
+
+type ResponseConfig struct {
+	Envelope bool // 成功响应默认是否使用 {code, message, data, timestamp} 包装，请求可用 raw 参数覆盖
+}
+
+type PaginationConfig struct {
+	DefaultPageSize int // 未指定 page_size 时的每页条数
+	MaxPageSize     int // 每页条数上限，超出时按上限返回
+}
+
+type TaskConfig struct {
+	DefaultPriority string   // 创建任务未指定优先级时使用
+	Statuses        []string // 允许的任务状态，第一个为初始状态
+	CompletedStatus string   // 表示已完成的状态，必须在 Statuses 中
+	Transitions     []string // 允许的状态流转，每项为 "from>to"；为空时不限制
+	MaxPerUser      int      // 每个用户的任务数量上限（不含已删除任务），0表示不限制
+}
+
 func Load() *Config {
 	// 加载.env文件
 	if err := godotenv.Load(); err != nil {
@@ -38,9 +136,18 @@ func Load() *Config {
 		log.Println("将使用系统环境变量或默认值")
 	}
 
-	return &Config{
-		Environment: getEnv("ENVIRONMENT", "development"),
-		ServerPort:  getEnv("SERVER_PORT", "8080"),
+	environment := getEnv("ENVIRONMENT", "development")
+
+	cfg := &Config{
+		Environment:   environment,
+		ServerPort:    getEnv("SERVER_PORT", "8080"),
+		EnableAPIDocs: getEnvBool("ENABLE_API_DOCS", environment != "production"),
+		Server: ServerConfig{
+			ReadTimeout:    time.Duration(getEnvInt("READ_TIMEOUT", 15)) * time.Second,
+			WriteTimeout:   time.Duration(getEnvInt("WRITE_TIMEOUT", 30)) * time.Second,
+			IdleTimeout:    time.Duration(getEnvInt("IDLE_TIMEOUT", 60)) * time.Second,
+			TrustedProxies: getEnvList("TRUSTED_PROXIES", nil),
+		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
 			Port:     getEnv("DB_PORT", "3306"),
@@ -49,14 +156,86 @@ func Load() *Config {
 			DBName:   getEnv("DB_NAME", "personaltask"),
 		},
 		JWT: JWTConfig{
-			SecretKey: getEnv("JWT_SECRET", "your-super-secret-key"),
+			SecretKey: getEnv("JWT_SECRET", defaultJWTSecret),
 			ExpiresIn: 24, // 24小时
 		},
+		Login: LoginConfig{
+			MaxAttempts:  getEnvInt("LOGIN_MAX_ATTEMPTS", 5),
+			Window:       time.Duration(getEnvInt("LOGIN_ATTEMPT_WINDOW_MINUTES", 15)) * time.Minute,
+			LockDuration: time.Duration(getEnvInt("LOGIN_LOCK_MINUTES", 15)) * time.Minute,
+			ResetTTL:     time.Duration(getEnvInt("PASSWORD_RESET_TTL_MINUTES", 30)) * time.Minute,
+		},
+		Security: SecurityConfig{
+			EnableCSP:             getEnvBool("SECURITY_ENABLE_CSP", true),
+			ContentSecurityPolicy: getEnv("SECURITY_CSP", "default-src 'none'; frame-ancestors 'none'"),
+			EnableHSTS:            getEnvBool("SECURITY_ENABLE_HSTS", true),
+			HSTSMaxAge:            getEnvInt("SECURITY_HSTS_MAX_AGE", 31536000),
+			BcryptCost:            getBcryptCost("BCRYPT_COST", bcrypt.DefaultCost),
+			HideResourceExistence: getEnvBool("SECURITY_HIDE_RESOURCE_EXISTENCE", false),
+		},
+		Password: PasswordConfig{
+			MinLength:      getEnvInt("PASSWORD_MIN_LENGTH", 6),
+			RequireDigit:   getEnvBool("PASSWORD_REQUIRE_DIGIT", false),
+			RequireUpper:   getEnvBool("PASSWORD_REQUIRE_UPPER", false),
+			RequireSpecial: getEnvBool("PASSWORD_REQUIRE_SPECIAL", false),
+		},
+		Upload: UploadConfig{
+			Dir:          getEnv("UPLOAD_DIR", "./uploads"),
+			MaxSize:      int64(getEnvInt("UPLOAD_MAX_SIZE_MB", 10)) << 20,
+			AllowedTypes: getEnvList("UPLOAD_ALLOWED_TYPES", []string{"image/png", "image/jpeg", "image/gif", "application/pdf", "text/plain"}),
+		},
+		RateLimit: RateLimitConfig{
+			IPLimit:     getEnvInt("RATE_LIMIT_IP", 60),
+			UserLimit:   getEnvInt("RATE_LIMIT_USER", 300),
+			Window:      time.Duration(getEnvInt("RATE_LIMIT_WINDOW_SECONDS", 60)) * time.Second,
+			ExemptPaths: getEnvList("RATE_LIMIT_EXEMPT_PATHS", nil),
+		},
+		Pagination: PaginationConfig{
+			DefaultPageSize: getEnvInt("DEFAULT_PAGE_SIZE", 10),
+			MaxPageSize:     getEnvInt("MAX_PAGE_SIZE", 100),
+		},
+		Task: TaskConfig{
+			DefaultPriority: getDefaultTaskPriority("DEFAULT_TASK_PRIORITY", "medium"),
+			MaxPerUser:      getEnvInt("TASK_MAX_PER_USER", 0),
+		},
+		Purge: PurgeConfig{
+			Enabled:   getEnvBool("TASK_PURGE_ENABLED", false),
+			Retention: time.Duration(getEnvInt("TASK_PURGE_RETENTION_DAYS", 30)) * 24 * time.Hour,
+			Interval:  24 * time.Hour,
+		},
+		Response: ResponseConfig{
+			Envelope: getEnvBool("RESPONSE_ENVELOPE", true),
+		},
 	}
+	cfg.Task.Statuses, cfg.Task.CompletedStatus = getTaskStatuses("TASK_STATUSES", "TASK_COMPLETED_STATUS")
+	cfg.Task.Transitions = getTaskTransitions("TASK_TRANSITIONS", cfg.Task.Statuses)
+
+	// 生产环境拒绝使用不安全的JWT密钥，其他环境仅警告
+	if err := validateJWTSecret(cfg.JWT.SecretKey); err != nil {
+		if environment == "production" {
+			log.Fatal("JWT密钥配置不安全: ", err)
+		}
+		log.Printf("警告: JWT密钥配置不安全（%v），切勿在生产环境中使用", err)
+	}
+
+	return cfg
+}
+
+// 校验JWT密钥：不能为空、不能为默认值且长度不少于 minJWTSecretLength
+func validateJWTSecret(secret string) error {
+	switch {
+	case secret == "":
+		return fmt.Errorf("JWT_SECRET 未设置")
+	case secret == defaultJWTSecret:
+		return fmt.Errorf("JWT_SECRET 使用了默认值")
+	case len(secret) < minJWTSecretLength:
+		return fmt.Errorf("JWT_SECRET 长度不能少于%d个字符", minJWTSecretLength)
+	}
+	return nil
 }
 
 func InitDB(cfg *Config) *gorm.DB {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=UTC",
 		cfg.Database.User,
 		cfg.Database.Password,
 		cfg.Database.Host,
@@ -77,8 +256,10 @@ func InitDB(cfg *Config) *gorm.DB {
 		logLevel = logger.Info
 	}
 
+	// 时间统一以UTC存储，按用户时区的换算在统计时进行
 	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logLevel),
+		Logger:  logger.Default.LogMode(logLevel),
+		NowFunc: func() time.Time { return time.Now().UTC() },
 	})
 	if err != nil {
 		log.Fatal("数据库连接失败:", err)
@@ -93,4 +274,79 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+		log.Printf("警告: 环境变量 %s 的值 %q 不是有效整数，使用默认值 %d", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+func getBcryptCost(key string, defaultValue int) int {
+	cost := getEnvInt(key, defaultValue)
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		log.Printf("警告: 环境变量 %s 的值 %d 超出允许范围 %d-%d，使用默认值 %d", key, cost, bcrypt.MinCost, bcrypt.MaxCost, defaultValue)
+		return defaultValue
+	}
+	return cost
+}
+
+func getDefaultTaskPriority(key, defaultValue string) string {
+	priority := getEnv(key, defaultValue)
+	if !utils.IsValidTaskPriority(priority) {
+		log.Printf("警告: 环境变量 %s 的值 %q 不是有效的任务优先级，使用默认值 %s", key, priority, defaultValue)
+		return defaultValue
+	}
+	return priority
+}
+
+func getTaskStatuses(statusesKey, completedKey string) ([]string, string) {
+	defaultStatuses := utils.TaskStatuses()
+	defaultCompleted := utils.CompletedTaskStatus()
+
+	statuses := getEnvList(statusesKey, defaultStatuses)
+	completed := getEnv(completedKey, defaultCompleted)
+	if err := utils.ValidateTaskStatuses(statuses, completed); err != nil {
+		log.Printf("警告: 环境变量 %s/%s 配置无效（%v），使用默认任务状态 %s", statusesKey, completedKey, err, strings.Join(defaultStatuses, ","))
+		return defaultStatuses, defaultCompleted
+	}
+	return statuses, completed
+}
+
+func getTaskTransitions(key string, statuses []string) []string {
+	entries := getEnvList(key, nil)
+	if _, err := utils.ParseTaskTransitions(entries, statuses); err != nil {
+		log.Printf("警告: 环境变量 %s 配置无效（%v），不限制任务状态流转", key, err)
+		return nil
+	}
+	return entries
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+		log.Printf("警告: 环境变量 %s 的值 %q 不是有效布尔值，使用默认值 %t", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
\ No newline at end of file
@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"personaltask/models"
+	"personaltask/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 导出文档的格式版本，导入时据此判断是否兼容
+const accountExportVersion = 1
+
+// 导出时每批读取的记录数，避免大账号一次加载全部任务
+const exportBatchSize = 500
+
+// 导出当前用户的全部数据（个人资料、分类、项目、任务及评论），不包含密码哈希
+// 默认以JSON文档流式输出，可直接用于导入；format=zip 时输出按类型拆分的CSV压缩包
+func (ac *AuthController) ExportAccount(c *gin.Context) {
+	user, ok := utils.GetCurrentUser(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "用户未登录", nil)
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "format 参数无效，应为 json 或 zip", nil)
+		return
+	}
+
+	// 分类和项目数量有限，开始输出前一次性加载，出错时仍可返回错误响应
+	var categories []models.Category
+	if err := ac.DB.WithContext(c).Where("user_id = ?", user.ID).Order("id asc").Find(&categories).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询分类失败", err)
+		return
+	}
+	var projects []models.Project
+	if err := ac.DB.WithContext(c).Where("user_id = ?", user.ID).Order("id asc").Find(&projects).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询项目失败", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	filename := "personaltask-export-" + now.Format("20060102-150405")
+
+	var err error
+	if format == "zip" {
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+		c.Status(http.StatusOK)
+		err = ac.writeExportZip(c.Writer, user, categories, projects)
+	} else {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+		c.Status(http.StatusOK)
+		err = ac.writeExportJSON(c.Writer, now, user, categories, projects)
+	}
+
+	// 响应头已发送，只能记录日志并中断输出
+	if err != nil {
+		log.Printf("导出用户 %d 的数据失败: %v", user.ID, err)
+		c.Abort()
+	}
+}
+
+// 以JSON文档流式输出导出数据，任务按批读取并逐条写出
+func (ac *AuthController) writeExportJSON(w gin.ResponseWriter, now time.Time, user models.User, categories []models.Category, projects []models.Project) error {
+	enc := json.NewEncoder(w)
+	write := func(s string) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+
+	if err := write(`{"version":` + strconv.Itoa(accountExportVersion) + `,"exported_at":`); err != nil {
+		return err
+	}
+	if err := enc.Encode(now); err != nil {
+		return err
+	}
+	for _, section := range []struct {
+		key  string
+		data interface{}
+	}{
+		{"user", user},
+		{"categories", categories},
+		{"projects", projects},
+	} {
+		if err := write(`,"` + section.key + `":`); err != nil {
+			return err
+		}
+		if err := enc.Encode(section.data); err != nil {
+			return err
+		}
+	}
+
+	if err := write(`,"tasks":[`); err != nil {
+		return err
+	}
+	var taskCount int
+	var batch []models.Task
+	err := ac.DB.Preload("Comments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id asc")
+	}).Where("user_id = ?", user.ID).FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, task := range batch {
+			if taskCount > 0 {
+				if err := write(","); err != nil {
+					return err
+				}
+			}
+			if err := enc.Encode(task); err != nil {
+				return err
+			}
+			taskCount++
+		}
+		w.Flush()
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	return write(fmt.Sprintf(`],"counts":{"categories":%d,"projects":%d,"tasks":%d}}`, len(categories), len(projects), taskCount))
+}
+
+// 以ZIP压缩包输出导出数据，每类数据一个CSV文件
+func (ac *AuthController) writeExportZip(w gin.ResponseWriter, user models.User, categories []models.Category, projects []models.Project) error {
+	zw := zip.NewWriter(w)
+
+	if err := writeExportCSV(zw, "user.csv", []string{"id", "username", "email", "created_at"}, func(cw *csv.Writer) error {
+		email := ""
+		if user.Email != nil {
+			email = *user.Email
+		}
+		return cw.Write([]string{formatExportID(user.ID), user.Username, email, formatExportTime(&user.CreatedAt)})
+	}); err != nil {
+		return err
+	}
+
+	if err := writeExportCSV(zw, "categories.csv", []string{"id", "name", "description", "color", "created_at"}, func(cw *csv.Writer) error {
+		for _, category := range categories {
+			if err := cw.Write([]string{formatExportID(category.ID), category.Name, category.Description, category.Color, formatExportTime(&category.CreatedAt)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := writeExportCSV(zw, "projects.csv", []string{"id", "name", "description", "status", "start_date", "end_date", "created_at"}, func(cw *csv.Writer) error {
+		for _, project := range projects {
+			if err := cw.Write([]string{formatExportID(project.ID), project.Name, project.Description, project.Status,
+				formatExportTime(project.StartDate), formatExportTime(project.EndDate), formatExportTime(&project.CreatedAt)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	taskHeader := []string{"id", "title", "description", "status", "priority", "start_date", "due_date", "completed_at",
+		"category_id", "project_id", "position", "archived", "created_at"}
+	if err := writeExportCSV(zw, "tasks.csv", taskHeader, func(cw *csv.Writer) error {
+		var batch []models.Task
+		return ac.DB.Where("user_id = ?", user.ID).FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, task := range batch {
+				if err := cw.Write([]string{formatExportID(task.ID), task.Title, task.Description, task.Status, task.Priority,
+					formatExportTime(task.StartDate), formatExportTime(task.DueDate), formatExportTime(task.CompletedAt),
+					formatExportOptionalID(task.CategoryID), formatExportOptionalID(task.ProjectID),
+					strconv.Itoa(task.Position), strconv.FormatBool(task.Archived), formatExportTime(&task.CreatedAt)}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	}); err != nil {
+		return err
+	}
+
+	if err := writeExportCSV(zw, "comments.csv", []string{"id", "task_id", "user_id", "body", "created_at"}, func(cw *csv.Writer) error {
+		var batch []models.Comment
+		return ac.DB.Where("task_id IN (?)", ac.DB.Model(&models.Task{}).Select("id").Where("user_id = ?", user.ID)).
+			FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+				for _, comment := range batch {
+					if err := cw.Write([]string{formatExportID(comment.ID), formatExportID(comment.TaskID), formatExportID(comment.UserID),
+						comment.Body, formatExportTime(&comment.CreatedAt)}); err != nil {
+						return err
+					}
+				}
+				return nil
+			}).Error
+	}); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// 在压缩包中写入一个CSV文件，rows 负责写入表头之后的数据行
+func writeExportCSV(zw *zip.Writer, name string, header []string, rows func(*csv.Writer) error) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := rows(cw); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatExportID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func formatExportOptionalID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return formatExportID(*id)
+}
+
+func formatExportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
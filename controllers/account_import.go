@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"personaltask/models"
+	"personaltask/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 导入文档，与 GET /api/auth/export 输出的JSON格式一致（个人资料和统计字段会被忽略）
+type accountImportDocument struct {
+	Version    int               `json:"version"`
+	Categories []models.Category `json:"categories"`
+	Projects   []models.Project  `json:"projects"`
+	Tasks      []models.Task     `json:"tasks"`
+}
+
+// 从导出文件恢复数据到当前账号，在同一事务中重建分类、项目、任务及评论，并重新映射它们之间的关联ID
+// mode=merge（默认）保留现有数据，同名分类和项目直接复用；mode=replace 先删除当前账号的分类、项目和任务
+// 单条数据不合法时跳过并在结果中说明原因，数据库错误则整体回滚
+func (ac *AuthController) ImportAccount(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+
+	mode := c.DefaultQuery("mode", "merge")
+	if mode != "merge" && mode != "replace" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "mode 参数无效，应为 merge 或 replace", nil)
+		return
+	}
+
+	var doc accountImportDocument
+	if err := c.ShouldBindJSON(&doc); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+	if doc.Version != accountExportVersion {
+		utils.ErrorResponse(c, http.StatusBadRequest, "导入文件版本不兼容", nil)
+		return
+	}
+
+	categoryResult := utils.NewBulkResult()
+	projectResult := utils.NewBulkResult()
+	taskResult := utils.NewBulkResult()
+
+	err := ac.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if mode == "replace" {
+			if err := clearAccountData(tx, userID); err != nil {
+				return err
+			}
+		}
+
+		categoryIDs, err := importCategories(tx, userID, doc.Categories, categoryResult)
+		if err != nil {
+			return err
+		}
+		projectIDs, err := importProjects(tx, userID, doc.Projects, projectResult)
+		if err != nil {
+			return err
+		}
+
+		// 按文件中的任务数检查配额（replace 模式下已删除的任务不计入）
+		if err := checkTaskQuota(tx, userID, ac.Config.Task.MaxPerUser, len(doc.Tasks)); err != nil {
+			return err
+		}
+		position, err := nextTaskPosition(tx, userID)
+		if err != nil {
+			return err
+		}
+		for i, item := range doc.Tasks {
+			title := strings.TrimSpace(item.Title)
+			if title == "" || len(title) > 200 {
+				taskResult.Fail(i, "任务标题为空或过长")
+				continue
+			}
+
+			task := models.Task{
+				Title:            title,
+				Description:      item.Description,
+				Status:           item.Status,
+				Priority:         item.Priority,
+				StartDate:        item.StartDate,
+				DueDate:          item.DueDate,
+				CompletedAt:      item.CompletedAt,
+				UserID:           userID,
+				Archived:         item.Archived,
+				ArchivedAt:       item.ArchivedAt,
+				Flagged:          item.Flagged,
+				Color:            item.Color,
+				EstimatedMinutes: item.EstimatedMinutes,
+				CreatedAt:        item.CreatedAt,
+				Position:         position + item.Position, // 导入的任务排在现有任务之后，并保持原有相对顺序
+			}
+			if !utils.IsValidTaskStatus(task.Status) {
+				task.Status = utils.InitialTaskStatus()
+			}
+			if !utils.IsValidTaskPriority(task.Priority) {
+				task.Priority = ac.Config.Task.DefaultPriority
+			}
+			if len(task.Color) > 7 {
+				task.Color = ""
+			}
+			// 关联的分类或项目未导入时，任务保留但不再关联
+			if item.CategoryID != nil {
+				if id, ok := categoryIDs[*item.CategoryID]; ok {
+					task.CategoryID = &id
+				}
+			}
+			if item.ProjectID != nil {
+				if id, ok := projectIDs[*item.ProjectID]; ok {
+					task.ProjectID = &id
+				}
+			}
+
+			if err := tx.Create(&task).Error; err != nil {
+				return err
+			}
+
+			for _, item := range item.Comments {
+				if strings.TrimSpace(item.Body) == "" {
+					continue
+				}
+				comment := models.Comment{
+					TaskID:    task.ID,
+					UserID:    userID,
+					Body:      item.Body,
+					CreatedAt: item.CreatedAt,
+				}
+				if err := tx.Create(&comment).Error; err != nil {
+					return err
+				}
+			}
+			taskResult.Succeed()
+		}
+		return nil
+	})
+	if errors.Is(err, errTaskQuotaExceeded) {
+		taskQuotaExceededResponse(c, ac.Config.Task.MaxPerUser)
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "数据导入失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"mode":       mode,
+		"categories": categoryResult,
+		"projects":   projectResult,
+		"tasks":      taskResult,
+	})
+}
+
+// 删除当前账号的任务、项目（含成员）和分类，用于 replace 模式导入
+func clearAccountData(tx *gorm.DB, userID uint) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.Task{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_id IN (?)", tx.Model(&models.Project{}).Select("id").Where("user_id = ?", userID)).
+		Delete(&models.ProjectMember{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&models.Project{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ?", userID).Delete(&models.Category{}).Error
+}
+
+// 导入分类，返回导出文件中的分类ID到新分类ID的映射；同名分类已存在时直接复用
+func importCategories(tx *gorm.DB, userID uint, items []models.Category, result *utils.BulkResult) (map[uint]uint, error) {
+	ids := make(map[uint]uint, len(items))
+	for i, item := range items {
+		name := strings.TrimSpace(item.Name)
+		if name == "" || len(name) > 50 {
+			result.Fail(i, "分类名称为空或过长")
+			continue
+		}
+
+		var category models.Category
+		err := tx.Where("name = ? AND user_id = ?", name, userID).First(&category).Error
+		if err == gorm.ErrRecordNotFound {
+			category = models.Category{
+				Name:        name,
+				Description: item.Description,
+				Color:       item.Color,
+				UserID:      userID,
+			}
+			if len(category.Color) != 7 {
+				var usedColors []string
+				tx.Model(&models.Category{}).Where("user_id = ?", userID).Pluck("color", &usedColors)
+				category.Color = utils.NextPaletteColor(usedColors)
+			}
+			err = tx.Create(&category).Error
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		ids[item.ID] = category.ID
+		result.Succeed()
+	}
+	return ids, nil
+}
+
+// 导入项目，返回导出文件中的项目ID到新项目ID的映射；同名项目已存在时直接复用
+func importProjects(tx *gorm.DB, userID uint, items []models.Project, result *utils.BulkResult) (map[uint]uint, error) {
+	ids := make(map[uint]uint, len(items))
+	for i, item := range items {
+		name := strings.TrimSpace(item.Name)
+		if name == "" || len(name) > 100 {
+			result.Fail(i, "项目名称为空或过长")
+			continue
+		}
+
+		var project models.Project
+		err := tx.Where("name = ? AND user_id = ?", name, userID).First(&project).Error
+		if err == gorm.ErrRecordNotFound {
+			project = models.Project{
+				Name:        name,
+				Description: item.Description,
+				Status:      item.Status,
+				StartDate:   item.StartDate,
+				EndDate:     item.EndDate,
+				UserID:      userID,
+			}
+			if !utils.IsValidProjectStatus(project.Status) {
+				project.Status = "active"
+			}
+			err = tx.Create(&project).Error
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		ids[item.ID] = project.ID
+		result.Succeed()
+	}
+	return ids, nil
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"personaltask/config"
+	"personaltask/models"
+	"personaltask/testutil"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAccountExportImportRoundTrip(t *testing.T) {
+	cfg := &config.Config{Task: config.TaskConfig{DefaultPriority: "medium"}}
+	created := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	due := time.Date(2024, 3, 15, 9, 0, 0, 0, time.UTC)
+
+	// 导出：分类1、2，项目5、6；任务10关联分类2和项目6，任务11只关联分类1，任务12关联已不存在的分类99
+	source, sourceFake := testutil.NewFakeDB(t)
+	sourceFake.On("FROM `categories`", []string{"id", "name", "color", "user_id", "created_at"},
+		[]driver.Value{int64(1), "工作", "#ff0000", int64(1), created},
+		[]driver.Value{int64(2), "生活", "#00ff00", int64(1), created},
+	)
+	sourceFake.On("FROM `projects`", []string{"id", "name", "status", "user_id", "created_at"},
+		[]driver.Value{int64(5), "网站改版", "active", int64(1), created},
+		[]driver.Value{int64(6), "搬家", "completed", int64(1), created},
+	)
+	sourceFake.On("FROM `tasks`", []string{"id", "title", "status", "priority", "due_date", "user_id", "category_id", "project_id", "position", "created_at"},
+		[]driver.Value{int64(10), "打包", "pending", "high", due, int64(1), int64(2), int64(6), int64(1), created},
+		[]driver.Value{int64(11), "写周报", "completed", "low", nil, int64(1), int64(1), nil, int64(2), created},
+		[]driver.Value{int64(12), "孤儿任务", "pending", "medium", nil, int64(1), int64(99), nil, int64(3), created},
+	)
+	sourceFake.On("FROM `comments`", []string{"id", "task_id", "user_id", "body", "created_at"},
+		[]driver.Value{int64(30), int64(10), int64(1), "记得买纸箱", created},
+	)
+
+	exporter := &AuthController{DB: source, Config: cfg}
+	w := serveTest(t, exporter.ExportAccount, "GET", "/api/auth/export", nil, 1, func(c *gin.Context) {
+		c.Set("current_user", models.User{ID: 1, Username: "alice"})
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("导出 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	// 导出文档直接输出，不带统一响应包装
+	var export struct {
+		Counts map[string]int `json:"counts"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &export); err != nil {
+		t.Fatalf("解析导出文档失败: %v", err)
+	}
+	if export.Counts["categories"] != 2 || export.Counts["projects"] != 2 || export.Counts["tasks"] != 3 {
+		t.Fatalf("counts = %v", export.Counts)
+	}
+
+	// 导入到另一个空账号
+	target, targetFake := testutil.NewFakeDB(t)
+	importer := &AuthController{DB: target, Config: cfg}
+	var result struct {
+		Tasks struct {
+			Succeeded int `json:"succeeded"`
+		} `json:"tasks"`
+	}
+	decodeResponse(t, serveTest(t, importer.ImportAccount, "POST", "/api/auth/import", json.RawMessage(w.Body.Bytes()), 2), &result)
+
+	categories := targetFake.Inserted("categories")
+	projects := targetFake.Inserted("projects")
+	tasks := targetFake.Inserted("tasks")
+	if len(categories) != 2 || len(projects) != 2 || len(tasks) != 3 {
+		t.Fatalf("插入了 %d 个分类、%d 个项目、%d 个任务，want 2、2、3", len(categories), len(projects), len(tasks))
+	}
+
+	// 新ID按插入顺序从 testutil.FakeFirstInsertID 开始分配
+	newCategory := map[string]int64{"工作": testutil.FakeFirstInsertID, "生活": testutil.FakeFirstInsertID + 1}
+	newProject := map[string]int64{"网站改版": testutil.FakeFirstInsertID, "搬家": testutil.FakeFirstInsertID + 1}
+	for i, name := range []string{"工作", "生活"} {
+		if categories[i]["name"] != name || categories[i]["user_id"] != int64(2) {
+			t.Errorf("categories[%d] = %v", i, categories[i])
+		}
+	}
+	for i, name := range []string{"网站改版", "搬家"} {
+		if projects[i]["name"] != name || projects[i]["user_id"] != int64(2) {
+			t.Errorf("projects[%d] = %v", i, projects[i])
+		}
+	}
+
+	wantTasks := []struct {
+		title      string
+		categoryID driver.Value
+		projectID  driver.Value
+	}{
+		{"打包", newCategory["生活"], newProject["搬家"]},
+		{"写周报", newCategory["工作"], nil},
+		{"孤儿任务", nil, nil},
+	}
+	for i, want := range wantTasks {
+		task := tasks[i]
+		if task["title"] != want.title || task["user_id"] != int64(2) {
+			t.Errorf("tasks[%d] = %v", i, task)
+		}
+		if task["category_id"] != want.categoryID {
+			t.Errorf("tasks[%d].category_id = %v, want %v", i, task["category_id"], want.categoryID)
+		}
+		if task["project_id"] != want.projectID {
+			t.Errorf("tasks[%d].project_id = %v, want %v", i, task["project_id"], want.projectID)
+		}
+	}
+	if got, ok := tasks[0]["due_date"].(time.Time); !ok || !got.Equal(due) {
+		t.Errorf("tasks[0].due_date = %v, want %v", tasks[0]["due_date"], due)
+	}
+
+	// 评论关联到新创建的任务
+	comments := targetFake.Inserted("comments")
+	if len(comments) != 1 || comments[0]["task_id"] != int64(testutil.FakeFirstInsertID) || comments[0]["body"] != "记得买纸箱" {
+		t.Errorf("comments = %v", comments)
+	}
+	if result.Tasks.Succeeded != 3 {
+		t.Errorf("tasks.succeeded = %d, want 3", result.Tasks.Succeeded)
+	}
+}
@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"personaltask/config"
+	"personaltask/models"
+	"personaltask/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AttachmentController struct {
+	DB     *gorm.DB
+	Config *config.Config
+}
+
+func NewAttachmentController(db *gorm.DB, cfg *config.Config) *AttachmentController {
+	return &AttachmentController{
+		DB:     db,
+		Config: cfg,
+	}
+}
+
+// 获取任务附件列表
+func (ac *AttachmentController) GetAttachments(c *gin.Context) {
+	taskID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	page, pageSize, offset := utils.GetPaginationParams(c)
+
+	query := ac.DB.WithContext(c).Model(&models.Attachment{}).Where("task_id = ?", taskID)
+
+	// 获取总数
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询附件失败", err)
+		return
+	}
+
+	// 分页查询
+	attachments := []models.Attachment{}
+	if err := query.Order("created_at asc, id asc").Offset(offset).Limit(pageSize).Find(&attachments).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询附件失败", err)
+		return
+	}
+
+	utils.PaginatedResponse(c, attachments, total, page, pageSize)
+}
+
+// 上传附件
+func (ac *AttachmentController) UploadAttachment(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	taskID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	// 限制请求体大小（预留multipart头部的空间）
+	maxSize := ac.Config.Upload.MaxSize
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "文件大小超出限制", nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "缺少上传文件", err)
+		return
+	}
+
+	if fileHeader.Size > maxSize {
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "文件大小超出限制", nil)
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "读取上传文件失败", err)
+		return
+	}
+	defer file.Close()
+
+	// 根据文件内容检测类型，不信任客户端声明
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	contentType := http.DetectContentType(head[:n])
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !utils.Contains(ac.Config.Upload.AllowedTypes, mediaType) {
+		utils.ErrorResponse(c, http.StatusUnsupportedMediaType, "不支持的文件类型", mediaType)
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "读取上传文件失败", err)
+		return
+	}
+
+	// 使用随机文件名存储，避免冲突和路径穿越
+	storageName, err := randomFileName()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "附件保存失败", err)
+		return
+	}
+
+	if err := os.MkdirAll(ac.Config.Upload.Dir, 0o750); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "附件保存失败", err)
+		return
+	}
+
+	dst, err := os.OpenFile(filepath.Join(ac.Config.Upload.Dir, storageName), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "附件保存失败", err)
+		return
+	}
+	written, err := io.Copy(dst, file)
+	dst.Close()
+	if err != nil {
+		os.Remove(filepath.Join(ac.Config.Upload.Dir, storageName))
+		utils.ErrorResponse(c, http.StatusInternalServerError, "附件保存失败", err)
+		return
+	}
+
+	attachment := models.Attachment{
+		TaskID:      taskID,
+		UserID:      userID,
+		Filename:    filepath.Base(fileHeader.Filename),
+		StoragePath: storageName,
+		Size:        written,
+		ContentType: mediaType,
+	}
+
+	if err := ac.DB.WithContext(c).Create(&attachment).Error; err != nil {
+		os.Remove(filepath.Join(ac.Config.Upload.Dir, storageName))
+		utils.ErrorResponse(c, http.StatusInternalServerError, "附件创建失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, attachment)
+}
+
+// 下载附件
+func (ac *AttachmentController) DownloadAttachment(c *gin.Context) {
+	attachment, ok := ac.findAttachment(c)
+	if !ok {
+		return
+	}
+
+	path := filepath.Join(ac.Config.Upload.Dir, filepath.Base(attachment.StoragePath))
+	if _, err := os.Stat(path); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "附件文件不存在", nil)
+		return
+	}
+
+	c.Header("Content-Type", attachment.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	c.File(path)
+}
+
+// 删除附件
+func (ac *AttachmentController) DeleteAttachment(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	attachment, ok := ac.findAttachment(c)
+	if !ok {
+		return
+	}
+
+	// 只有上传者和任务创建者可以删除附件
+	if attachment.UserID != userID && c.GetString("task_role") != "owner" {
+		utils.ErrorResponse(c, http.StatusForbidden, "无权删除该附件", nil)
+		return
+	}
+
+	if err := ac.DB.WithContext(c).Delete(&attachment).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "附件删除失败", err)
+		return
+	}
+
+	// 删除磁盘文件
+	os.Remove(filepath.Join(ac.Config.Upload.Dir, filepath.Base(attachment.StoragePath)))
+
+	utils.SuccessResponse(c, gin.H{"message": "附件删除成功"})
+}
+
+// 查找属于当前任务的附件（任务的访问权限已由 TaskAccess 中间件校验）
+func (ac *AttachmentController) findAttachment(c *gin.Context) (models.Attachment, bool) {
+	var attachment models.Attachment
+
+	taskID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return attachment, false
+	}
+	attachmentID, ok := utils.ParseID(c, "attachmentId")
+	if !ok {
+		return attachment, false
+	}
+
+	if err := ac.DB.WithContext(c).Where("id = ? AND task_id = ?", attachmentID, taskID).First(&attachment).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "附件不存在", nil)
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "查询附件失败", err)
+		}
+		return attachment, false
+	}
+
+	return attachment, true
+}
+
+// 生成随机存储文件名
+func randomFileName() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("生成文件名失败: %w", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
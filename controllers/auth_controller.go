@@ -1,24 +1,36 @@
 package controllers
 
 import (
+	"math"
 	"net/http"
 	"personaltask/config"
 	"personaltask/models"
 	"personaltask/utils"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type AuthController struct {
-	DB     *gorm.DB
-	Config *config.Config
+	DB           *gorm.DB
+	Config       *config.Config
+	LoginLimiter *utils.LoginLimiter
+	Mailer       utils.Mailer
 }
 
 func NewAuthController(db *gorm.DB, cfg *config.Config) *AuthController {
 	return &AuthController{
 		DB:     db,
 		Config: cfg,
+		LoginLimiter: utils.NewLoginLimiter(
+			utils.NewMemoryLoginAttemptStore(),
+			cfg.Login.MaxAttempts,
+			cfg.Login.Window,
+			cfg.Login.LockDuration,
+		),
+		Mailer: utils.NewLogMailer(),
 	}
 }
 
@@ -26,19 +38,42 @@ func NewAuthController(db *gorm.DB, cfg *config.Config) *AuthController {
 func (ac *AuthController) Register(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "请求参数错误", err)
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	// 规范化用户名
+	req.Username = utils.NormalizeUsername(req.Username)
+	if len(req.Username) < 3 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "请求参数错误", "用户名长度不能少于3个字符")
 		return
 	}
 
 	// 检查用户名是否已存在
 	var existingUser models.User
-	if err := ac.DB.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
+	if err := ac.DB.WithContext(c).Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
 		utils.ErrorResponse(c, http.StatusConflict, "用户名已存在", nil)
 		return
 	}
 
+	// 检查邮箱是否已被使用
+	var email *string
+	if req.Email != "" {
+		normalized := utils.NormalizeEmail(req.Email)
+		if ac.emailTaken(normalized, 0) {
+			utils.ErrorResponse(c, http.StatusConflict, "邮箱已被使用", nil)
+			return
+		}
+		email = &normalized
+	}
+
+	if err := utils.ValidatePassword(req.Password); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "密码不符合要求", err)
+		return
+	}
+
 	// 加密密码
-	hashedPassword, err := utils.HashPassword(req.Password)
+	hashedPassword, err := utils.HashPassword(req.Password, ac.Config.Security.BcryptCost)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "密码加密失败", err)
 		return
@@ -48,10 +83,10 @@ func (ac *AuthController) Register(c *gin.Context) {
 	user := models.User{
 		Username: req.Username,
 		Password: hashedPassword,
-		Email:    req.Email,
+		Email:    email,
 	}
 
-	if err := ac.DB.Create(&user).Error; err != nil {
+	if err := ac.DB.WithContext(c).Create(&user).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "用户创建失败", err)
 		return
 	}
@@ -73,30 +108,47 @@ func (ac *AuthController) Register(c *gin.Context) {
 		"token": token,
 	}
 
-	utils.SuccessResponse(c, response)
+	utils.CreatedResponse(c, "/api/auth/profile", response)
 }
 
 // 用户登录
 func (ac *AuthController) Login(c *gin.Context) {
 	var req models.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "请求参数错误", err)
+		utils.BindErrorResponse(c, err)
+		return
+	}
+	req.Username = utils.NormalizeUsername(req.Username)
+
+	// 检查账户是否因多次登录失败被锁定
+	lockKey := req.Username
+	if remaining := ac.LoginLimiter.Locked(lockKey); remaining > 0 {
+		respondLoginLocked(c, remaining)
 		return
 	}
 
 	// 查找用户
 	var user models.User
-	if err := ac.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "用户名或密码错误", nil)
+	if err := ac.DB.WithContext(c).Where("username = ?", req.Username).First(&user).Error; err != nil {
+		ac.loginFailed(c, lockKey)
 		return
 	}
 
 	// 验证密码
 	if !utils.CheckPassword(req.Password, user.Password) {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "用户名或密码错误", nil)
+		ac.loginFailed(c, lockKey)
 		return
 	}
 
+	// 登录成功，清除失败记录
+	ac.LoginLimiter.Succeed(lockKey)
+
+	// 记录最近登录时间（只更新单列，不修改 updated_at）
+	now := time.Now().UTC()
+	if err := ac.DB.WithContext(c).Model(&user).UpdateColumn("last_login_at", now).Error; err == nil {
+		user.LastLoginAt = &now
+	}
+
 	// 生成JWT Token
 	token, err := utils.GenerateToken(user.ID, user.Username, ac.Config.JWT.SecretKey, ac.Config.JWT.ExpiresIn)
 	if err != nil {
@@ -117,6 +169,21 @@ func (ac *AuthController) Login(c *gin.Context) {
 	utils.SuccessResponse(c, response)
 }
 
+// 记录登录失败，达到上限时返回锁定响应
+func (ac *AuthController) loginFailed(c *gin.Context, key string) {
+	if lockDuration := ac.LoginLimiter.Fail(key); lockDuration > 0 {
+		respondLoginLocked(c, lockDuration)
+		return
+	}
+	utils.ErrorResponse(c, http.StatusUnauthorized, "用户名或密码错误", nil)
+}
+
+// 账户锁定响应
+func respondLoginLocked(c *gin.Context, remaining time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+	utils.ErrorResponse(c, http.StatusTooManyRequests, "登录失败次数过多，账户已被临时锁定，请稍后再试", nil)
+}
+
 // 获取用户信息
 func (ac *AuthController) GetProfile(c *gin.Context) {
 	user, exists := utils.GetCurrentUser(c)
@@ -125,12 +192,47 @@ func (ac *AuthController) GetProfile(c *gin.Context) {
 		return
 	}
 
+	// 任务汇总统计（单条聚合查询）
+	var taskSummary struct {
+		TotalTasks     int64
+		CompletedTasks int64
+		LastActiveAt   *time.Time
+	}
+	ac.DB.WithContext(c).Model(&models.Task{}).
+		Select("COUNT(*) AS total_tasks, COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS completed_tasks, MAX(updated_at) AS last_active_at", utils.CompletedTaskStatus()).
+		Where("user_id = ?", user.ID).
+		Scan(&taskSummary)
+
+	var totalProjects, totalCategories int64
+	ac.DB.WithContext(c).Model(&models.Project{}).Where("user_id = ?", user.ID).Count(&totalProjects)
+	ac.DB.WithContext(c).Model(&models.Category{}).Where("user_id = ?", user.ID).Count(&totalCategories)
+
+	completionRate := 0.0
+	if taskSummary.TotalTasks > 0 {
+		completionRate = float64(taskSummary.CompletedTasks) / float64(taskSummary.TotalTasks) * 100
+	}
+
 	response := gin.H{
-		"id":         user.ID,
-		"username":   user.Username,
-		"email":      user.Email,
-		"created_at": user.CreatedAt,
-		"updated_at": user.UpdatedAt,
+		"id":            user.ID,
+		"username":      user.Username,
+		"email":         user.Email,
+		"last_login_at": user.LastLoginAt,
+		"created_at":    user.CreatedAt,
+		"updated_at":    user.UpdatedAt,
+		"stats": gin.H{
+			"total_tasks":      taskSummary.TotalTasks,
+			"completed_tasks":  taskSummary.CompletedTasks,
+			"completion_rate":  completionRate,
+			"total_projects":   totalProjects,
+			"total_categories": totalCategories,
+			"account_age_days": int(time.Since(user.CreatedAt).Hours() / 24),
+			"last_active_at":   taskSummary.LastActiveAt,
+		},
+		// 任务配额，limit 为0表示不限制
+		"task_quota": gin.H{
+			"used":  taskSummary.TotalTasks,
+			"limit": ac.Config.Task.MaxPerUser,
+		},
 	}
 
 	utils.SuccessResponse(c, response)
@@ -145,20 +247,44 @@ func (ac *AuthController) UpdateProfile(c *gin.Context) {
 	}
 
 	var req struct {
-		Email string `json:"email" binding:"omitempty,email"`
+		Username string `json:"username" binding:"omitempty,min=3,max=50"`
+		Email    string `json:"email" binding:"omitempty,email"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "请求参数错误", err)
+		utils.BindErrorResponse(c, err)
 		return
 	}
 
+	// 修改用户名（与注册相同的规范化和长度要求）
+	usernameChanged := false
+	if req.Username != "" {
+		username := utils.NormalizeUsername(req.Username)
+		if len(username) < 3 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "请求参数错误", "用户名长度不能少于3个字符")
+			return
+		}
+		if username != user.Username {
+			if ac.usernameTaken(username, user.ID) {
+				utils.ErrorResponse(c, http.StatusConflict, "用户名已存在", nil)
+				return
+			}
+			user.Username = username
+			usernameChanged = true
+		}
+	}
+
 	// 更新用户信息
 	if req.Email != "" {
-		user.Email = req.Email
+		email := utils.NormalizeEmail(req.Email)
+		if ac.emailTaken(email, user.ID) {
+			utils.ErrorResponse(c, http.StatusConflict, "邮箱已被使用", nil)
+			return
+		}
+		user.Email = &email
 	}
 
-	if err := ac.DB.Save(&user).Error; err != nil {
+	if err := ac.DB.WithContext(c).Save(&user).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "用户信息更新失败", err)
 		return
 	}
@@ -170,5 +296,144 @@ func (ac *AuthController) UpdateProfile(c *gin.Context) {
 		"updated_at": user.UpdatedAt,
 	}
 
+	// 令牌中包含用户名，修改用户名后返回新令牌
+	if usernameChanged {
+		token, err := utils.GenerateToken(user.ID, user.Username, ac.Config.JWT.SecretKey, ac.Config.JWT.ExpiresIn)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "令牌生成失败", err)
+			return
+		}
+		response["token"] = token
+	}
+
 	utils.SuccessResponse(c, response)
+}
+
+// 修改密码（需验证原密码）
+func (ac *AuthController) ChangePassword(c *gin.Context) {
+	user, exists := utils.GetCurrentUser(c)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "用户未登录", nil)
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	if !utils.CheckPassword(req.OldPassword, user.Password) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "原密码错误", nil)
+		return
+	}
+
+	if err := utils.ValidatePassword(req.NewPassword); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "密码不符合要求", err)
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword, ac.Config.Security.BcryptCost)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "密码加密失败", err)
+		return
+	}
+
+	if err := ac.DB.WithContext(c).Model(&user).Update("password", hashedPassword).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "密码修改失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "密码修改成功"})
+}
+
+// 检查用户名是否已被其他用户使用（包含已注销用户，与唯一索引保持一致）
+func (ac *AuthController) usernameTaken(username string, excludeUserID uint) bool {
+	var count int64
+	ac.DB.Unscoped().Model(&models.User{}).Where("username = ? AND id != ?", username, excludeUserID).Count(&count)
+	return count > 0
+}
+
+// 检查邮箱是否已被其他用户使用（包含已注销用户，与唯一索引保持一致）
+func (ac *AuthController) emailTaken(email string, excludeUserID uint) bool {
+	var count int64
+	ac.DB.Unscoped().Model(&models.User{}).Where("email = ? AND id != ?", email, excludeUserID).Count(&count)
+	return count > 0
+}
+
+// 获取API密钥列表
+func (ac *AuthController) GetAPIKeys(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+
+	keys := []models.APIKey{}
+	if err := ac.DB.WithContext(c).Where("user_id = ?", userID).Order("created_at desc").Find(&keys).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询API密钥失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, keys)
+}
+
+// 创建API密钥（明文仅在创建时返回一次）
+func (ac *AuthController) CreateAPIKey(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.APIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	plainKey, err := utils.GenerateAPIKey()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "API密钥生成失败", err)
+		return
+	}
+
+	apiKey := models.APIKey{
+		Name:    req.Name,
+		KeyHash: utils.HashAPIKey(plainKey),
+		Prefix:  plainKey[:10],
+		UserID:  userID,
+	}
+
+	if err := ac.DB.WithContext(c).Create(&apiKey).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "API密钥创建失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"api_key": apiKey,
+		"key":     plainKey,
+	})
+}
+
+// 撤销API密钥
+func (ac *AuthController) RevokeAPIKey(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	keyID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	result := ac.DB.WithContext(c).Where("id = ? AND user_id = ?", keyID, userID).Delete(&models.APIKey{})
+	if result.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "API密钥撤销失败", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "API密钥不存在", nil)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "API密钥已撤销"})
 }
\ No newline at end of file
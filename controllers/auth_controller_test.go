@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"personaltask/models"
+	"personaltask/testutil"
+	"personaltask/utils"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateAPIKeyStoresOnlyHash(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	ac := &AuthController{DB: db}
+
+	var resp struct {
+		APIKey models.APIKey `json:"api_key"`
+		Key    string        `json:"key"`
+	}
+	decodeResponse(t, serveTest(t, ac.CreateAPIKey, "POST", "/api/auth/keys", models.APIKeyRequest{Name: "脚本"}, 1), &resp)
+
+	if !strings.HasPrefix(resp.Key, "pt_") {
+		t.Fatalf("key = %q, want pt_ 前缀的明文密钥", resp.Key)
+	}
+	rows := fake.Inserted("api_keys")
+	if len(rows) != 1 {
+		t.Fatalf("插入了 %d 个密钥，want 1", len(rows))
+	}
+	row := rows[0]
+	if row["key_hash"] != utils.HashAPIKey(resp.Key) || row["prefix"] != resp.Key[:10] || row["user_id"] != int64(1) {
+		t.Errorf("插入的密钥 = %v", row)
+	}
+	// 明文只在响应中返回一次，不写入数据库
+	for _, stmt := range fake.Find("") {
+		for _, arg := range stmt.Args {
+			if arg == resp.Key {
+				t.Errorf("明文密钥被写入数据库: %s", stmt.SQL)
+			}
+		}
+	}
+}
+
+func TestGetAPIKeysHidesHash(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	hash := utils.HashAPIKey("pt_secret")
+	fake.On("FROM `api_keys`", []string{"id", "name", "key_hash", "prefix", "user_id"},
+		[]driver.Value{int64(3), "脚本", hash, "pt_secret", int64(1)})
+	ac := &AuthController{DB: db}
+
+	w := serveTest(t, ac.GetAPIKeys, "GET", "/api/auth/keys", nil, 1)
+	var keys []models.APIKey
+	decodeResponse(t, w, &keys)
+
+	if len(keys) != 1 || keys[0].Name != "脚本" {
+		t.Fatalf("keys = %+v", keys)
+	}
+	if strings.Contains(w.Body.String(), hash) {
+		t.Errorf("响应中不应包含密钥哈希: %s", w.Body.String())
+	}
+	if queries := fake.Find("FROM `api_keys`"); len(queries) != 1 || !containsArg(queries[0].Args, int64(1)) {
+		t.Errorf("应只查询当前用户的密钥，got %v", queries)
+	}
+}
+
+func TestRevokeAPIKeySoftDeletesOwnKey(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	ac := &AuthController{DB: db}
+
+	w := serveTest(t, ac.RevokeAPIKey, "DELETE", "/api/auth/keys/3", nil, 1, func(c *gin.Context) {
+		c.Params = gin.Params{{Key: "id", Value: "3"}}
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	// 撤销即软删除，JWTAuth 查询密钥时会排除已撤销的密钥
+	updates := fake.Find("UPDATE `api_keys` SET `deleted_at`=?")
+	if len(updates) != 1 {
+		t.Fatalf("执行了 %d 次软删除，want 1", len(updates))
+	}
+	if args := updates[0].Args; !containsArg(args, int64(3)) || !containsArg(args, int64(1)) {
+		t.Errorf("应按密钥ID和当前用户删除，args = %v", args)
+	}
+	if deletes := fake.Find("DELETE FROM"); len(deletes) != 0 {
+		t.Errorf("不应物理删除密钥，got %v", deletes)
+	}
+}
@@ -1,9 +1,11 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
 	"personaltask/models"
 	"personaltask/utils"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -17,17 +19,46 @@ func NewCategoryController(db *gorm.DB) *CategoryController {
 	return &CategoryController{DB: db}
 }
 
+// 允许排序的分类字段
+var categoryOrderFields = []string{"created_at", "updated_at", "name", "usage"}
+
 // 获取分类列表
 func (cc *CategoryController) GetCategories(c *gin.Context) {
-	userID := utils.GetUserID(c)
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	if !utils.ValidateOrderParams(c, categoryOrderFields) {
+		return
+	}
 
-	var categories []models.Category
-	query := cc.DB.Where("user_id = ?", userID)
+	categories := []models.Category{}
+	query := cc.DB.WithContext(c).Where("categories.user_id = ?", userID)
 
-	// 排序
-	orderBy := c.DefaultQuery("order_by", "created_at")
-	orderDir := c.DefaultQuery("order_dir", "asc")
-	query = query.Order(orderBy + " " + orderDir)
+	// 排序，usage 按引用该分类的任务数排序（默认最常用的在前）
+	orderBy := c.Query("order_by")
+	if orderBy == "" {
+		orderBy = "created_at"
+	}
+	if orderBy == "usage" {
+		orderDir := "desc"
+		if c.Query("order_dir") == "asc" {
+			orderDir = "asc"
+		}
+		usage := cc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).
+			Select("category_id, COUNT(*) AS task_count").
+			Where("user_id = ? AND category_id IS NOT NULL", userID).
+			Group("category_id")
+		query = query.Joins("LEFT JOIN (?) AS category_usage ON category_usage.category_id = categories.id", usage).
+			Order("COALESCE(category_usage.task_count, 0) " + orderDir).
+			Order("categories.created_at asc")
+	} else {
+		orderDir := c.Query("order_dir")
+		if orderDir == "" {
+			orderDir = "asc"
+		}
+		query = query.Order("categories." + orderBy + " " + orderDir)
+	}
 
 	// 是否包含任务数量统计
 	if c.Query("with_count") == "true" {
@@ -36,19 +67,42 @@ func (cc *CategoryController) GetCategories(c *gin.Context) {
 			TaskCount int64 `json:"task_count"`
 		}
 
-		var categoriesWithCount []CategoryWithCount
 		if err := query.Find(&categories).Error; err != nil {
 			utils.ErrorResponse(c, http.StatusInternalServerError, "查询分类失败", err)
 			return
 		}
+		categoriesWithCount := make([]CategoryWithCount, 0, len(categories))
+
+		// 按分类分组一次统计任务数量，没有任务的分类计为0
+		categoryIDs := make([]uint, 0, len(categories))
+		for _, category := range categories {
+			categoryIDs = append(categoryIDs, category.ID)
+		}
+
+		var rows []struct {
+			CategoryID uint
+			TaskCount  int64
+		}
+		if len(categoryIDs) > 0 {
+			if err := cc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).
+				Select("category_id, COUNT(*) AS task_count").
+				Where("category_id IN ? AND user_id = ?", categoryIDs, userID).
+				Group("category_id").
+				Scan(&rows).Error; err != nil {
+				utils.ErrorResponse(c, http.StatusInternalServerError, "统计分类任务失败", err)
+				return
+			}
+		}
+
+		counts := make(map[uint]int64, len(rows))
+		for _, row := range rows {
+			counts[row.CategoryID] = row.TaskCount
+		}
 
 		for _, category := range categories {
-			var taskCount int64
-			cc.DB.Model(&models.Task{}).Where("category_id = ? AND user_id = ?", category.ID, userID).Count(&taskCount)
-			
 			categoriesWithCount = append(categoriesWithCount, CategoryWithCount{
 				Category:  category,
-				TaskCount: taskCount,
+				TaskCount: counts[category.ID],
 			})
 		}
 
@@ -66,17 +120,20 @@ func (cc *CategoryController) GetCategories(c *gin.Context) {
 
 // 创建分类
 func (cc *CategoryController) CreateCategory(c *gin.Context) {
-	userID := utils.GetUserID(c)
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
 
 	var req models.CategoryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "请求参数错误", err)
+		utils.BindErrorResponse(c, err)
 		return
 	}
 
 	// 检查分类名称是否已存在
 	var existingCategory models.Category
-	if err := cc.DB.Where("name = ? AND user_id = ?", req.Name, userID).First(&existingCategory).Error; err == nil {
+	if err := cc.DB.WithContext(c).Where("name = ? AND user_id = ?", req.Name, userID).First(&existingCategory).Error; err == nil {
 		utils.ErrorResponse(c, http.StatusConflict, "分类名称已存在", nil)
 		return
 	}
@@ -88,26 +145,34 @@ func (cc *CategoryController) CreateCategory(c *gin.Context) {
 		UserID:      userID,
 	}
 
-	// 如果没有设置颜色，使用默认颜色
+	// 如果没有设置颜色，从调色板中选择尚未使用的颜色
 	if category.Color == "" {
-		category.Color = "#007bff"
+		var usedColors []string
+		cc.DB.WithContext(c).Model(&models.Category{}).Where("user_id = ?", userID).Pluck("color", &usedColors)
+		category.Color = utils.NextPaletteColor(usedColors)
 	}
 
-	if err := cc.DB.Create(&category).Error; err != nil {
+	if err := cc.DB.WithContext(c).Create(&category).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "分类创建失败", err)
 		return
 	}
 
-	utils.SuccessResponse(c, category)
+	utils.CreatedResponse(c, fmt.Sprintf("/api/categories/%d", category.ID), category)
 }
 
 // 获取分类详情
 func (cc *CategoryController) GetCategory(c *gin.Context) {
-	userID := utils.GetUserID(c)
-	categoryID := c.Param("id")
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	categoryID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
 
 	var category models.Category
-	if err := cc.DB.Where("id = ? AND user_id = ?", categoryID, userID).First(&category).Error; err != nil {
+	if err := cc.DB.WithContext(c).Where("id = ? AND user_id = ?", categoryID, userID).First(&category).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			utils.ErrorResponse(c, http.StatusNotFound, "分类不存在", nil)
 		} else {
@@ -118,7 +183,7 @@ func (cc *CategoryController) GetCategory(c *gin.Context) {
 
 	// 如果需要包含任务信息
 	if c.Query("with_tasks") == "true" {
-		cc.DB.Preload("Tasks", "user_id = ?", userID).First(&category, category.ID)
+		cc.DB.WithContext(c).Preload("Tasks", "user_id = ?", userID).First(&category, category.ID)
 	}
 
 	utils.SuccessResponse(c, category)
@@ -126,18 +191,24 @@ func (cc *CategoryController) GetCategory(c *gin.Context) {
 
 // 更新分类
 func (cc *CategoryController) UpdateCategory(c *gin.Context) {
-	userID := utils.GetUserID(c)
-	categoryID := c.Param("id")
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	categoryID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
 
 	var req models.CategoryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "请求参数错误", err)
+		utils.BindErrorResponse(c, err)
 		return
 	}
 
 	// 查找分类
 	var category models.Category
-	if err := cc.DB.Where("id = ? AND user_id = ?", categoryID, userID).First(&category).Error; err != nil {
+	if err := cc.DB.WithContext(c).Where("id = ? AND user_id = ?", categoryID, userID).First(&category).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			utils.ErrorResponse(c, http.StatusNotFound, "分类不存在", nil)
 		} else {
@@ -148,7 +219,7 @@ func (cc *CategoryController) UpdateCategory(c *gin.Context) {
 
 	// 检查分类名称是否已存在（排除当前分类）
 	var existingCategory models.Category
-	if err := cc.DB.Where("name = ? AND user_id = ? AND id != ?", req.Name, userID, categoryID).First(&existingCategory).Error; err == nil {
+	if err := cc.DB.WithContext(c).Where("name = ? AND user_id = ? AND id != ?", req.Name, userID, categoryID).First(&existingCategory).Error; err == nil {
 		utils.ErrorResponse(c, http.StatusConflict, "分类名称已存在", nil)
 		return
 	}
@@ -160,7 +231,7 @@ func (cc *CategoryController) UpdateCategory(c *gin.Context) {
 		category.Color = req.Color
 	}
 
-	if err := cc.DB.Save(&category).Error; err != nil {
+	if err := cc.DB.WithContext(c).Save(&category).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "分类更新失败", err)
 		return
 	}
@@ -168,14 +239,75 @@ func (cc *CategoryController) UpdateCategory(c *gin.Context) {
 	utils.SuccessResponse(c, category)
 }
 
+// 部分更新分类，未提供的字段保持不变（description 可显式置空）
+func (cc *CategoryController) PatchCategory(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	categoryID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.CategoryPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	var category models.Category
+	if err := cc.DB.WithContext(c).Where("id = ? AND user_id = ?", categoryID, userID).First(&category).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "分类不存在", nil)
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "查询分类失败", err)
+		}
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil && *req.Name != category.Name {
+		// 仅在名称变化时检查是否与其他分类重名
+		var existingCategory models.Category
+		if err := cc.DB.WithContext(c).Where("name = ? AND user_id = ? AND id != ?", *req.Name, userID, categoryID).First(&existingCategory).Error; err == nil {
+			utils.ErrorResponse(c, http.StatusConflict, "分类名称已存在", nil)
+			return
+		}
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Color != nil {
+		updates["color"] = *req.Color
+	}
+
+	if len(updates) > 0 {
+		if err := cc.DB.WithContext(c).Model(&category).Updates(updates).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "分类更新失败", err)
+			return
+		}
+		cc.DB.WithContext(c).First(&category, categoryID)
+	}
+
+	utils.SuccessResponse(c, category)
+}
+
 // 删除分类
 func (cc *CategoryController) DeleteCategory(c *gin.Context) {
-	userID := utils.GetUserID(c)
-	categoryID := c.Param("id")
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	categoryID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
 
 	// 检查分类是否存在
 	var category models.Category
-	if err := cc.DB.Where("id = ? AND user_id = ?", categoryID, userID).First(&category).Error; err != nil {
+	if err := cc.DB.WithContext(c).Where("id = ? AND user_id = ?", categoryID, userID).First(&category).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			utils.ErrorResponse(c, http.StatusNotFound, "分类不存在", nil)
 		} else {
@@ -184,26 +316,43 @@ func (cc *CategoryController) DeleteCategory(c *gin.Context) {
 		return
 	}
 
-	// 检查分类下是否有任务
-	var taskCount int64
-	cc.DB.Model(&models.Task{}).Where("category_id = ? AND user_id = ?", categoryID, userID).Count(&taskCount)
-
-	if taskCount > 0 {
-		// 如果有任务，询问是否强制删除
-		if c.Query("force") != "true" {
-			utils.ErrorResponse(c, http.StatusConflict, "分类下存在任务，无法删除。如需强制删除，请添加 force=true 参数", nil)
+	// 指定了 reassign_to 时将任务移到另一个分类，需为当前用户的其他分类
+	var reassignTo *uint
+	if value := c.Query("reassign_to"); value != "" {
+		targetID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || targetID == 0 || uint(targetID) == categoryID {
+			utils.ErrorResponse(c, http.StatusBadRequest, "无效的目标分类", nil)
 			return
 		}
-
-		// 强制删除：将关联任务的分类ID设为null
-		if err := cc.DB.Model(&models.Task{}).Where("category_id = ? AND user_id = ?", categoryID, userID).Update("category_id", nil).Error; err != nil {
-			utils.ErrorResponse(c, http.StatusInternalServerError, "清理关联任务失败", err)
+		var target models.Category
+		if err := cc.DB.WithContext(c).Where("id = ? AND user_id = ?", targetID, userID).First(&target).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "目标分类不存在或无权限", err)
 			return
 		}
+		id := uint(targetID)
+		reassignTo = &id
 	}
 
-	// 删除分类
-	if err := cc.DB.Delete(&category).Error; err != nil {
+	// 检查分类下是否有任务
+	var taskCount int64
+	cc.DB.WithContext(c).Model(&models.Task{}).Where("category_id = ? AND user_id = ?", categoryID, userID).Count(&taskCount)
+
+	// 有任务时需要指定转移目标，或通过 force=true 将任务的分类置空
+	if taskCount > 0 && reassignTo == nil && c.Query("force") != "true" {
+		utils.ErrorResponse(c, http.StatusConflict, "分类下存在任务，无法删除。可通过 reassign_to 参数转移任务，或添加 force=true 参数强制删除", nil)
+		return
+	}
+
+	err := cc.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if taskCount > 0 {
+			if err := tx.Model(&models.Task{}).Where("category_id = ? AND user_id = ?", categoryID, userID).
+				Update("category_id", reassignTo).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&category).Error
+	})
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "分类删除失败", err)
 		return
 	}
@@ -213,12 +362,18 @@ func (cc *CategoryController) DeleteCategory(c *gin.Context) {
 
 // 获取分类统计信息
 func (cc *CategoryController) GetCategoryStats(c *gin.Context) {
-	userID := utils.GetUserID(c)
-	categoryID := c.Param("id")
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	categoryID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
 
 	// 验证分类存在
 	var category models.Category
-	if err := cc.DB.Where("id = ? AND user_id = ?", categoryID, userID).First(&category).Error; err != nil {
+	if err := cc.DB.WithContext(c).Where("id = ? AND user_id = ?", categoryID, userID).First(&category).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			utils.ErrorResponse(c, http.StatusNotFound, "分类不存在", nil)
 		} else {
@@ -230,10 +385,10 @@ func (cc *CategoryController) GetCategoryStats(c *gin.Context) {
 	// 统计任务数量
 	var totalTasks, pendingTasks, inProgressTasks, completedTasks int64
 
-	cc.DB.Model(&models.Task{}).Where("category_id = ? AND user_id = ?", categoryID, userID).Count(&totalTasks)
-	cc.DB.Model(&models.Task{}).Where("category_id = ? AND user_id = ? AND status = ?", categoryID, userID, "pending").Count(&pendingTasks)
-	cc.DB.Model(&models.Task{}).Where("category_id = ? AND user_id = ? AND status = ?", categoryID, userID, "in_progress").Count(&inProgressTasks)
-	cc.DB.Model(&models.Task{}).Where("category_id = ? AND user_id = ? AND status = ?", categoryID, userID, "completed").Count(&completedTasks)
+	cc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).Where("category_id = ? AND user_id = ?", categoryID, userID).Count(&totalTasks)
+	cc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).Where("category_id = ? AND user_id = ? AND status = ?", categoryID, userID, "pending").Count(&pendingTasks)
+	cc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).Where("category_id = ? AND user_id = ? AND status = ?", categoryID, userID, "in_progress").Count(&inProgressTasks)
+	cc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).Where("category_id = ? AND user_id = ? AND status = ?", categoryID, userID, utils.CompletedTaskStatus()).Count(&completedTasks)
 
 	stats := gin.H{
 		"category":          category,
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"net/http"
+	"personaltask/testutil"
+	"strings"
+	"testing"
+)
+
+func TestGetCategoriesOrderParams(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantCode  int
+		wantOrder string
+	}{
+		{"", http.StatusOK, "ORDER BY categories.created_at asc"},
+		{"order_by=name&order_dir=desc", http.StatusOK, "ORDER BY categories.name desc"},
+		{"order_by=usage", http.StatusOK, "ORDER BY COALESCE(category_usage.task_count, 0) desc"},
+		{"order_by=id%3BDROP%20TABLE%20categories", http.StatusBadRequest, ""},
+		{"order_by=user_id", http.StatusBadRequest, ""},
+		{"order_by=name&order_dir=asc%2Cid", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			db, fake := testutil.NewFakeDB(t)
+			cc := &CategoryController{DB: db}
+
+			w := serveTest(t, cc.GetCategories, "GET", "/api/categories?"+tt.query, nil, 1)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			queries := fake.Find("FROM `categories`")
+			if tt.wantOrder == "" {
+				if len(queries) != 0 {
+					t.Errorf("参数不合法时不应执行查询，got %v", queries)
+				}
+				return
+			}
+			if len(queries) != 1 || !strings.Contains(queries[0].SQL, tt.wantOrder) {
+				t.Errorf("查询 = %v, want 包含 %q", queries, tt.wantOrder)
+			}
+		})
+	}
+}
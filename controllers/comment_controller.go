@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"net/http"
+	"personaltask/models"
+	"personaltask/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CommentController struct {
+	DB *gorm.DB
+}
+
+func NewCommentController(db *gorm.DB) *CommentController {
+	return &CommentController{DB: db}
+}
+
+// 获取任务评论列表（按时间正序）
+func (cc *CommentController) GetComments(c *gin.Context) {
+	taskID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+	page, pageSize, offset := utils.GetPaginationParams(c)
+
+	query := cc.DB.WithContext(c).Model(&models.Comment{}).Where("task_id = ?", taskID)
+
+	// 获取总数
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询评论失败", err)
+		return
+	}
+
+	// 分页查询
+	comments := []models.Comment{}
+	if err := query.Order("created_at asc, id asc").Offset(offset).Limit(pageSize).Find(&comments).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询评论失败", err)
+		return
+	}
+
+	utils.PaginatedResponse(c, comments, total, page, pageSize)
+}
+
+// 创建评论
+func (cc *CommentController) CreateComment(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	taskID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	comment := models.Comment{
+		TaskID: taskID,
+		UserID: userID,
+		Body:   req.Body,
+	}
+
+	if err := cc.DB.WithContext(c).Create(&comment).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "评论创建失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, comment)
+}
+
+// 删除评论
+func (cc *CommentController) DeleteComment(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	taskID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+	commentID, ok := utils.ParseID(c, "commentId")
+	if !ok {
+		return
+	}
+
+	result := cc.DB.WithContext(c).Where("id = ? AND task_id = ? AND user_id = ?", commentID, taskID, userID).Delete(&models.Comment{})
+	if result.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "评论删除失败", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "评论不存在", nil)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "评论删除成功"})
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"personaltask/models"
+	"personaltask/testutil"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 评论和变更历史都是任务下的分页子资源，分页行为与主列表一致
+var taskSubResources = []struct {
+	name    string
+	table   string
+	handler func(db *gorm.DB) gin.HandlerFunc
+}{
+	{"评论", "comments", func(db *gorm.DB) gin.HandlerFunc { return (&CommentController{DB: db}).GetComments }},
+	{"变更历史", "task_histories", func(db *gorm.DB) gin.HandlerFunc { return (&TaskController{DB: db}).GetTaskHistory }},
+}
+
+func TestTaskSubResourcesPaginate(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantPage  int
+		wantSize  int
+		wantLimit string
+		wantPages int
+		wantNext  bool
+	}{
+		{"", 1, 10, "LIMIT 10", 15, true},
+		{"page=2&page_size=20", 2, 20, "LIMIT 20 OFFSET 20", 8, true},
+		{"page=8&page_size=20", 8, 20, "LIMIT 20 OFFSET 140", 8, false},
+		{"page_size=1000", 1, 100, "LIMIT 100", 2, true},
+	}
+	for _, res := range taskSubResources {
+		for _, tt := range tests {
+			t.Run(res.name+" "+tt.query, func(t *testing.T) {
+				db, fake := testutil.NewFakeDB(t)
+				fake.On("count(*)", []string{"count"}, []driver.Value{int64(150)})
+				fake.On("FROM `", []string{"id", "task_id"}, []driver.Value{int64(1), int64(7)}, []driver.Value{int64(2), int64(7)})
+
+				w := serveTest(t, res.handler(db), "GET", "/api/tasks/7/"+res.table+"?"+tt.query, nil, 1, withTaskID("7"))
+				var resp models.PaginatedResponse
+				decodeResponse(t, w, &resp)
+				if resp.Total != 150 || resp.Page != tt.wantPage || resp.PageSize != tt.wantSize ||
+					resp.TotalPages != tt.wantPages || resp.HasNext != tt.wantNext {
+					t.Errorf("分页信息 = %+v, want page %d, page_size %d, total_pages %d, has_next %v",
+						resp, tt.wantPage, tt.wantSize, tt.wantPages, tt.wantNext)
+				}
+				if items, ok := resp.Items.([]interface{}); !ok || len(items) != 2 {
+					t.Errorf("items = %v, want 2 条", resp.Items)
+				}
+
+				queries := fake.Find("SELECT * FROM `" + res.table + "`")
+				if len(queries) != 1 || !strings.HasSuffix(queries[0].SQL, tt.wantLimit) || !containsArg(queries[0].Args, int64(7)) {
+					t.Errorf("列表查询 = %v, want 按任务7查询并以 %s 结尾", queries, tt.wantLimit)
+				}
+			})
+		}
+	}
+}
+
+func TestTaskSubResourcesCountError(t *testing.T) {
+	for _, res := range taskSubResources {
+		t.Run(res.name, func(t *testing.T) {
+			db, fake := testutil.NewFakeDB(t)
+			fake.OnError("count(*)", errors.New("连接中断"))
+
+			w := serveTest(t, res.handler(db), "GET", "/api/tasks/7/"+res.table, nil, 1, withTaskID("7"))
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("status = %d, want 500", w.Code)
+			}
+			if queries := fake.Find("SELECT * FROM"); len(queries) != 0 {
+				t.Errorf("统计失败后不应继续查询: %v", queries)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 以指定用户身份调用处理函数，返回响应（raw=true，不带统一响应包装）
+func serveTest(t *testing.T, handler gin.HandlerFunc, method, target string, body interface{}, userID uint, setup ...func(*gin.Context)) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("序列化请求体失败: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	if strings.Contains(target, "?") {
+		target += "&raw=true"
+	} else {
+		target += "?raw=true"
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, reader)
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user_id", userID)
+	for _, fn := range setup {
+		fn(c)
+	}
+	handler(c)
+	return w
+}
+
+// 解析成功响应（200或201）的JSON
+func decodeResponse(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if w.Code != http.StatusOK && w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("解析响应失败: %v, body = %s", err, w.Body.String())
+	}
+}
+
+// 参数列表中是否包含 want
+func containsArg(args []driver.Value, want driver.Value) bool {
+	for _, arg := range args {
+		if arg == want {
+			return true
+		}
+	}
+	return false
+}
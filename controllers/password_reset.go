@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"personaltask/models"
+	"personaltask/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errResetTokenInvalid = errors.New("重置令牌无效或已过期")
+
+// 忘记密码：向已注册邮箱发送重置令牌
+// 无论邮箱是否存在都返回相同结果，避免被用于探测已注册邮箱
+func (ac *AuthController) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	var user models.User
+	if err := ac.DB.WithContext(c).Where("email = ?", utils.NormalizeEmail(req.Email)).First(&user).Error; err == nil {
+		if err := ac.sendResetToken(user); err != nil {
+			log.Printf("发送密码重置邮件失败 user_id=%d: %v", user.ID, err)
+		}
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "如果该邮箱已注册，重置密码的邮件将很快送达"})
+}
+
+// 生成重置令牌并发送邮件，同时作废该用户之前未使用的令牌
+func (ac *AuthController) sendResetToken(user models.User) error {
+	token, err := utils.GenerateResetToken()
+	if err != nil {
+		return err
+	}
+
+	err = ac.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: time.Now().UTC().Add(ac.Config.Login.ResetTTL),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("您好 %s：\n\n您的密码重置令牌为：%s\n令牌将在 %d 分钟后失效，且只能使用一次。如非本人操作请忽略此邮件。",
+		user.Username, token, int(ac.Config.Login.ResetTTL.Minutes()))
+	return ac.Mailer.Send(*user.Email, "重置密码", body)
+}
+
+// 使用重置令牌设置新密码
+func (ac *AuthController) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	if err := utils.ValidatePassword(req.Password); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "密码不符合要求", err)
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password, ac.Config.Security.BcryptCost)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "密码加密失败", err)
+		return
+	}
+
+	var user models.User
+	err = ac.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var resetToken models.PasswordResetToken
+		if err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(req.Token), time.Now()).
+			First(&resetToken).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return errResetTokenInvalid
+			}
+			return err
+		}
+
+		// 条件更新保证令牌只能被使用一次
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", resetToken.ID).
+			Update("used_at", time.Now().UTC())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errResetTokenInvalid
+		}
+
+		if err := tx.First(&user, resetToken.UserID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return errResetTokenInvalid
+			}
+			return err
+		}
+		return tx.Model(&user).Update("password", hashedPassword).Error
+	})
+
+	if err == errResetTokenInvalid {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "密码重置失败", err)
+		return
+	}
+
+	// 密码已重置，解除登录锁定
+	ac.LoginLimiter.Succeed(user.Username)
+
+	utils.SuccessResponse(c, gin.H{"message": "密码重置成功"})
+}
@@ -1,9 +1,12 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
 	"personaltask/models"
 	"personaltask/utils"
+	"sort"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -17,37 +20,116 @@ func NewProjectController(db *gorm.DB) *ProjectController {
 	return &ProjectController{DB: db}
 }
 
-// 获取项目列表
-func (pc *ProjectController) GetProjects(c *gin.Context) {
-	userID := utils.GetUserID(c)
-	page, pageSize, offset := utils.GetPaginationParams(c)
+// 带任务统计的项目
+type projectWithStats struct {
+	models.Project
+	TotalTasks     int64   `json:"total_tasks"`
+	CompletedTasks int64   `json:"completed_tasks"`
+	Progress       float64 `json:"progress"`
+}
 
-	// 构建查询
-	query := pc.DB.Model(&models.Project{}).Where("user_id = ?", userID)
+// 当前用户可见的项目查询（包含自己创建的和被共享的项目），并应用状态和关键词过滤
+func (pc *ProjectController) projectListQuery(c *gin.Context, userID uint) *gorm.DB {
+	query := pc.DB.WithContext(c).Model(&models.Project{}).Where("user_id = ? OR id IN (?)", userID,
+		pc.DB.WithContext(c).Model(&models.ProjectMember{}).Select("project_id").Where("user_id = ?", userID))
 
-	// 状态过滤
-	if status := c.Query("status"); status != "" {
-		if utils.IsValidProjectStatus(status) {
-			query = query.Where("status = ?", status)
-		}
+	// 状态过滤（未指定状态时默认隐藏已归档项目，include_archived=true 时全部返回）
+	if status := c.Query("status"); status != "" && utils.IsValidProjectStatus(status) {
+		query = query.Where("status = ?", status)
+	} else if c.Query("include_archived") != "true" {
+		query = query.Where("status != ?", "archived")
 	}
 
 	// 关键词搜索
 	if keyword := c.Query("keyword"); keyword != "" {
-		query = query.Where("name LIKE ? OR description LIKE ?", "%"+keyword+"%", "%"+keyword+"%")
+		pattern := utils.LikePattern(keyword)
+		query = query.Where("name COLLATE "+utils.SearchCollation+" LIKE ? OR description COLLATE "+utils.SearchCollation+" LIKE ?", pattern, pattern)
+	}
+
+	return query
+}
+
+// 为项目附加任务统计，按项目分组一次查询完成，避免逐个项目计数
+// 项目任务归属于项目创建者，因此只统计与项目 user_id 相同的任务
+func (pc *ProjectController) withTaskStats(c *gin.Context, projects []models.Project) ([]projectWithStats, error) {
+	result := make([]projectWithStats, 0, len(projects))
+	if len(projects) == 0 {
+		return result, nil
+	}
+
+	projectIDs := make([]uint, 0, len(projects))
+	for _, project := range projects {
+		projectIDs = append(projectIDs, project.ID)
+	}
+
+	var rows []struct {
+		ProjectID      uint
+		TotalTasks     int64
+		CompletedTasks int64
+	}
+	err := pc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).
+		Select("tasks.project_id, COUNT(*) AS total_tasks, COALESCE(SUM(CASE WHEN tasks.status = ? THEN 1 ELSE 0 END), 0) AS completed_tasks", utils.CompletedTaskStatus()).
+		Joins("JOIN projects ON projects.id = tasks.project_id AND projects.user_id = tasks.user_id").
+		Where("tasks.project_id IN ?", projectIDs).
+		Group("tasks.project_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]projectWithStats, len(rows))
+	for _, row := range rows {
+		counts[row.ProjectID] = projectWithStats{TotalTasks: row.TotalTasks, CompletedTasks: row.CompletedTasks}
+	}
+
+	for _, project := range projects {
+		stats := counts[project.ID]
+		stats.Project = project
+		if stats.TotalTasks > 0 {
+			stats.Progress = float64(stats.CompletedTasks) / float64(stats.TotalTasks) * 100
+		}
+		result = append(result, stats)
+	}
+	return result, nil
+}
+
+// 允许排序的项目字段
+var projectOrderFields = []string{"created_at", "updated_at", "name", "status", "start_date", "end_date"}
+
+// 获取项目列表
+func (pc *ProjectController) GetProjects(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	page, pageSize, offset := utils.GetPaginationParams(c)
+	if !utils.ValidateOrderParams(c, projectOrderFields) {
+		return
 	}
 
+	// 构建查询
+	query := pc.projectListQuery(c, userID)
+
 	// 排序
-	orderBy := c.DefaultQuery("order_by", "created_at")
-	orderDir := c.DefaultQuery("order_dir", "desc")
+	orderBy := c.Query("order_by")
+	if orderBy == "" {
+		orderBy = "created_at"
+	}
+	orderDir := c.Query("order_dir")
+	if orderDir == "" {
+		orderDir = "desc"
+	}
 	query = query.Order(orderBy + " " + orderDir)
 
 	// 获取总数
 	var total int64
-	query.Count(&total)
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询项目失败", err)
+		return
+	}
 
 	// 分页查询
-	var projects []models.Project
+	projects := []models.Project{}
 	if err := query.Offset(offset).Limit(pageSize).Find(&projects).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "查询项目失败", err)
 		return
@@ -55,52 +137,57 @@ func (pc *ProjectController) GetProjects(c *gin.Context) {
 
 	// 如果需要包含任务统计
 	if c.Query("with_stats") == "true" {
-		type ProjectWithStats struct {
-			models.Project
-			TotalTasks     int64 `json:"total_tasks"`
-			CompletedTasks int64 `json:"completed_tasks"`
-			Progress       float64 `json:"progress"`
+		projectsWithStats, err := pc.withTaskStats(c, projects)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "统计项目任务失败", err)
+			return
 		}
 
-		var projectsWithStats []ProjectWithStats
-		for _, project := range projects {
-			var totalTasks, completedTasks int64
-			pc.DB.Model(&models.Task{}).Where("project_id = ? AND user_id = ?", project.ID, userID).Count(&totalTasks)
-			pc.DB.Model(&models.Task{}).Where("project_id = ? AND user_id = ? AND status = ?", project.ID, userID, "completed").Count(&completedTasks)
+		utils.PaginatedResponse(c, projectsWithStats, total, page, pageSize)
+		return
+	}
 
-			progress := 0.0
-			if totalTasks > 0 {
-				progress = float64(completedTasks) / float64(totalTasks) * 100
-			}
+	utils.PaginatedResponse(c, projects, total, page, pageSize)
+}
 
-			projectsWithStats = append(projectsWithStats, ProjectWithStats{
-				Project:        project,
-				TotalTasks:     totalTasks,
-				CompletedTasks: completedTasks,
-				Progress:       progress,
-			})
-		}
+// 获取所有项目的任务统计（支持与项目列表相同的状态和关键词过滤）
+func (pc *ProjectController) GetAllProjectStats(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
 
-		utils.PaginatedResponse(c, projectsWithStats, total, page, pageSize)
+	var projects []models.Project
+	if err := pc.projectListQuery(c, userID).Order("created_at desc").Find(&projects).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询项目失败", err)
 		return
 	}
 
-	utils.PaginatedResponse(c, projects, total, page, pageSize)
+	projectsWithStats, err := pc.withTaskStats(c, projects)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "统计项目任务失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, projectsWithStats)
 }
 
 // 创建项目
 func (pc *ProjectController) CreateProject(c *gin.Context) {
-	userID := utils.GetUserID(c)
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
 
 	var req models.ProjectRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "请求参数错误", err)
+		utils.BindErrorResponse(c, err)
 		return
 	}
 
 	// 检查项目名称是否已存在
 	var existingProject models.Project
-	if err := pc.DB.Where("name = ? AND user_id = ?", req.Name, userID).First(&existingProject).Error; err == nil {
+	if err := pc.DB.WithContext(c).Where("name = ? AND user_id = ?", req.Name, userID).First(&existingProject).Error; err == nil {
 		utils.ErrorResponse(c, http.StatusConflict, "项目名称已存在", nil)
 		return
 	}
@@ -119,32 +206,45 @@ func (pc *ProjectController) CreateProject(c *gin.Context) {
 		project.Status = "active"
 	}
 
-	if err := pc.DB.Create(&project).Error; err != nil {
+	if err := pc.DB.WithContext(c).Create(&project).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "项目创建失败", err)
 		return
 	}
 
-	utils.SuccessResponse(c, project)
+	utils.CreatedResponse(c, fmt.Sprintf("/api/projects/%d", project.ID), project)
 }
 
 // 获取项目详情
 func (pc *ProjectController) GetProject(c *gin.Context) {
-	userID := utils.GetUserID(c)
-	projectID := c.Param("id")
+	projectID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
 
-	var project models.Project
-	if err := pc.DB.Where("id = ? AND user_id = ?", projectID, userID).First(&project).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			utils.ErrorResponse(c, http.StatusNotFound, "项目不存在", nil)
-		} else {
-			utils.ErrorResponse(c, http.StatusInternalServerError, "查询项目失败", err)
-		}
+	project, ok := pc.findProject(c, projectID)
+	if !ok {
 		return
 	}
+	ownerID := project.UserID
 
-	// 如果需要包含任务信息
+	// 如果需要包含任务信息，同时附带任务统计和进度（分组计数，不依赖加载的任务列表）
 	if c.Query("with_tasks") == "true" {
-		pc.DB.Preload("Tasks", "user_id = ?", userID).First(&project, project.ID)
+		// 任务列表与统计口径一致：只包含项目创建者的、未被删除且所属分类未被删除的任务
+		err := pc.DB.WithContext(c).Preload("Tasks", func(db *gorm.DB) *gorm.DB {
+			return db.Scopes(countableTasks).Where("user_id = ?", ownerID)
+		}).First(&project, project.ID).Error
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "查询项目任务失败", err)
+			return
+		}
+
+		stats, err := pc.withTaskStats(c, []models.Project{project})
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "统计项目任务失败", err)
+			return
+		}
+		utils.SuccessResponse(c, stats[0])
+		return
 	}
 
 	utils.SuccessResponse(c, project)
@@ -152,29 +252,26 @@ func (pc *ProjectController) GetProject(c *gin.Context) {
 
 // 更新项目
 func (pc *ProjectController) UpdateProject(c *gin.Context) {
-	userID := utils.GetUserID(c)
-	projectID := c.Param("id")
+	projectID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
 
 	var req models.ProjectRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "请求参数错误", err)
+		utils.BindErrorResponse(c, err)
 		return
 	}
 
-	// 查找项目
-	var project models.Project
-	if err := pc.DB.Where("id = ? AND user_id = ?", projectID, userID).First(&project).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			utils.ErrorResponse(c, http.StatusNotFound, "项目不存在", nil)
-		} else {
-			utils.ErrorResponse(c, http.StatusInternalServerError, "查询项目失败", err)
-		}
+	project, ok := pc.findProject(c, projectID)
+	if !ok {
 		return
 	}
+	ownerID := project.UserID
 
 	// 检查项目名称是否已存在（排除当前项目）
 	var existingProject models.Project
-	if err := pc.DB.Where("name = ? AND user_id = ? AND id != ?", req.Name, userID, projectID).First(&existingProject).Error; err == nil {
+	if err := pc.DB.WithContext(c).Where("name = ? AND user_id = ? AND id != ?", req.Name, ownerID, projectID).First(&existingProject).Error; err == nil {
 		utils.ErrorResponse(c, http.StatusConflict, "项目名称已存在", nil)
 		return
 	}
@@ -188,7 +285,7 @@ func (pc *ProjectController) UpdateProject(c *gin.Context) {
 	project.StartDate = req.StartDate
 	project.EndDate = req.EndDate
 
-	if err := pc.DB.Save(&project).Error; err != nil {
+	if err := pc.DB.WithContext(c).Save(&project).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "项目更新失败", err)
 		return
 	}
@@ -196,42 +293,99 @@ func (pc *ProjectController) UpdateProject(c *gin.Context) {
 	utils.SuccessResponse(c, project)
 }
 
+// 部分更新项目，未提供的字段保持不变
+func (pc *ProjectController) PatchProject(c *gin.Context) {
+	projectID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.ProjectPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	project, ok := pc.findProject(c, projectID)
+	if !ok {
+		return
+	}
+	ownerID := project.UserID
+
+	updates := map[string]interface{}{}
+	if req.Name != nil && *req.Name != project.Name {
+		// 仅在名称变化时检查是否与其他项目重名
+		var existingProject models.Project
+		if err := pc.DB.WithContext(c).Where("name = ? AND user_id = ? AND id != ?", *req.Name, ownerID, projectID).First(&existingProject).Error; err == nil {
+			utils.ErrorResponse(c, http.StatusConflict, "项目名称已存在", nil)
+			return
+		}
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Status != nil {
+		updates["status"] = *req.Status
+	}
+	if req.StartDate != nil {
+		updates["start_date"] = *req.StartDate
+	}
+	if req.EndDate != nil {
+		updates["end_date"] = *req.EndDate
+	}
+
+	if len(updates) > 0 {
+		if err := pc.DB.WithContext(c).Model(&project).Updates(updates).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "项目更新失败", err)
+			return
+		}
+		pc.DB.WithContext(c).First(&project, projectID)
+	}
+
+	utils.SuccessResponse(c, project)
+}
+
 // 删除项目
 func (pc *ProjectController) DeleteProject(c *gin.Context) {
-	userID := utils.GetUserID(c)
-	projectID := c.Param("id")
-
-	// 检查项目是否存在
-	var project models.Project
-	if err := pc.DB.Where("id = ? AND user_id = ?", projectID, userID).First(&project).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			utils.ErrorResponse(c, http.StatusNotFound, "项目不存在", nil)
-		} else {
-			utils.ErrorResponse(c, http.StatusInternalServerError, "查询项目失败", err)
-		}
+	projectID, ok := utils.ParseID(c, "id")
+	if !ok {
 		return
 	}
 
+	project, ok := pc.findProject(c, projectID)
+	if !ok {
+		return
+	}
+	ownerID := project.UserID
+
 	// 检查项目下是否有任务
 	var taskCount int64
-	pc.DB.Model(&models.Task{}).Where("project_id = ? AND user_id = ?", projectID, userID).Count(&taskCount)
+	pc.DB.WithContext(c).Model(&models.Task{}).Where("project_id = ? AND user_id = ?", projectID, ownerID).Count(&taskCount)
 
-	if taskCount > 0 {
-		// 如果有任务，询问是否强制删除
-		if c.Query("force") != "true" {
-			utils.ErrorResponse(c, http.StatusConflict, "项目下存在任务，无法删除。如需强制删除，请添加 force=true 参数", nil)
-			return
-		}
+	// 如果有任务，询问是否强制删除
+	if taskCount > 0 && c.Query("force") != "true" {
+		utils.ErrorResponse(c, http.StatusConflict, "项目下存在任务，无法删除。如需强制删除，请添加 force=true 参数", nil)
+		return
+	}
 
+	// 清理关联数据与删除项目在同一事务中完成
+	err := pc.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
 		// 强制删除：将关联任务的项目ID设为null
-		if err := pc.DB.Model(&models.Task{}).Where("project_id = ? AND user_id = ?", projectID, userID).Update("project_id", nil).Error; err != nil {
-			utils.ErrorResponse(c, http.StatusInternalServerError, "清理关联任务失败", err)
-			return
+		if taskCount > 0 {
+			if err := tx.Model(&models.Task{}).Where("project_id = ? AND user_id = ?", projectID, ownerID).Update("project_id", nil).Error; err != nil {
+				return err
+			}
+		}
+
+		// 删除项目成员
+		if err := tx.Where("project_id = ?", projectID).Delete(&models.ProjectMember{}).Error; err != nil {
+			return err
 		}
-	}
 
-	// 删除项目
-	if err := pc.DB.Delete(&project).Error; err != nil {
+		return tx.Delete(&project).Error
+	})
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "项目删除失败", err)
 		return
 	}
@@ -239,25 +393,81 @@ func (pc *ProjectController) DeleteProject(c *gin.Context) {
 	utils.SuccessResponse(c, gin.H{"message": "项目删除成功"})
 }
 
+// 归档项目
+func (pc *ProjectController) ArchiveProject(c *gin.Context) {
+	pc.setProjectStatus(c, "archived")
+}
+
+// 取消归档项目（恢复为进行中）
+func (pc *ProjectController) UnarchiveProject(c *gin.Context) {
+	pc.setProjectStatus(c, "active")
+}
+
+// 重新打开已完成或已归档的项目（恢复为进行中）
+func (pc *ProjectController) ReopenProject(c *gin.Context) {
+	projectID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	project, ok := pc.findProject(c, projectID)
+	if !ok {
+		return
+	}
+
+	if project.Status != "completed" && project.Status != "archived" {
+		utils.ErrorResponse(c, http.StatusConflict, "只有已完成或已归档的项目可以重新打开", nil)
+		return
+	}
+
+	if err := pc.DB.WithContext(c).Model(&project).Update("status", "active").Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "项目状态更新失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, project)
+}
+
+func (pc *ProjectController) setProjectStatus(c *gin.Context, status string) {
+	projectID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	project, ok := pc.findProject(c, projectID)
+	if !ok {
+		return
+	}
+
+	if project.Status != status {
+		if err := pc.DB.WithContext(c).Model(&project).Update("status", status).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "项目状态更新失败", err)
+			return
+		}
+	}
+
+	utils.SuccessResponse(c, project)
+}
+
 // 获取项目下的任务
 func (pc *ProjectController) GetProjectTasks(c *gin.Context) {
-	userID := utils.GetUserID(c)
-	projectID := c.Param("id")
+	projectID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
 	page, pageSize, offset := utils.GetPaginationParams(c)
+	if !utils.ValidateOrderParams(c, taskOrderFields) {
+		return
+	}
 
-	// 验证项目存在
-	var project models.Project
-	if err := pc.DB.Where("id = ? AND user_id = ?", projectID, userID).First(&project).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			utils.ErrorResponse(c, http.StatusNotFound, "项目不存在", nil)
-		} else {
-			utils.ErrorResponse(c, http.StatusInternalServerError, "查询项目失败", err)
-		}
+	project, ok := pc.findProject(c, projectID)
+	if !ok {
 		return
 	}
+	ownerID := project.UserID
 
 	// 构建查询
-	query := pc.DB.Model(&models.Task{}).Where("project_id = ? AND user_id = ?", projectID, userID)
+	query := pc.DB.WithContext(c).Model(&models.Task{}).Where("project_id = ? AND user_id = ?", projectID, ownerID)
 
 	// 状态过滤
 	if status := c.Query("status"); status != "" {
@@ -274,16 +484,17 @@ func (pc *ProjectController) GetProjectTasks(c *gin.Context) {
 	}
 
 	// 排序
-	orderBy := c.DefaultQuery("order_by", "created_at")
-	orderDir := c.DefaultQuery("order_dir", "desc")
-	query = query.Order(orderBy + " " + orderDir)
+	query = applyTaskOrder(query, c.Query("order_by"), c.Query("order_dir"))
 
 	// 获取总数
 	var total int64
-	query.Count(&total)
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+		return
+	}
 
 	// 分页查询
-	var tasks []models.Task
+	tasks := []models.Task{}
 	if err := query.Preload("Category").Offset(offset).Limit(pageSize).Find(&tasks).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
 		return
@@ -294,34 +505,31 @@ func (pc *ProjectController) GetProjectTasks(c *gin.Context) {
 
 // 获取项目统计信息
 func (pc *ProjectController) GetProjectStats(c *gin.Context) {
-	userID := utils.GetUserID(c)
-	projectID := c.Param("id")
-
-	// 验证项目存在
-	var project models.Project
-	if err := pc.DB.Where("id = ? AND user_id = ?", projectID, userID).First(&project).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			utils.ErrorResponse(c, http.StatusNotFound, "项目不存在", nil)
-		} else {
-			utils.ErrorResponse(c, http.StatusInternalServerError, "查询项目失败", err)
-		}
+	projectID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	project, ok := pc.findProject(c, projectID)
+	if !ok {
 		return
 	}
+	ownerID := project.UserID
 
 	// 统计任务数量
 	var totalTasks, pendingTasks, inProgressTasks, completedTasks int64
 
-	pc.DB.Model(&models.Task{}).Where("project_id = ? AND user_id = ?", projectID, userID).Count(&totalTasks)
-	pc.DB.Model(&models.Task{}).Where("project_id = ? AND user_id = ? AND status = ?", projectID, userID, "pending").Count(&pendingTasks)
-	pc.DB.Model(&models.Task{}).Where("project_id = ? AND user_id = ? AND status = ?", projectID, userID, "in_progress").Count(&inProgressTasks)
-	pc.DB.Model(&models.Task{}).Where("project_id = ? AND user_id = ? AND status = ?", projectID, userID, "completed").Count(&completedTasks)
+	pc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).Where("project_id = ? AND user_id = ?", projectID, ownerID).Count(&totalTasks)
+	pc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).Where("project_id = ? AND user_id = ? AND status = ?", projectID, ownerID, "pending").Count(&pendingTasks)
+	pc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).Where("project_id = ? AND user_id = ? AND status = ?", projectID, ownerID, "in_progress").Count(&inProgressTasks)
+	pc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).Where("project_id = ? AND user_id = ? AND status = ?", projectID, ownerID, utils.CompletedTaskStatus()).Count(&completedTasks)
 
 	// 统计优先级分布
 	var lowPriorityTasks, mediumPriorityTasks, highPriorityTasks, urgentPriorityTasks int64
-	pc.DB.Model(&models.Task{}).Where("project_id = ? AND user_id = ? AND priority = ?", projectID, userID, "low").Count(&lowPriorityTasks)
-	pc.DB.Model(&models.Task{}).Where("project_id = ? AND user_id = ? AND priority = ?", projectID, userID, "medium").Count(&mediumPriorityTasks)
-	pc.DB.Model(&models.Task{}).Where("project_id = ? AND user_id = ? AND priority = ?", projectID, userID, "high").Count(&highPriorityTasks)
-	pc.DB.Model(&models.Task{}).Where("project_id = ? AND user_id = ? AND priority = ?", projectID, userID, "urgent").Count(&urgentPriorityTasks)
+	pc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).Where("project_id = ? AND user_id = ? AND priority = ?", projectID, ownerID, "low").Count(&lowPriorityTasks)
+	pc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).Where("project_id = ? AND user_id = ? AND priority = ?", projectID, ownerID, "medium").Count(&mediumPriorityTasks)
+	pc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).Where("project_id = ? AND user_id = ? AND priority = ?", projectID, ownerID, "high").Count(&highPriorityTasks)
+	pc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).Where("project_id = ? AND user_id = ? AND priority = ?", projectID, ownerID, "urgent").Count(&urgentPriorityTasks)
 
 	stats := gin.H{
 		"project":           project,
@@ -343,4 +551,93 @@ func (pc *ProjectController) GetProjectStats(c *gin.Context) {
 	}
 
 	utils.SuccessResponse(c, stats)
+}
+
+// 甘特图任务条
+type ganttItem struct {
+	ID           uint       `json:"id"`
+	Label        string     `json:"label"`
+	Start        *time.Time `json:"start"`
+	End          *time.Time `json:"end"`
+	Progress     float64    `json:"progress"`
+	Status       string     `json:"status"`
+	Dependencies []uint     `json:"dependencies"`
+}
+
+// 获取项目甘特图数据
+func (pc *ProjectController) GetProjectGantt(c *gin.Context) {
+	projectID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	project, ok := pc.findProject(c, projectID)
+	if !ok {
+		return
+	}
+	ownerID := project.UserID
+
+	var tasks []models.Task
+	if err := pc.DB.WithContext(c).Where("project_id = ? AND user_id = ?", projectID, ownerID).Find(&tasks).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+		return
+	}
+
+	scheduled := []ganttItem{}
+	unscheduled := []ganttItem{}
+	for _, task := range tasks {
+		item := ganttItem{
+			ID:           task.ID,
+			Label:        task.Title,
+			Start:        task.StartDate,
+			End:          task.DueDate,
+			Progress:     taskProgress(task.Status),
+			Status:       task.Status,
+			Dependencies: []uint{},
+		}
+
+		// 只有开始或截止日期之一时，按单日任务处理
+		if item.Start == nil {
+			item.Start = item.End
+		}
+		if item.End == nil {
+			item.End = item.Start
+		}
+
+		if item.Start == nil {
+			unscheduled = append(unscheduled, item)
+		} else {
+			scheduled = append(scheduled, item)
+		}
+	}
+
+	sort.SliceStable(scheduled, func(i, j int) bool {
+		if !scheduled[i].Start.Equal(*scheduled[j].Start) {
+			return scheduled[i].Start.Before(*scheduled[j].Start)
+		}
+		return scheduled[i].ID < scheduled[j].ID
+	})
+
+	utils.SuccessResponse(c, gin.H{
+		"project": gin.H{
+			"id":    project.ID,
+			"label": project.Name,
+			"start": project.StartDate,
+			"end":   project.EndDate,
+		},
+		"tasks":       scheduled,
+		"unscheduled": unscheduled,
+	})
+}
+
+// 按任务状态估算进度：初始状态为0，完成状态为100，其余中间状态为50
+func taskProgress(status string) float64 {
+	switch status {
+	case utils.CompletedTaskStatus():
+		return 100
+	case utils.InitialTaskStatus():
+		return 0
+	default:
+		return 50
+	}
 }
\ No newline at end of file
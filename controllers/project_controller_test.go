@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"personaltask/testutil"
+	"strings"
+	"testing"
+)
+
+func TestGetAllProjectStatsUsesGroupedCounts(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	fake.On("GROUP BY `tasks`.`project_id`", []string{"project_id", "total_tasks", "completed_tasks"},
+		[]driver.Value{int64(9), int64(4), int64(1)},
+		[]driver.Value{int64(8), int64(2), int64(2)},
+	)
+	fake.On("FROM `projects`", []string{"id", "name", "user_id"},
+		[]driver.Value{int64(9), "网站改版", int64(1)},
+		[]driver.Value{int64(8), "搬家", int64(1)},
+		[]driver.Value{int64(7), "空项目", int64(2)},
+	)
+	pc := &ProjectController{DB: db}
+
+	var stats []projectWithStats
+	decodeResponse(t, serveTest(t, pc.GetAllProjectStats, "GET", "/api/projects/stats", nil, 1), &stats)
+
+	// 每个项目的统计与按项目单独计数的结果一致，没有任务的项目为0
+	want := map[uint]struct {
+		total, completed int64
+		progress         float64
+	}{
+		9: {4, 1, 25},
+		8: {2, 2, 100},
+		7: {0, 0, 0},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("len(stats) = %d, want %d", len(stats), len(want))
+	}
+	for _, s := range stats {
+		w := want[s.ID]
+		if s.TotalTasks != w.total || s.CompletedTasks != w.completed || s.Progress != w.progress {
+			t.Errorf("项目 %d = %d/%d %.0f%%, want %d/%d %.0f%%", s.ID, s.CompletedTasks, s.TotalTasks, s.Progress, w.completed, w.total, w.progress)
+		}
+	}
+
+	// 与 GetProjectStats 的单项目计数口径相同：排除软删除的任务，只统计项目创建者的任务
+	queries := fake.Find("FROM `tasks`")
+	if len(queries) != 1 {
+		t.Fatalf("执行了 %d 次任务统计查询，want 1", len(queries))
+	}
+	for _, fragment := range []string{"tasks.deleted_at IS NULL", "projects.user_id = tasks.user_id", "tasks.project_id IN (?,?,?)"} {
+		if !strings.Contains(queries[0].SQL, fragment) {
+			t.Errorf("统计查询缺少 %q: %s", fragment, queries[0].SQL)
+		}
+	}
+}
+
+// 项目列表附带统计时，任务查询次数不随项目数量增长
+func TestGetProjectsWithStatsQueryCount(t *testing.T) {
+	for _, n := range []int{1, 5, 20} {
+		db, fake := testutil.NewFakeDB(t)
+		fake.On("SELECT count(*)", []string{"count"}, []driver.Value{int64(n)})
+		rows := make([][]driver.Value, 0, n)
+		for i := 1; i <= n; i++ {
+			rows = append(rows, []driver.Value{int64(i), fmt.Sprintf("项目%d", i), int64(1)})
+		}
+		fake.On("FROM `projects`", []string{"id", "name", "user_id"}, rows...)
+		pc := &ProjectController{DB: db}
+
+		w := serveTest(t, pc.GetProjects, "GET", "/api/projects?with_stats=true&page_size=50", nil, 1)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%d 个项目: status = %d, body = %s", n, w.Code, w.Body.String())
+		}
+		if queries := fake.Find("FROM `tasks`"); len(queries) != 1 {
+			t.Errorf("%d 个项目执行了 %d 次任务查询，want 1", n, len(queries))
+		}
+	}
+}
+
+func TestGetProjectsOrderParams(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantCode  int
+		wantOrder string
+	}{
+		{"", http.StatusOK, "ORDER BY created_at desc"},
+		{"order_by=name&order_dir=asc", http.StatusOK, "ORDER BY name asc"},
+		{"order_by=end_date", http.StatusOK, "ORDER BY end_date desc"},
+		{"order_by=id%3BDROP%20TABLE%20projects", http.StatusBadRequest, ""},
+		{"order_by=user_id", http.StatusBadRequest, ""},
+		{"order_dir=down", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			db, fake := testutil.NewFakeDB(t)
+			pc := &ProjectController{DB: db}
+
+			w := serveTest(t, pc.GetProjects, "GET", "/api/projects?"+tt.query, nil, 1)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			queries := fake.Find("SELECT * FROM `projects`")
+			if tt.wantOrder == "" {
+				if all := fake.Find(""); len(all) != 0 {
+					t.Errorf("参数不合法时不应执行查询，got %v", all)
+				}
+				return
+			}
+			if len(queries) != 1 || !strings.Contains(queries[0].SQL, tt.wantOrder) {
+				t.Errorf("查询 = %v, want 包含 %q", queries, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestGetProjectWithTasksUsesCountableTasks(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	fake.On("FROM `projects`", []string{"id", "name", "user_id"}, []driver.Value{int64(9), "网站改版", int64(1)})
+	fake.On("GROUP BY `tasks`.`project_id`", []string{"project_id", "total_tasks", "completed_tasks"},
+		[]driver.Value{int64(9), int64(2), int64(1)})
+	fake.On("SELECT * FROM `tasks`", []string{"id", "title", "project_id", "user_id"},
+		[]driver.Value{int64(5), "首页设计", int64(9), int64(1)},
+		[]driver.Value{int64(6), "接口联调", int64(9), int64(1)},
+	)
+	pc := &ProjectController{DB: db}
+
+	// 项目成员查看项目时，任务仍按项目创建者筛选
+	var resp projectWithStats
+	decodeResponse(t, serveTest(t, pc.GetProject, "GET", "/api/projects/9?with_tasks=true", nil, 2, withTaskID("9")), &resp)
+
+	if len(resp.Tasks) != 2 || resp.TotalTasks != 2 || resp.CompletedTasks != 1 {
+		t.Fatalf("tasks = %d, stats = %d/%d, want 2 个任务、1/2", len(resp.Tasks), resp.CompletedTasks, resp.TotalTasks)
+	}
+	preloads := fake.Find("SELECT * FROM `tasks`")
+	if len(preloads) != 1 {
+		t.Fatalf("执行了 %d 次任务加载，want 1", len(preloads))
+	}
+	for _, fragment := range []string{"tasks.deleted_at IS NULL", "EXISTS (SELECT 1 FROM categories", "EXISTS (SELECT 1 FROM projects", "user_id = ?"} {
+		if !strings.Contains(preloads[0].SQL, fragment) {
+			t.Errorf("任务加载缺少 %q: %s", fragment, preloads[0].SQL)
+		}
+	}
+	if !containsArg(preloads[0].Args, int64(1)) {
+		t.Errorf("任务加载应按项目创建者筛选，args = %v", preloads[0].Args)
+	}
+}
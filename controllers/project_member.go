@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"net/http"
+	"personaltask/models"
+	"personaltask/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 按ID查找项目，访问权限已由 ProjectAccess 中间件校验
+func (pc *ProjectController) findProject(c *gin.Context, projectID uint) (models.Project, bool) {
+	var project models.Project
+	if err := pc.DB.WithContext(c).First(&project, projectID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "项目不存在", nil)
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "查询项目失败", err)
+		}
+		return models.Project{}, false
+	}
+	return project, true
+}
+
+// 获取项目成员
+func (pc *ProjectController) GetProjectMembers(c *gin.Context) {
+	projectID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	members := []models.ProjectMember{}
+	if err := pc.DB.WithContext(c).Preload("User").Where("project_id = ?", projectID).Order("created_at asc").Find(&members).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询项目成员失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, members)
+}
+
+// 邀请项目成员，已是成员时更新其角色
+func (pc *ProjectController) AddProjectMember(c *gin.Context) {
+	projectID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.ProjectMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	project, ok := pc.findProject(c, projectID)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	if err := pc.DB.WithContext(c).Where("username = ?", utils.NormalizeUsername(req.Username)).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "用户不存在", nil)
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "查询用户失败", err)
+		}
+		return
+	}
+
+	if user.ID == project.UserID {
+		utils.ErrorResponse(c, http.StatusBadRequest, "不能邀请项目创建者", nil)
+		return
+	}
+
+	role := req.Role
+	if role == "" {
+		role = "viewer"
+	}
+
+	var member models.ProjectMember
+	err := pc.DB.WithContext(c).Where("project_id = ? AND user_id = ?", projectID, user.ID).First(&member).Error
+	switch {
+	case err == nil:
+		member.Role = role
+		err = pc.DB.WithContext(c).Save(&member).Error
+	case err == gorm.ErrRecordNotFound:
+		member = models.ProjectMember{ProjectID: projectID, UserID: user.ID, Role: role}
+		err = pc.DB.WithContext(c).Create(&member).Error
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "邀请项目成员失败", err)
+		return
+	}
+
+	member.User = user
+	utils.SuccessResponse(c, member)
+}
+
+// 按用户名移除项目成员
+func (pc *ProjectController) RemoveProjectMember(c *gin.Context) {
+	projectID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var user models.User
+	if err := pc.DB.WithContext(c).Where("username = ?", utils.NormalizeUsername(c.Param("username"))).First(&user).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "项目成员不存在", nil)
+		return
+	}
+
+	result := pc.DB.WithContext(c).Where("project_id = ? AND user_id = ?", projectID, user.ID).Delete(&models.ProjectMember{})
+	if result.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "移除项目成员失败", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "项目成员不存在", nil)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "项目成员已移除"})
+}
@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"net/http"
+	"personaltask/models"
+	"personaltask/testutil"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAddProjectMemberRejectsOwnerRole(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	pc := &ProjectController{DB: db}
+
+	w := serveTest(t, pc.AddProjectMember, "POST", "/api/projects/9/members", models.ProjectMemberRequest{Username: "bob", Role: "owner"}, 1, func(c *gin.Context) {
+		c.Params = gin.Params{{Key: "id", Value: "9"}}
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if writes := append(fake.Find("INSERT INTO"), fake.Find("UPDATE")...); len(writes) != 0 {
+		t.Errorf("不应写入成员记录，got %v", writes)
+	}
+}
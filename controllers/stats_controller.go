@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"personaltask/models"
 	"personaltask/utils"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,38 +13,130 @@ import (
 )
 
 type StatsController struct {
-	DB *gorm.DB
+	DB    *gorm.DB
+	Clock utils.Clock
 }
 
 func NewStatsController(db *gorm.DB) *StatsController {
-	return &StatsController{DB: db}
+	return &StatsController{DB: db, Clock: utils.NewRealClock()}
+}
+
+// 解析统计使用的时区（tz 参数，IANA名称），未提供时使用用户偏好设置中的时区（默认UTC），数据库中的时间均以UTC存储
+func statsLocation(c *gin.Context, db *gorm.DB) (*time.Location, bool) {
+	tz := c.Query("tz")
+	if tz == "" {
+		prefs, ok := currentUserPreferences(c, db)
+		if !ok {
+			return nil, false
+		}
+		tz = prefs.Timezone
+	}
+	loc, err := utils.ParseTimezone(tz)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "时区参数无效", err)
+		return nil, false
+	}
+	return loc, true
+}
+
+// 解析一天的起始小时（day_start_hour 参数，0-23），未提供时使用用户偏好设置（默认0点）
+func dayStartHourParam(c *gin.Context, db *gorm.DB) (int, bool) {
+	value := c.Query("day_start_hour")
+	if value == "" {
+		prefs, ok := currentUserPreferences(c, db)
+		if !ok {
+			return 0, false
+		}
+		return prefs.DayStartHour, true
+	}
+	hour, err := strconv.Atoi(value)
+	if err != nil || hour < 0 || hour > 23 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "day_start_hour 参数无效，应为0-23之间的整数", err)
+		return 0, false
+	}
+	return hour, true
+}
+
+// 解析每周起始日（week_start 参数，monday 或 sunday），未提供时使用用户偏好设置（默认周一）
+func weekStartParam(c *gin.Context, db *gorm.DB) (time.Weekday, bool) {
+	weekStart := c.Query("week_start")
+	if weekStart == "" {
+		prefs, ok := currentUserPreferences(c, db)
+		if !ok {
+			return 0, false
+		}
+		weekStart = prefs.WeekStart
+	}
+	switch weekStart {
+	case "monday":
+		return time.Monday, true
+	case "sunday":
+		return time.Sunday, true
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "week_start 参数无效，应为 monday 或 sunday", nil)
+		return 0, false
+	}
+}
+
+// 分类/项目统计中计入的任务：显式排除软删除的任务，以及所属分类或项目已被软删除的任务
+func countableTasks(db *gorm.DB) *gorm.DB {
+	return db.Where("tasks.deleted_at IS NULL").
+		Where("tasks.category_id IS NULL OR EXISTS (SELECT 1 FROM categories WHERE categories.id = tasks.category_id AND categories.deleted_at IS NULL)").
+		Where("tasks.project_id IS NULL OR EXISTS (SELECT 1 FROM projects WHERE projects.id = tasks.project_id AND projects.deleted_at IS NULL)")
 }
 
 // 任务概览统计
 func (sc *StatsController) GetOverview(c *gin.Context) {
-	userID := utils.GetUserID(c)
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
 
 	var overview models.StatsOverview
 
 	// 统计任务
-	sc.DB.Model(&models.Task{}).Where("user_id = ?", userID).Count(&overview.TotalTasks)
-	sc.DB.Model(&models.Task{}).Where("user_id = ? AND status = ?", userID, "pending").Count(&overview.PendingTasks)
-	sc.DB.Model(&models.Task{}).Where("user_id = ? AND status = ?", userID, "in_progress").Count(&overview.InProgressTasks)
-	sc.DB.Model(&models.Task{}).Where("user_id = ? AND status = ?", userID, "completed").Count(&overview.CompletedTasks)
+	sc.DB.WithContext(c).Model(&models.Task{}).Where("user_id = ?", userID).Count(&overview.TotalTasks)
+	sc.DB.WithContext(c).Model(&models.Task{}).Where("user_id = ? AND status = ?", userID, "pending").Count(&overview.PendingTasks)
+	sc.DB.WithContext(c).Model(&models.Task{}).Where("user_id = ? AND status = ?", userID, "in_progress").Count(&overview.InProgressTasks)
+	sc.DB.WithContext(c).Model(&models.Task{}).Where("user_id = ? AND status = ?", userID, utils.CompletedTaskStatus()).Count(&overview.CompletedTasks)
+
+	// 按优先级分组统计任务数量和未完成数量
+	var priorityRows []struct {
+		Priority  string
+		Total     int64
+		Remaining int64
+	}
+	sc.DB.WithContext(c).Model(&models.Task{}).
+		Select("priority, COUNT(*) AS total, COALESCE(SUM(CASE WHEN status != ? THEN 1 ELSE 0 END), 0) AS remaining", utils.CompletedTaskStatus()).
+		Where("user_id = ?", userID).
+		Group("priority").
+		Scan(&priorityRows)
+
+	overview.PriorityCounts = make(map[string]int64, len(utils.TaskPriorityWeights))
+	for priority := range utils.TaskPriorityWeights {
+		overview.PriorityCounts[priority] = 0
+	}
+	for _, row := range priorityRows {
+		overview.PriorityCounts[row.Priority] = row.Total
+		overview.WorkloadScore += row.Remaining * int64(utils.PriorityWeight(row.Priority))
+	}
 
 	// 统计项目
-	sc.DB.Model(&models.Project{}).Where("user_id = ?", userID).Count(&overview.TotalProjects)
-	sc.DB.Model(&models.Project{}).Where("user_id = ? AND status = ?", userID, "active").Count(&overview.ActiveProjects)
+	sc.DB.WithContext(c).Model(&models.Project{}).Where("user_id = ?", userID).Count(&overview.TotalProjects)
+	sc.DB.WithContext(c).Model(&models.Project{}).Where("user_id = ? AND status = ?", userID, "active").Count(&overview.ActiveProjects)
 
 	// 统计分类
-	sc.DB.Model(&models.Category{}).Where("user_id = ?", userID).Count(&overview.TotalCategories)
+	sc.DB.WithContext(c).Model(&models.Category{}).Where("user_id = ?", userID).Count(&overview.TotalCategories)
 
 	utils.SuccessResponse(c, overview)
 }
 
 // 每日任务统计
 func (sc *StatsController) GetDailyStats(c *gin.Context) {
-	userID := utils.GetUserID(c)
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
 
 	// 获取日期范围参数
 	daysStr := c.DefaultQuery("days", "7") // 默认最近7天
@@ -53,23 +147,29 @@ func (sc *StatsController) GetDailyStats(c *gin.Context) {
 		}
 	}
 
+	loc, ok := statsLocation(c, sc.DB)
+	if !ok {
+		return
+	}
+
 	var dailyStats []models.DailyStats
 
-	// 生成最近几天的统计数据
+	// 生成最近几天的统计数据（按用户时区划分自然日）
+	now := sc.Clock.Now().In(loc)
 	for i := days - 1; i >= 0; i-- {
-		date := time.Now().AddDate(0, 0, -i)
-		dateStr := date.Format("2006-01-02")
+		dayStart, dayEnd := utils.DayRange(now.AddDate(0, 0, -i))
+		dateStr := dayStart.Format("2006-01-02")
 
 		var tasksCreated, tasksCompleted int64
 
 		// 统计当天创建的任务
-		sc.DB.Model(&models.Task{}).
-			Where("user_id = ? AND DATE(created_at) = ?", userID, dateStr).
+		sc.DB.WithContext(c).Model(&models.Task{}).
+			Where("user_id = ? AND created_at >= ? AND created_at < ?", userID, dayStart, dayEnd).
 			Count(&tasksCreated)
 
 		// 统计当天完成的任务
-		sc.DB.Model(&models.Task{}).
-			Where("user_id = ? AND DATE(completed_at) = ?", userID, dateStr).
+		sc.DB.WithContext(c).Model(&models.Task{}).
+			Where("user_id = ? AND completed_at >= ? AND completed_at < ?", userID, dayStart, dayEnd).
 			Count(&tasksCompleted)
 
 		dailyStats = append(dailyStats, models.DailyStats{
@@ -84,7 +184,10 @@ func (sc *StatsController) GetDailyStats(c *gin.Context) {
 
 // 每周任务统计
 func (sc *StatsController) GetWeeklyStats(c *gin.Context) {
-	userID := utils.GetUserID(c)
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
 
 	// 获取周数参数
 	weeksStr := c.DefaultQuery("weeks", "4") // 默认最近4周
@@ -101,27 +204,38 @@ func (sc *StatsController) GetWeeklyStats(c *gin.Context) {
 		TasksCompleted int64  `json:"tasks_completed"`
 	}
 
+	loc, ok := statsLocation(c, sc.DB)
+	if !ok {
+		return
+	}
+
+	firstDay, ok := weekStartParam(c, sc.DB)
+	if !ok {
+		return
+	}
+
 	var weeklyStats []WeeklyStats
 
-	// 生成最近几周的统计数据
+	// 生成最近几周的统计数据（按用户时区划分自然日）
+	currentWeekStart := utils.WeekStart(sc.Clock.Now().In(loc), firstDay)
 	for i := weeks - 1; i >= 0; i-- {
-		// 计算周的开始和结束日期
-		now := time.Now()
-		weekStart := now.AddDate(0, 0, -int(now.Weekday())+1-i*7) // 本周周一
-		weekEnd := weekStart.AddDate(0, 0, 6)                      // 本周周日
+		// 计算周的开始和结束日期，相邻周首尾相接
+		weekStart := currentWeekStart.AddDate(0, 0, -i*7)
+		nextWeekStart := weekStart.AddDate(0, 0, 7)
+		weekEnd := nextWeekStart.AddDate(0, 0, -1)
 
 		weekStr := weekStart.Format("2006-01-02") + " 至 " + weekEnd.Format("2006-01-02")
 
 		var tasksCreated, tasksCompleted int64
 
 		// 统计本周创建的任务
-		sc.DB.Model(&models.Task{}).
-			Where("user_id = ? AND created_at >= ? AND created_at <= ?", userID, weekStart, weekEnd.Add(24*time.Hour)).
+		sc.DB.WithContext(c).Model(&models.Task{}).
+			Where("user_id = ? AND created_at >= ? AND created_at < ?", userID, weekStart, nextWeekStart).
 			Count(&tasksCreated)
 
 		// 统计本周完成的任务
-		sc.DB.Model(&models.Task{}).
-			Where("user_id = ? AND completed_at >= ? AND completed_at <= ?", userID, weekStart, weekEnd.Add(24*time.Hour)).
+		sc.DB.WithContext(c).Model(&models.Task{}).
+			Where("user_id = ? AND completed_at >= ? AND completed_at < ?", userID, weekStart, nextWeekStart).
 			Count(&tasksCompleted)
 
 		weeklyStats = append(weeklyStats, WeeklyStats{
@@ -136,12 +250,24 @@ func (sc *StatsController) GetWeeklyStats(c *gin.Context) {
 
 // 工作效率分析
 func (sc *StatsController) GetProductivityStats(c *gin.Context) {
-	userID := utils.GetUserID(c)
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	loc, ok := statsLocation(c, sc.DB)
+	if !ok {
+		return
+	}
+	dayStartHour, ok := dayStartHourParam(c, sc.DB)
+	if !ok {
+		return
+	}
+	now := sc.Clock.Now().In(loc)
 
 	// 基础统计
 	var totalTasks, completedTasks int64
-	sc.DB.Model(&models.Task{}).Where("user_id = ?", userID).Count(&totalTasks)
-	sc.DB.Model(&models.Task{}).Where("user_id = ? AND status = ?", userID, "completed").Count(&completedTasks)
+	sc.DB.WithContext(c).Model(&models.Task{}).Where("user_id = ?", userID).Count(&totalTasks)
+	sc.DB.WithContext(c).Model(&models.Task{}).Where("user_id = ? AND status = ?", userID, utils.CompletedTaskStatus()).Count(&completedTasks)
 
 	// 计算完成率
 	completionRate := 0.0
@@ -151,10 +277,10 @@ func (sc *StatsController) GetProductivityStats(c *gin.Context) {
 
 	// 优先级分布
 	var lowPriority, mediumPriority, highPriority, urgentPriority int64
-	sc.DB.Model(&models.Task{}).Where("user_id = ? AND priority = ?", userID, "low").Count(&lowPriority)
-	sc.DB.Model(&models.Task{}).Where("user_id = ? AND priority = ?", userID, "medium").Count(&mediumPriority)
-	sc.DB.Model(&models.Task{}).Where("user_id = ? AND priority = ?", userID, "high").Count(&highPriority)
-	sc.DB.Model(&models.Task{}).Where("user_id = ? AND priority = ?", userID, "urgent").Count(&urgentPriority)
+	sc.DB.WithContext(c).Model(&models.Task{}).Where("user_id = ? AND priority = ?", userID, "low").Count(&lowPriority)
+	sc.DB.WithContext(c).Model(&models.Task{}).Where("user_id = ? AND priority = ?", userID, "medium").Count(&mediumPriority)
+	sc.DB.WithContext(c).Model(&models.Task{}).Where("user_id = ? AND priority = ?", userID, "high").Count(&highPriority)
+	sc.DB.WithContext(c).Model(&models.Task{}).Where("user_id = ? AND priority = ?", userID, "urgent").Count(&urgentPriority)
 
 	// 每个优先级的完成率
 	priorityCompletionRates := make(map[string]float64)
@@ -162,8 +288,8 @@ func (sc *StatsController) GetProductivityStats(c *gin.Context) {
 	
 	for _, priority := range priorities {
 		var total, completed int64
-		sc.DB.Model(&models.Task{}).Where("user_id = ? AND priority = ?", userID, priority).Count(&total)
-		sc.DB.Model(&models.Task{}).Where("user_id = ? AND priority = ? AND status = ?", userID, priority, "completed").Count(&completed)
+		sc.DB.WithContext(c).Model(&models.Task{}).Where("user_id = ? AND priority = ?", userID, priority).Count(&total)
+		sc.DB.WithContext(c).Model(&models.Task{}).Where("user_id = ? AND priority = ? AND status = ?", userID, priority, utils.CompletedTaskStatus()).Count(&completed)
 		
 		rate := 0.0
 		if total > 0 {
@@ -179,26 +305,26 @@ func (sc *StatsController) GetProductivityStats(c *gin.Context) {
 	}
 	var result CompletionTime
 	
-	sc.DB.Raw(`
+	sc.DB.WithContext(c).Raw(`
 		SELECT AVG(TIMESTAMPDIFF(HOUR, created_at, completed_at)) as hours 
 		FROM tasks 
-		WHERE user_id = ? AND status = 'completed' AND completed_at IS NOT NULL
-	`, userID).Scan(&result)
+		WHERE user_id = ? AND status = ? AND completed_at IS NOT NULL AND deleted_at IS NULL
+	`, userID, utils.CompletedTaskStatus()).Scan(&result)
 	
 	avgCompletionTime = result.Hours
 
-	// 最近7天的工作效率趋势
+	// 最近7天的工作效率趋势（按一天的起始小时划分日期）
 	var recentProductivity []gin.H
 	for i := 6; i >= 0; i-- {
-		date := time.Now().AddDate(0, 0, -i)
-		dateStr := date.Format("2006-01-02")
+		dayStart, dayEnd := utils.DayRangeFrom(now.AddDate(0, 0, -i), dayStartHour)
+		dateStr := dayStart.Format("2006-01-02")
 
 		var created, completed int64
-		sc.DB.Model(&models.Task{}).
-			Where("user_id = ? AND DATE(created_at) = ?", userID, dateStr).
+		sc.DB.WithContext(c).Model(&models.Task{}).
+			Where("user_id = ? AND created_at >= ? AND created_at < ?", userID, dayStart, dayEnd).
 			Count(&created)
-		sc.DB.Model(&models.Task{}).
-			Where("user_id = ? AND DATE(completed_at) = ?", userID, dateStr).
+		sc.DB.WithContext(c).Model(&models.Task{}).
+			Where("user_id = ? AND completed_at >= ? AND completed_at < ?", userID, dayStart, dayEnd).
 			Count(&completed)
 
 		efficiency := 0.0
@@ -219,12 +345,12 @@ func (sc *StatsController) GetProductivityStats(c *gin.Context) {
 	// 分类效率分析
 	var categoryStats []gin.H
 	var categories []models.Category
-	sc.DB.Where("user_id = ?", userID).Find(&categories)
+	sc.DB.WithContext(c).Where("user_id = ?", userID).Find(&categories)
 
 	for _, category := range categories {
 		var total, completed int64
-		sc.DB.Model(&models.Task{}).Where("user_id = ? AND category_id = ?", userID, category.ID).Count(&total)
-		sc.DB.Model(&models.Task{}).Where("user_id = ? AND category_id = ? AND status = ?", userID, category.ID, "completed").Count(&completed)
+		sc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).Where("user_id = ? AND category_id = ?", userID, category.ID).Count(&total)
+		sc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).Where("user_id = ? AND category_id = ? AND status = ?", userID, category.ID, utils.CompletedTaskStatus()).Count(&completed)
 
 		rate := 0.0
 		if total > 0 {
@@ -241,19 +367,18 @@ func (sc *StatsController) GetProductivityStats(c *gin.Context) {
 
 	// 逾期任务统计
 	var overdueTasks int64
-	now := time.Now()
-	sc.DB.Model(&models.Task{}).
-		Where("user_id = ? AND status != ? AND due_date < ?", userID, "completed", now).
+	sc.DB.WithContext(c).Model(&models.Task{}).
+		Where("user_id = ? AND status != ? AND due_date < ?", userID, utils.CompletedTaskStatus(), now).
 		Count(&overdueTasks)
 
-	// 今日任务统计
-	today := now.Format("2006-01-02")
+	// 今日任务统计（一天从 day_start_hour 点开始）
+	todayStart, todayEnd := utils.DayRangeFrom(now, dayStartHour)
 	var todayTasks, todayCompleted int64
-	sc.DB.Model(&models.Task{}).
-		Where("user_id = ? AND DATE(due_date) = ?", userID, today).
+	sc.DB.WithContext(c).Model(&models.Task{}).
+		Where("user_id = ? AND due_date >= ? AND due_date < ?", userID, todayStart, todayEnd).
 		Count(&todayTasks)
-	sc.DB.Model(&models.Task{}).
-		Where("user_id = ? AND DATE(due_date) = ? AND status = ?", userID, today, "completed").
+	sc.DB.WithContext(c).Model(&models.Task{}).
+		Where("user_id = ? AND due_date >= ? AND due_date < ? AND status = ?", userID, todayStart, todayEnd, utils.CompletedTaskStatus()).
 		Count(&todayCompleted)
 
 	stats := gin.H{
@@ -290,11 +415,19 @@ func (sc *StatsController) GetProductivityStats(c *gin.Context) {
 
 // 获取月度报告
 func (sc *StatsController) GetMonthlyReport(c *gin.Context) {
-	userID := utils.GetUserID(c)
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+
+	loc, ok := statsLocation(c, sc.DB)
+	if !ok {
+		return
+	}
 
 	// 获取月份参数，默认当前月
-	monthStr := c.DefaultQuery("month", time.Now().Format("2006-01"))
-	month, err := time.Parse("2006-01", monthStr)
+	monthStr := c.DefaultQuery("month", sc.Clock.Now().In(loc).Format("2006-01"))
+	month, err := time.ParseInLocation("2006-01", monthStr, loc)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "月份格式错误，应为 YYYY-MM", err)
 		return
@@ -306,16 +439,29 @@ func (sc *StatsController) GetMonthlyReport(c *gin.Context) {
 
 	// 月度基础统计
 	var tasksCreated, tasksCompleted, tasksInProgress int64
-	sc.DB.Model(&models.Task{}).
+	sc.DB.WithContext(c).Model(&models.Task{}).
 		Where("user_id = ? AND created_at >= ? AND created_at <= ?", userID, monthStart, monthEnd).
 		Count(&tasksCreated)
-	sc.DB.Model(&models.Task{}).
+	sc.DB.WithContext(c).Model(&models.Task{}).
 		Where("user_id = ? AND completed_at >= ? AND completed_at <= ?", userID, monthStart, monthEnd).
 		Count(&tasksCompleted)
-	sc.DB.Model(&models.Task{}).
+	sc.DB.WithContext(c).Model(&models.Task{}).
 		Where("user_id = ? AND status = ? AND created_at >= ? AND created_at <= ?", userID, "in_progress", monthStart, monthEnd).
 		Count(&tasksInProgress)
 
+	// 本月到期的任务中已完成的数量，以及本月创建的任务中目前已完成的数量
+	// 与 completion_rate（本月完成数/本月创建数，可能超过100%）不同，这两个比率不会超过100%
+	var tasksDue, tasksDueCompleted, createdCompleted int64
+	sc.DB.WithContext(c).Model(&models.Task{}).
+		Where("user_id = ? AND due_date >= ? AND due_date <= ?", userID, monthStart, monthEnd).
+		Count(&tasksDue)
+	sc.DB.WithContext(c).Model(&models.Task{}).
+		Where("user_id = ? AND due_date >= ? AND due_date <= ? AND status = ?", userID, monthStart, monthEnd, utils.CompletedTaskStatus()).
+		Count(&tasksDueCompleted)
+	sc.DB.WithContext(c).Model(&models.Task{}).
+		Where("user_id = ? AND created_at >= ? AND created_at <= ? AND status = ?", userID, monthStart, monthEnd, utils.CompletedTaskStatus()).
+		Count(&createdCompleted)
+
 	// 每日创建/完成趋势
 	type DailyTrend struct {
 		Day       int   `json:"day"`
@@ -331,10 +477,10 @@ func (sc *StatsController) GetMonthlyReport(c *gin.Context) {
 		dayEnd := dayStart.Add(24*time.Hour - time.Second)
 		
 		var created, completed int64
-		sc.DB.Model(&models.Task{}).
+		sc.DB.WithContext(c).Model(&models.Task{}).
 			Where("user_id = ? AND created_at >= ? AND created_at <= ?", userID, dayStart, dayEnd).
 			Count(&created)
-		sc.DB.Model(&models.Task{}).
+		sc.DB.WithContext(c).Model(&models.Task{}).
 			Where("user_id = ? AND completed_at >= ? AND completed_at <= ?", userID, dayStart, dayEnd).
 			Count(&completed)
 			
@@ -348,12 +494,12 @@ func (sc *StatsController) GetMonthlyReport(c *gin.Context) {
 	// 项目进展统计
 	var projectProgress []gin.H
 	var projects []models.Project
-	sc.DB.Where("user_id = ?", userID).Find(&projects)
+	sc.DB.WithContext(c).Where("user_id = ?", userID).Find(&projects)
 	
 	for _, project := range projects {
 		var total, completed int64
-		sc.DB.Model(&models.Task{}).Where("project_id = ? AND user_id = ?", project.ID, userID).Count(&total)
-		sc.DB.Model(&models.Task{}).Where("project_id = ? AND user_id = ? AND status = ?", project.ID, userID, "completed").Count(&completed)
+		sc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).Where("project_id = ? AND user_id = ?", project.ID, userID).Count(&total)
+		sc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).Where("project_id = ? AND user_id = ? AND status = ?", project.ID, userID, utils.CompletedTaskStatus()).Count(&completed)
 		
 		progress := 0.0
 		if total > 0 {
@@ -380,10 +526,91 @@ func (sc *StatsController) GetMonthlyReport(c *gin.Context) {
 				}
 				return 0.0
 			}(),
+			"tasks_due":           tasksDue,
+			"tasks_due_completed": tasksDueCompleted,
+			"due_completion_rate": func() float64 {
+				if tasksDue > 0 {
+					return float64(tasksDueCompleted) / float64(tasksDue) * 100
+				}
+				return 0.0
+			}(),
+			"created_completed": createdCompleted,
+			"created_completion_rate": func() float64 {
+				if tasksCreated > 0 {
+					return float64(createdCompleted) / float64(tasksCreated) * 100
+				}
+				return 0.0
+			}(),
 		},
 		"daily_trends":     dailyTrends,
 		"project_progress": projectProgress,
 	}
 
 	utils.SuccessResponse(c, report)
+}
+
+// 工作量预测允许的最大天数
+const maxForecastDays = 90
+
+// 某一天到期的未完成任务数及预计耗时
+type forecastDay struct {
+	Date             string `json:"date"`
+	TaskCount        int    `json:"task_count"`
+	EstimatedMinutes int    `json:"estimated_minutes"` // 未估计耗时的任务不计入
+	UnestimatedTasks int    `json:"unestimated_tasks"`
+}
+
+// 获取未来 days 天（含今天，默认14天）每天到期的未完成任务数和预计耗时，没有任务的日期返回0
+// 日期按 tz 时区和一天的起始小时（day_start_hour）划分，不含已归档和已逾期的任务
+func (sc *StatsController) GetWorkloadForecast(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "14"))
+	if err != nil || days < 1 || days > maxForecastDays {
+		utils.ErrorResponse(c, http.StatusBadRequest, "days 参数无效，应为1-90之间的整数", err)
+		return
+	}
+	loc, ok := statsLocation(c, sc.DB)
+	if !ok {
+		return
+	}
+	dayStartHour, ok := dayStartHourParam(c, sc.DB)
+	if !ok {
+		return
+	}
+
+	now := sc.Clock.Now().In(loc)
+	forecast := make([]forecastDay, days)
+	bounds := make([]time.Time, days+1)
+	bounds[0], _ = utils.DayRangeFrom(now, dayStartHour)
+	for i := 0; i < days; i++ {
+		_, bounds[i+1] = utils.DayRangeFrom(bounds[i], dayStartHour)
+		forecast[i].Date = bounds[i].Format("2006-01-02")
+	}
+
+	var tasks []models.Task
+	if err := sc.DB.WithContext(c).Model(&models.Task{}).Scopes(countableTasks).
+		Select("due_date", "estimated_minutes").
+		Where("user_id = ? AND status != ? AND archived = ?", userID, utils.CompletedTaskStatus(), false).
+		Where("due_date >= ? AND due_date < ?", bounds[0], bounds[days]).
+		Find(&tasks).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+		return
+	}
+
+	for _, task := range tasks {
+		// bounds 递增，找到第一个晚于截止时间的边界即为所在日期的下一天
+		i := sort.Search(days, func(i int) bool { return bounds[i+1].After(*task.DueDate) })
+		forecast[i].TaskCount++
+		if task.EstimatedMinutes != nil {
+			forecast[i].EstimatedMinutes += *task.EstimatedMinutes
+		} else {
+			forecast[i].UnestimatedTasks++
+		}
+	}
+
+	utils.SuccessResponse(c, forecast)
 }
\ No newline at end of file
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"personaltask/models"
+	"personaltask/testutil"
+	"personaltask/utils"
+	"testing"
+	"time"
+)
+
+func TestGetDailyStatsUsesClock(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	fake.On("completed_at >= ?", []string{"count(*)"}, []driver.Value{int64(1)})
+	sc := &StatsController{DB: db, Clock: utils.NewFakeClock(time.Date(2024, 3, 1, 1, 0, 0, 0, time.UTC))}
+
+	var stats []models.DailyStats
+	decodeResponse(t, serveTest(t, sc.GetDailyStats, "GET", "/api/stats/daily?days=3&tz=UTC", nil, 1), &stats)
+
+	want := []string{"2024-02-28", "2024-02-29", "2024-03-01"}
+	if len(stats) != len(want) {
+		t.Fatalf("len(stats) = %d, want %d", len(stats), len(want))
+	}
+	for i, day := range stats {
+		if day.Date != want[i] {
+			t.Errorf("stats[%d].Date = %s, want %s", i, day.Date, want[i])
+		}
+		if day.TasksCreated != 0 || day.TasksCompleted != 1 {
+			t.Errorf("stats[%d] = %+v, want created 0 completed 1", i, day)
+		}
+	}
+}
+
+func TestGetWeeklyStatsUsesClockWeekBoundaries(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	// UTC 周日 20:00，上海已是周一，本周应从3月11日开始
+	sc := &StatsController{DB: db, Clock: utils.NewFakeClock(time.Date(2024, 3, 10, 20, 0, 0, 0, time.UTC))}
+
+	var stats []struct {
+		Week string `json:"week"`
+	}
+	decodeResponse(t, serveTest(t, sc.GetWeeklyStats, "GET", "/api/stats/weekly?weeks=2&tz=Asia/Shanghai&week_start=monday", nil, 1), &stats)
+
+	want := []string{"2024-03-04 至 2024-03-10", "2024-03-11 至 2024-03-17"}
+	if len(stats) != len(want) {
+		t.Fatalf("len(stats) = %d, want %d", len(stats), len(want))
+	}
+	for i, week := range stats {
+		if week.Week != want[i] {
+			t.Errorf("stats[%d].Week = %s, want %s", i, week.Week, want[i])
+		}
+	}
+
+	// 相邻周首尾相接，本周的查询范围为上海时间3月11日零点至3月18日零点
+	queries := fake.Find("created_at >= ?")
+	if len(queries) != 2 {
+		t.Fatalf("执行了 %d 次创建数查询，want 2", len(queries))
+	}
+	args := queries[1].Args
+	if start := args[1].(time.Time); !start.Equal(time.Date(2024, 3, 10, 16, 0, 0, 0, time.UTC)) {
+		t.Errorf("本周起始 = %v", start)
+	}
+	if end := args[2].(time.Time); !end.Equal(time.Date(2024, 3, 17, 16, 0, 0, 0, time.UTC)) {
+		t.Errorf("本周结束 = %v", end)
+	}
+	if prevEnd := queries[0].Args[2].(time.Time); !prevEnd.Equal(args[1].(time.Time)) {
+		t.Errorf("上周结束 %v 与本周起始 %v 不相接", prevEnd, args[1])
+	}
+}
+
+func TestGetWorkloadForecastAggregatesPerDay(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	fake.On("FROM `tasks`", []string{"due_date", "estimated_minutes"},
+		[]driver.Value{time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC), int64(30)},
+		[]driver.Value{time.Date(2024, 3, 10, 23, 59, 0, 0, time.UTC), nil},
+		[]driver.Value{time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC), int64(45)},
+		[]driver.Value{time.Date(2024, 3, 12, 9, 0, 0, 0, time.UTC), int64(15)},
+	)
+	sc := &StatsController{DB: db, Clock: utils.NewFakeClock(time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC))}
+
+	var forecast []forecastDay
+	decodeResponse(t, serveTest(t, sc.GetWorkloadForecast, "GET", "/api/stats/forecast?days=3&tz=UTC&day_start_hour=0", nil, 1), &forecast)
+
+	// 3月11日没有任务，仍返回0
+	want := []forecastDay{
+		{Date: "2024-03-10", TaskCount: 2, EstimatedMinutes: 30, UnestimatedTasks: 1},
+		{Date: "2024-03-11"},
+		{Date: "2024-03-12", TaskCount: 2, EstimatedMinutes: 60},
+	}
+	if len(forecast) != len(want) {
+		t.Fatalf("len(forecast) = %d, want %d", len(forecast), len(want))
+	}
+	for i := range want {
+		if forecast[i] != want[i] {
+			t.Errorf("forecast[%d] = %+v, want %+v", i, forecast[i], want[i])
+		}
+	}
+
+	queries := fake.Find("FROM `tasks`")
+	if len(queries) != 1 {
+		t.Fatalf("执行了 %d 次任务查询，want 1", len(queries))
+	}
+	args := queries[0].Args
+	start, end := args[len(args)-2].(time.Time), args[len(args)-1].(time.Time)
+	if !start.Equal(time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("查询范围 = %v ~ %v", start, end)
+	}
+}
+
+func TestGetWorkloadForecastUsesTimezoneAndDayStart(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	// 上海时间3月11日03:00，一天从4点开始时仍属于3月10日
+	fake.On("FROM `tasks`", []string{"due_date", "estimated_minutes"},
+		[]driver.Value{time.Date(2024, 3, 10, 19, 0, 0, 0, time.UTC), int64(20)},
+	)
+	sc := &StatsController{DB: db, Clock: utils.NewFakeClock(time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC))}
+
+	var forecast []forecastDay
+	decodeResponse(t, serveTest(t, sc.GetWorkloadForecast, "GET", "/api/stats/forecast?days=2&tz=Asia/Shanghai&day_start_hour=4", nil, 1), &forecast)
+
+	want := []forecastDay{
+		{Date: "2024-03-10", TaskCount: 1, EstimatedMinutes: 20},
+		{Date: "2024-03-11"},
+	}
+	if len(forecast) != len(want) {
+		t.Fatalf("len(forecast) = %d, want %d", len(forecast), len(want))
+	}
+	for i := range want {
+		if forecast[i] != want[i] {
+			t.Errorf("forecast[%d] = %+v, want %+v", i, forecast[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"personaltask/models"
+	"personaltask/testutil"
+	"personaltask/utils"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 批量操作的测试数据：用户1拥有任务1和3，任务2属于其他用户或不存在
+func newBatchFakeDB(t *testing.T) (*TaskController, *testutil.FakeDB) {
+	db, fake := testutil.NewFakeDB(t)
+	fake.On("SELECT `id` FROM `tasks`", []string{"id"}, []driver.Value{int64(1)}, []driver.Value{int64(3)})
+	return &TaskController{DB: db, Clock: utils.NewFakeClock(time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC))}, fake
+}
+
+// 断言返回400并列出无权限的任务ID，且没有执行任何修改
+func assertUnownedRejected(t *testing.T, w *httptest.ResponseRecorder, fake *testutil.FakeDB, want []uint) {
+	t.Helper()
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, body = %s, want 400", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			UnauthorizedIDs []uint `json:"unauthorized_ids"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Data.UnauthorizedIDs, want) {
+		t.Errorf("unauthorized_ids = %v, want %v", resp.Data.UnauthorizedIDs, want)
+	}
+	for _, prefix := range []string{"UPDATE", "DELETE", "INSERT"} {
+		if writes := fake.Find(prefix); len(writes) != 0 {
+			t.Errorf("存在无权限的任务时不应执行修改，got %v", writes)
+		}
+	}
+}
+
+func TestBatchUpdateTaskStatusReturnsUpdatedTasks(t *testing.T) {
+	tc, fake := newBatchFakeDB(t)
+	fake.On("SELECT `id`,`status` FROM `tasks`", []string{"id", "status"},
+		[]driver.Value{int64(1), "pending"},
+		[]driver.Value{int64(3), "completed"},
+	)
+	fake.On("FROM `tasks`", []string{"id", "title", "status", "user_id"},
+		[]driver.Value{int64(1), "写周报", "completed", int64(1)},
+		[]driver.Value{int64(3), "整理文档", "completed", int64(1)},
+	)
+
+	var resp struct {
+		AffectedCount int64         `json:"affected_count"`
+		Tasks         []models.Task `json:"tasks"`
+	}
+	body := gin.H{"task_ids": []uint{1, 3}, "status": "completed"}
+	decodeResponse(t, serveTest(t, tc.BatchUpdateTaskStatus, "PATCH", "/api/tasks/batch/status", body, 1), &resp)
+
+	if resp.AffectedCount != 1 || len(resp.Tasks) != 2 {
+		t.Fatalf("affected_count = %d, tasks = %+v", resp.AffectedCount, resp.Tasks)
+	}
+	// 只更新状态实际变化的任务
+	updates := fake.Find("UPDATE `tasks`")
+	if len(updates) != 1 || !containsArg(updates[0].Args, int64(1)) || containsArg(updates[0].Args, int64(3)) {
+		t.Errorf("updates = %v, want 只更新任务1", updates)
+	}
+}
+
+func TestBatchUpdateTaskStatusRejectsUnownedTasks(t *testing.T) {
+	tc, fake := newBatchFakeDB(t)
+
+	body := gin.H{"task_ids": []uint{1, 2, 3}, "status": "completed"}
+	w := serveTest(t, tc.BatchUpdateTaskStatus, "PATCH", "/api/tasks/batch/status", body, 1)
+	assertUnownedRejected(t, w, fake, []uint{2})
+}
+
+func TestBatchDeleteTasksRejectsUnownedTasks(t *testing.T) {
+	tc, fake := newBatchFakeDB(t)
+
+	w := serveTest(t, tc.BatchDeleteTasks, "DELETE", "/api/tasks/batch", gin.H{"task_ids": []uint{2, 1, 4}}, 1)
+	assertUnownedRejected(t, w, fake, []uint{2, 4})
+}
+
+func TestBatchDeleteTasksSoftDeletesOwnedTasks(t *testing.T) {
+	tc, fake := newBatchFakeDB(t)
+
+	// 重复的ID只计一次
+	w := serveTest(t, tc.BatchDeleteTasks, "DELETE", "/api/tasks/batch", gin.H{"task_ids": []uint{1, 3, 1}}, 1)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	deletes := fake.Find("UPDATE `tasks` SET `deleted_at`=?")
+	if len(deletes) != 1 || !containsArg(deletes[0].Args, int64(1)) || !containsArg(deletes[0].Args, int64(3)) {
+		t.Errorf("deletes = %v, want 软删除任务1和3", deletes)
+	}
+}
+
+func TestBatchArchiveTasks(t *testing.T) {
+	now := time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		archived       bool
+		wantArchivedAt driver.Value
+	}{
+		{"归档", true, now},
+		{"取消归档", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc, fake := newBatchFakeDB(t)
+
+			w := serveTest(t, tc.BatchArchiveTasks, "PATCH", "/api/tasks/batch/archive", gin.H{"task_ids": []uint{1, 3}, "archived": tt.archived}, 1)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+			}
+
+			updates := fake.Updated("tasks")
+			if len(updates) != 1 {
+				t.Fatalf("执行了 %d 次更新，want 1", len(updates))
+			}
+			if updates[0]["archived"] != tt.archived || updates[0]["archived_at"] != tt.wantArchivedAt {
+				t.Errorf("更新 = %v, want archived %v archived_at %v", updates[0], tt.archived, tt.wantArchivedAt)
+			}
+			if histories := fake.Inserted("task_histories"); len(histories) != 2 {
+				t.Errorf("记录了 %d 条历史，want 2", len(histories))
+			}
+		})
+	}
+}
+
+func TestBatchArchiveTasksRejectsUnownedTasks(t *testing.T) {
+	tc, fake := newBatchFakeDB(t)
+
+	w := serveTest(t, tc.BatchArchiveTasks, "PATCH", "/api/tasks/batch/archive", gin.H{"task_ids": []uint{1, 2, 3}, "archived": true}, 1)
+	assertUnownedRejected(t, w, fake, []uint{2})
+}
+
+func TestBatchDeleteTasksDryRun(t *testing.T) {
+	tc, fake := newBatchFakeDB(t)
+	fake.On("SELECT `id`,`title` FROM `tasks`", []string{"id", "title"},
+		[]driver.Value{int64(1), "写周报"},
+		[]driver.Value{int64(3), "整理文档"},
+	)
+
+	var resp struct {
+		DryRun        bool `json:"dry_run"`
+		AffectedCount int  `json:"affected_count"`
+		Tasks         []struct {
+			ID    uint   `json:"id"`
+			Title string `json:"title"`
+		} `json:"tasks"`
+	}
+	decodeResponse(t, serveTest(t, tc.BatchDeleteTasks, "DELETE", "/api/tasks/batch?dry_run=true", gin.H{"task_ids": []uint{3, 1}}, 1), &resp)
+
+	if !resp.DryRun || resp.AffectedCount != 2 || len(resp.Tasks) != 2 || resp.Tasks[0].Title != "写周报" || resp.Tasks[1].ID != 3 {
+		t.Errorf("预览结果 = %+v", resp)
+	}
+	for _, prefix := range []string{"UPDATE", "DELETE", "INSERT"} {
+		if writes := fake.Find(prefix); len(writes) != 0 {
+			t.Errorf("预览模式不应执行修改，got %v", writes)
+		}
+	}
+}
+
+func TestBatchDeleteTasksDryRunRejectsUnownedTasks(t *testing.T) {
+	tc, fake := newBatchFakeDB(t)
+
+	w := serveTest(t, tc.BatchDeleteTasks, "DELETE", "/api/tasks/batch?dry_run=true", gin.H{"task_ids": []uint{1, 2, 3}}, 1)
+	assertUnownedRejected(t, w, fake, []uint{2})
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"net/http"
+	"personaltask/models"
+	"personaltask/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 截止日期分组，按展示顺序排列
+var taskBucketNames = []string{"overdue", "today", "this_week", "later", "no_date"}
+
+// 按截止日期分组获取未完成任务（逾期/今天/本周/以后/无截止日期）
+// 分组按自然日划分：截止日期在今天之前为逾期，今天内（含今天已过的时刻）为今天
+// 分组边界按 tz 时区和 week_start 计算，每组返回总数和前 limit 条任务
+func (tc *TaskController) GetTaskBuckets(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+
+	loc, ok := statsLocation(c, tc.DB)
+	if !ok {
+		return
+	}
+	firstDay, ok := weekStartParam(c, tc.DB)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	if err != nil || limit < 0 || limit > 50 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "limit 参数无效，应为0-50之间的整数", err)
+		return
+	}
+
+	now := tc.Clock.Now().In(loc)
+	todayStart, tomorrow := utils.DayRange(now)
+	nextWeek := utils.WeekStart(now, firstDay).AddDate(0, 0, 7)
+
+	bucketScopes := map[string]func(*gorm.DB) *gorm.DB{
+		"overdue": func(db *gorm.DB) *gorm.DB {
+			return db.Where("due_date < ?", todayStart)
+		},
+		"today": func(db *gorm.DB) *gorm.DB {
+			return db.Where("due_date >= ? AND due_date < ?", todayStart, tomorrow)
+		},
+		"this_week": func(db *gorm.DB) *gorm.DB {
+			return db.Where("due_date >= ? AND due_date < ?", tomorrow, nextWeek)
+		},
+		"later": func(db *gorm.DB) *gorm.DB {
+			return db.Where("due_date >= ?", nextWeek)
+		},
+		"no_date": func(db *gorm.DB) *gorm.DB {
+			return db.Where("due_date IS NULL")
+		},
+	}
+
+	buckets := make(gin.H, len(taskBucketNames))
+	for _, name := range taskBucketNames {
+		base := tc.DB.WithContext(c).Model(&models.Task{}).
+			Where("user_id = ? AND status != ? AND archived = ?", userID, utils.CompletedTaskStatus(), false).
+			Scopes(bucketScopes[name])
+
+		var count int64
+		if err := base.Session(&gorm.Session{}).Count(&count).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+			return
+		}
+
+		tasks := []models.Task{}
+		if limit > 0 && count > 0 {
+			if err := base.Preload("Category").Preload("Project").
+				Order("due_date asc").Order(utils.PriorityWeightSQL() + " desc").Order("id asc").
+				Limit(limit).Find(&tasks).Error; err != nil {
+				utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+				return
+			}
+		}
+
+		buckets[name] = gin.H{
+			"count": count,
+			"tasks": tasks,
+		}
+	}
+
+	utils.SuccessResponse(c, buckets)
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"net/http"
+	"personaltask/testutil"
+	"personaltask/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetTaskBucketsBoundaries(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	// 2024-03-13 是周三
+	now := time.Date(2024, 3, 13, 10, 0, 0, 0, time.UTC)
+	tc := &TaskController{DB: db, Clock: utils.NewFakeClock(now)}
+
+	w := serveTest(t, tc.GetTaskBuckets, "GET", "/api/tasks/buckets?tz=UTC&week_start=monday&limit=0", nil, 1)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	// 各分组的计数查询按 taskBucketNames 的顺序执行，前三个参数为用户、状态和归档条件
+	counts := fake.Find("SELECT count(*) FROM `tasks`")
+	if len(counts) != len(taskBucketNames) {
+		t.Fatalf("执行了 %d 次计数查询，want %d", len(counts), len(taskBucketNames))
+	}
+	contains := func(stmt testutil.FakeStatement, due *time.Time) bool {
+		bounds := stmt.Args[3:]
+		switch {
+		case strings.Contains(stmt.SQL, "due_date IS NULL"):
+			return due == nil
+		case due == nil:
+			return false
+		case strings.Contains(stmt.SQL, "due_date >= ? AND due_date < ?"):
+			return !due.Before(bounds[0].(time.Time)) && due.Before(bounds[1].(time.Time))
+		case strings.Contains(stmt.SQL, "due_date < ?"):
+			return due.Before(bounds[0].(time.Time))
+		case strings.Contains(stmt.SQL, "due_date >= ?"):
+			return !due.Before(bounds[0].(time.Time))
+		}
+		t.Fatalf("无法识别的分组条件: %s", stmt.SQL)
+		return false
+	}
+
+	at := func(day, hour, min, sec int) *time.Time {
+		due := time.Date(2024, 3, day, hour, min, sec, 0, time.UTC)
+		return &due
+	}
+	tests := []struct {
+		name string
+		due  *time.Time
+		want string
+	}{
+		{"昨天最后一刻", at(12, 23, 59, 59), "overdue"},
+		{"今天零点", at(13, 0, 0, 0), "today"},
+		{"今天已过的时刻", at(13, 9, 0, 0), "today"},
+		{"今天最后一刻", at(13, 23, 59, 59), "today"},
+		{"明天零点", at(14, 0, 0, 0), "this_week"},
+		{"本周日最后一刻", at(17, 23, 59, 59), "this_week"},
+		{"下周一零点", at(18, 0, 0, 0), "later"},
+		{"无截止日期", nil, "no_date"},
+	}
+	for _, tt := range tests {
+		var got []string
+		for i, stmt := range counts {
+			if contains(stmt, tt.due) {
+				got = append(got, taskBucketNames[i])
+			}
+		}
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s: 分组 = %v, want [%s]", tt.name, got, tt.want)
+		}
+	}
+}
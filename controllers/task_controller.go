@@ -1,9 +1,17 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"personaltask/config"
+	"personaltask/jobs"
 	"personaltask/models"
 	"personaltask/utils"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,76 +19,358 @@ import (
 )
 
 type TaskController struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	Config *config.Config
+	Clock  utils.Clock
 }
 
-func NewTaskController(db *gorm.DB) *TaskController {
-	return &TaskController{DB: db}
+var errTaskNotOwned = errors.New("任务不存在或无权限")
+
+var errTaskVersionConflict = errors.New("任务已被修改，请刷新后重试")
+
+var errTaskQuotaExceeded = errors.New("任务数量已达上限")
+
+// 检查用户新增 count 个任务后是否超出任务配额，limit 为0表示不限制；已删除的任务不计入
+func checkTaskQuota(tx *gorm.DB, userID uint, limit, count int) error {
+	if limit <= 0 {
+		return nil
+	}
+	var total int64
+	if err := tx.Model(&models.Task{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return err
+	}
+	if total+int64(count) > int64(limit) {
+		return errTaskQuotaExceeded
+	}
+	return nil
 }
 
-// 获取任务列表
-func (tc *TaskController) GetTasks(c *gin.Context) {
-	userID := utils.GetUserID(c)
-	page, pageSize, offset := utils.GetPaginationParams(c)
+// 在事务中创建任务：检查配额、追加到任务列表末尾并记录创建历史
+func insertTask(tx *gorm.DB, task *models.Task, quota int) error {
+	if err := checkTaskQuota(tx, task.UserID, quota, 1); err != nil {
+		return err
+	}
 
-	// 构建查询
-	query := tc.DB.Model(&models.Task{}).Where("user_id = ?", userID)
+	position, err := nextTaskPosition(tx, task.UserID)
+	if err != nil {
+		return err
+	}
+	task.Position = position
+
+	if err := tx.Create(task).Error; err != nil {
+		return err
+	}
+	return recordTaskHistory(tx, task.ID, task.UserID, []taskChange{{Field: "created", NewValue: task.Title}})
+}
+
+// 任务数量超出配额时的响应
+func taskQuotaExceededResponse(c *gin.Context, limit int) {
+	utils.ErrorResponse(c, http.StatusForbidden, fmt.Sprintf("任务数量已达上限（%d个），请删除部分任务后再创建", limit), errTaskQuotaExceeded)
+}
+
+// 获取用户任务列表末尾的下一个位置
+func nextTaskPosition(tx *gorm.DB, userID uint) (int, error) {
+	var maxPosition int
+	err := tx.Model(&models.Task{}).Where("user_id = ?", userID).
+		Select("COALESCE(MAX(position), 0)").Scan(&maxPosition).Error
+	return maxPosition + 1, err
+}
+
+// 去除重复的任务ID，保留原有顺序
+func uniqueTaskIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// 找出不存在或不属于用户的任务ID
+func unownedTaskIDs(db *gorm.DB, userID uint, taskIDs []uint) ([]uint, error) {
+	var ownedIDs []uint
+	if err := db.Model(&models.Task{}).Where("id IN ? AND user_id = ?", taskIDs, userID).Pluck("id", &ownedIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(ownedIDs) == len(taskIDs) {
+		return nil, nil
+	}
+
+	owned := make(map[uint]bool, len(ownedIDs))
+	for _, id := range ownedIDs {
+		owned[id] = true
+	}
+	unowned := []uint{}
+	for _, id := range taskIDs {
+		if !owned[id] {
+			unowned = append(unowned, id)
+		}
+	}
+	return unowned, nil
+}
+
+// 批量操作中包含无权限任务时返回400，并列出这些任务ID
+func respondUnownedTasks(c *gin.Context, taskIDs []uint) {
+	c.JSON(http.StatusBadRequest, models.Response{
+		Code:      http.StatusBadRequest,
+		Message:   "部分任务不存在或无权限",
+		Data:      gin.H{"unauthorized_ids": taskIDs},
+		Timestamp: time.Now().UTC(),
+	})
+}
 
+func NewTaskController(db *gorm.DB, cfg *config.Config) *TaskController {
+	return &TaskController{
+		DB:     db,
+		Config: cfg,
+		Clock:  utils.NewRealClock(),
+	}
+}
+
+// 智能排序：未完成的任务在前，按截止日期升序（无截止日期的排在最后），截止日期相同时按优先级从高到低
+// 完成状态可配置，且已限定为小写字母、数字和下划线，可直接拼入SQL
+func smartTaskOrder() string {
+	return "status = '" + utils.CompletedTaskStatus() + "', due_date IS NULL, due_date ASC, " + utils.PriorityWeightSQL() + " DESC, id ASC"
+}
+
+// expand 参数可展开的任务关联
+var taskExpandAssociations = map[string]string{
+	"category": "Category",
+	"project":  "Project",
+	"comments": "Comments",
+}
+
+// 按 expand 参数（逗号分隔，如 expand=category,project）预加载关联，默认只返回关联ID
+func applyTaskExpand(c *gin.Context, query *gorm.DB) (*gorm.DB, bool) {
+	for _, name := range strings.Split(c.Query("expand"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		association, ok := taskExpandAssociations[name]
+		if !ok {
+			utils.ErrorResponse(c, http.StatusBadRequest, "expand 参数无效", fmt.Sprintf("不支持展开: %s", name))
+			return nil, false
+		}
+		if association == "Comments" {
+			query = query.Preload(association, func(db *gorm.DB) *gorm.DB {
+				return db.Order("created_at asc")
+			})
+		} else {
+			query = query.Preload(association)
+		}
+	}
+	return query, true
+}
+
+// 当前请求访问的任务所属用户ID（由 TaskAccess 中间件设置）
+// 项目成员访问共享项目中的任务时与当前用户不同，未经过该中间件时为当前用户
+func taskOwnerID(c *gin.Context, userID uint) uint {
+	if ownerID := c.GetUint("task_owner_id"); ownerID != 0 {
+		return ownerID
+	}
+	return userID
+}
+
+// 任务排序字段对应的排序表达式，优先级按权重而非字母顺序排序
+func taskOrderColumn(orderBy string) string {
+	if orderBy == "priority" {
+		return utils.PriorityWeightSQL()
+	}
+	return orderBy
+}
+
+// 按查询参数为任务查询添加过滤和排序条件（任务列表与保存的视图共用）
+func applyTaskFilters(query *gorm.DB, params url.Values) *gorm.DB {
 	// 状态过滤
-	if status := c.Query("status"); status != "" {
+	if status := params.Get("status"); status != "" {
 		if utils.IsValidTaskStatus(status) {
 			query = query.Where("status = ?", status)
 		}
 	}
 
 	// 优先级过滤
-	if priority := c.Query("priority"); priority != "" {
+	if priority := params.Get("priority"); priority != "" {
 		if utils.IsValidTaskPriority(priority) {
 			query = query.Where("priority = ?", priority)
 		}
 	}
 
 	// 分类过滤
-	if categoryID := c.Query("category_id"); categoryID != "" {
+	if categoryID := params.Get("category_id"); categoryID != "" {
 		query = query.Where("category_id = ?", categoryID)
 	}
 
 	// 项目过滤
-	if projectID := c.Query("project_id"); projectID != "" {
+	if projectID := params.Get("project_id"); projectID != "" {
 		query = query.Where("project_id = ?", projectID)
 	}
 
-	// 关键词搜索
-	if keyword := c.Query("keyword"); keyword != "" {
-		query = query.Where("title LIKE ? OR description LIKE ?", "%"+keyword+"%", "%"+keyword+"%")
+	// 关键词搜索（match 指定匹配方式，默认标题或描述包含关键词）
+	if keyword := params.Get("keyword"); keyword != "" {
+		switch params.Get("match") {
+		case "prefix":
+			query = query.Where("title COLLATE "+utils.SearchCollation+" LIKE ?", utils.LikePrefixPattern(keyword))
+		case "exact":
+			query = query.Where("title COLLATE "+utils.SearchCollation+" = ?", strings.TrimSpace(keyword))
+		default:
+			pattern := utils.LikePattern(keyword)
+			query = query.Where("title COLLATE "+utils.SearchCollation+" LIKE ? OR description COLLATE "+utils.SearchCollation+" LIKE ?", pattern, pattern)
+		}
+	}
+
+	// 日期范围过滤（创建时间、截止日期、完成时间；NULL 值不会匹配任何范围条件）
+	for _, filter := range taskDateFilters {
+		value := params.Get(filter.Param)
+		if value == "" {
+			continue
+		}
+		t, dateOnly, err := utils.ParseDate(value)
+		if err != nil {
+			continue // 调用方已校验，忽略非法取值
+		}
+		switch {
+		case !filter.Upper:
+			query = query.Where(filter.Column+" >= ?", t)
+		case dateOnly:
+			query = query.Where(filter.Column+" < ?", t.AddDate(0, 0, 1))
+		default:
+			query = query.Where(filter.Column+" <= ?", t)
+		}
+	}
+
+	// 标记过滤
+	if flagged := params.Get("flagged"); flagged == "true" || flagged == "false" {
+		query = query.Where("flagged = ?", flagged == "true")
+	}
+
+	// 归档过滤：默认隐藏已归档任务，archived=true 只看归档任务，include_archived=true 全部返回
+	if params.Get("archived") == "true" {
+		query = query.Where("archived = ?", true)
+	} else if params.Get("include_archived") != "true" {
+		query = query.Where("archived = ?", false)
 	}
 
-	// 日期范围过滤
-	if startDate := c.Query("start_date"); startDate != "" {
-		query = query.Where("created_at >= ?", startDate)
+	return applyTaskOrder(query, params.Get("order_by"), params.Get("order_dir"))
+}
+
+// 按排序参数为任务查询添加排序条件，不在 taskOrderFields 中的字段和非 asc/desc 的方向按默认值处理
+// 默认按创建时间倒序，自定义排序默认按位置升序
+func applyTaskOrder(query *gorm.DB, orderBy, orderDir string) *gorm.DB {
+	if !utils.Contains(taskOrderFields, orderBy) {
+		orderBy = "created_at"
+	}
+	if orderBy == "smart" {
+		return query.Order(smartTaskOrder())
+	}
+	if orderDir != "asc" && orderDir != "desc" {
+		orderDir = "desc"
+		if orderBy == "position" {
+			orderDir = "asc"
+		}
+	}
+	query = query.Order(taskOrderColumn(orderBy) + " " + orderDir)
+	if orderBy == "position" {
+		query = query.Order("id asc")
+	}
+	return query
+}
+
+// 任务日期筛选参数及对应的列，Upper 表示范围上限（只精确到日时包含当天整天）
+var taskDateFilters = []struct {
+	Param  string
+	Column string
+	Upper  bool
+}{
+	{"start_date", "created_at", false},
+	{"end_date", "created_at", true},
+	{"due_before", "due_date", true},
+	{"completed_after", "completed_at", false},
+	{"completed_before", "completed_at", true},
+}
+
+// 校验任务日期筛选参数、关键词匹配方式和排序参数，不合法时返回400
+func validateTaskFilterParams(c *gin.Context) bool {
+	if !utils.ValidateOrderParams(c, taskOrderFields) {
+		return false
+	}
+	for _, filter := range taskDateFilters {
+		if _, ok := utils.ParseDateParam(c, filter.Param); !ok {
+			return false
+		}
+	}
+	if match := c.Query("match"); match != "" && !utils.IsValidKeywordMatch(match) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "match 参数无效，应为 contains、prefix 或 exact", nil)
+		return false
+	}
+	return true
+}
+
+// 校验筛选参数中的分类和项目存在且属于当前用户，与创建/更新任务时的归属校验一致
+func (tc *TaskController) validateTaskFilterOwnership(c *gin.Context, userID uint) bool {
+	checks := []struct {
+		param   string
+		model   interface{}
+		message string
+	}{
+		{"category_id", &models.Category{}, "分类不存在或无权限"},
+		{"project_id", &models.Project{}, "项目不存在或无权限"},
+	}
+	for _, check := range checks {
+		value := c.Query(check.param)
+		if value == "" {
+			continue
+		}
+		if !isValidFilterID(value) {
+			utils.ErrorResponse(c, http.StatusBadRequest, check.message, nil)
+			return false
+		}
+		var count int64
+		if err := tc.DB.WithContext(c).Model(check.model).Where("id = ? AND user_id = ?", value, userID).Count(&count).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+			return false
+		}
+		if count == 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, check.message, nil)
+			return false
+		}
 	}
-	if endDate := c.Query("end_date"); endDate != "" {
-		query = query.Where("created_at <= ?", endDate)
+	return true
+}
+
+// 获取任务列表
+func (tc *TaskController) GetTasks(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
 	}
+	page, pageSize, offset := utils.GetPaginationParams(c)
 
-	// 截止日期过滤
-	if dueBefore := c.Query("due_before"); dueBefore != "" {
-		query = query.Where("due_date <= ?", dueBefore)
+	if !validateTaskFilterParams(c) || !tc.validateTaskFilterOwnership(c, userID) {
+		return
 	}
 
-	// 排序
-	orderBy := c.DefaultQuery("order_by", "created_at")
-	orderDir := c.DefaultQuery("order_dir", "desc")
-	query = query.Order(orderBy + " " + orderDir)
+	// 构建查询
+	query := applyTaskFilters(tc.DB.WithContext(c).Model(&models.Task{}).Where("user_id = ?", userID), c.Request.URL.Query())
+
+	query, ok = applyTaskExpand(c, query)
+	if !ok {
+		return
+	}
 
 	// 获取总数
 	var total int64
-	query.Count(&total)
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+		return
+	}
 
 	// 分页查询
-	var tasks []models.Task
-	if err := query.Preload("Category").Preload("Project").
-		Offset(offset).Limit(pageSize).Find(&tasks).Error; err != nil {
+	tasks := []models.Task{}
+	if err := query.Offset(offset).Limit(pageSize).Find(&tasks).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
 		return
 	}
@@ -90,18 +380,21 @@ func (tc *TaskController) GetTasks(c *gin.Context) {
 
 // 创建任务
 func (tc *TaskController) CreateTask(c *gin.Context) {
-	userID := utils.GetUserID(c)
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
 
 	var req models.TaskRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "请求参数错误", err)
+		utils.BindErrorResponse(c, err)
 		return
 	}
 
 	// 验证分类归属
 	if req.CategoryID != nil {
 		var category models.Category
-		if err := tc.DB.Where("id = ? AND user_id = ?", *req.CategoryID, userID).First(&category).Error; err != nil {
+		if err := tc.DB.WithContext(c).Where("id = ? AND user_id = ?", *req.CategoryID, userID).First(&category).Error; err != nil {
 			utils.ErrorResponse(c, http.StatusBadRequest, "分类不存在或无权限", err)
 			return
 		}
@@ -110,42 +403,197 @@ func (tc *TaskController) CreateTask(c *gin.Context) {
 	// 验证项目归属
 	if req.ProjectID != nil {
 		var project models.Project
-		if err := tc.DB.Where("id = ? AND user_id = ?", *req.ProjectID, userID).First(&project).Error; err != nil {
+		if err := tc.DB.WithContext(c).Where("id = ? AND user_id = ?", *req.ProjectID, userID).First(&project).Error; err != nil {
 			utils.ErrorResponse(c, http.StatusBadRequest, "项目不存在或无权限", err)
 			return
 		}
 	}
 
 	task := models.Task{
-		Title:       req.Title,
-		Description: req.Description,
-		Priority:    req.Priority,
-		DueDate:     req.DueDate,
-		UserID:      userID,
-		CategoryID:  req.CategoryID,
-		ProjectID:   req.ProjectID,
-		Status:      "pending",
+		Title:            req.Title,
+		Description:      req.Description,
+		Priority:         req.Priority,
+		StartDate:        req.StartDate,
+		DueDate:          req.DueDate,
+		UserID:           userID,
+		CategoryID:       req.CategoryID,
+		ProjectID:        req.ProjectID,
+		Flagged:          req.Flagged,
+		Color:            req.Color,
+		EstimatedMinutes: req.EstimatedMinutes,
+		Status:           utils.InitialTaskStatus(),
+		Version:          1,
+	}
+
+	// 未指定优先级时使用用户偏好或配置的默认优先级
+	if task.Priority == "" {
+		if task.Priority, ok = tc.defaultPriority(c); !ok {
+			return
+		}
 	}
 
-	if err := tc.DB.Create(&task).Error; err != nil {
+	err := tc.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		return insertTask(tx, &task, tc.Config.Task.MaxPerUser)
+	})
+	if errors.Is(err, errTaskQuotaExceeded) {
+		taskQuotaExceededResponse(c, tc.Config.Task.MaxPerUser)
+		return
+	}
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "任务创建失败", err)
 		return
 	}
 
 	// 重新查询以获取关联数据
-	tc.DB.Preload("Category").Preload("Project").First(&task, task.ID)
+	tc.DB.WithContext(c).Preload("Category").Preload("Project").First(&task, task.ID)
 
-	utils.SuccessResponse(c, task)
+	utils.CreatedResponse(c, fmt.Sprintf("/api/tasks/%d", task.ID), task)
+}
+
+// 新建任务未指定优先级时使用的默认优先级：用户偏好设置优先，其次为服务端配置
+func (tc *TaskController) defaultPriority(c *gin.Context) (string, bool) {
+	prefs, ok := currentUserPreferences(c, tc.DB)
+	if !ok {
+		return "", false
+	}
+	if prefs.DefaultPriority != "" {
+		return prefs.DefaultPriority, true
+	}
+	return tc.Config.Task.DefaultPriority, true
+}
+
+// 快速记录任务，只需提供标题，其余字段使用默认值
+// 标题中的快捷标记（#分类 !优先级 ^截止日期）会被解析并移除，日期按 tz 时区计算；分类不存在时自动创建
+func (tc *TaskController) QuickCreateTask(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.QuickTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	loc, ok := statsLocation(c, tc.DB)
+	if !ok {
+		return
+	}
+
+	parsed := utils.ParseQuickTask(req.Title, tc.Clock.Now().In(loc))
+	if parsed.Title == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "任务标题不能为空", nil)
+		return
+	}
+
+	task := models.Task{
+		Title:    parsed.Title,
+		Priority: parsed.Priority,
+		UserID:   userID,
+		Status:   utils.InitialTaskStatus(),
+		Version:  1,
+	}
+	if task.Priority == "" {
+		if task.Priority, ok = tc.defaultPriority(c); !ok {
+			return
+		}
+	}
+	if parsed.DueDate != nil {
+		dueDate := parsed.DueDate.UTC()
+		task.DueDate = &dueDate
+	}
+
+	err := tc.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if parsed.Category != "" {
+			categoryID, err := findOrCreateCategory(tx, userID, parsed.Category)
+			if err != nil {
+				return err
+			}
+			task.CategoryID = &categoryID
+		}
+		return insertTask(tx, &task, tc.Config.Task.MaxPerUser)
+	})
+	if errors.Is(err, errTaskQuotaExceeded) {
+		taskQuotaExceededResponse(c, tc.Config.Task.MaxPerUser)
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "任务创建失败", err)
+		return
+	}
+
+	tc.DB.WithContext(c).Preload("Category").First(&task, task.ID)
+
+	utils.CreatedResponse(c, fmt.Sprintf("/api/tasks/%d", task.ID), task)
+}
+
+// 按名称查找用户的分类，不存在时使用调色板中的下一个颜色创建
+func findOrCreateCategory(tx *gorm.DB, userID uint, name string) (uint, error) {
+	var category models.Category
+	err := tx.Where("name = ? AND user_id = ?", name, userID).First(&category).Error
+	if err == nil {
+		return category.ID, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return 0, err
+	}
+
+	var usedColors []string
+	if err := tx.Model(&models.Category{}).Where("user_id = ?", userID).Pluck("color", &usedColors).Error; err != nil {
+		return 0, err
+	}
+	category = models.Category{
+		Name:   name,
+		Color:  utils.NextPaletteColor(usedColors),
+		UserID: userID,
+	}
+	if err := tx.Create(&category).Error; err != nil {
+		return 0, err
+	}
+	return category.ID, nil
+}
+
+// 统计符合筛选条件的任务数量，筛选参数与任务列表一致
+func (tc *TaskController) CountTasks(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+
+	if !validateTaskFilterParams(c) || !tc.validateTaskFilterOwnership(c, userID) {
+		return
+	}
+
+	query := applyTaskFilters(tc.DB.WithContext(c).Model(&models.Task{}).Where("user_id = ?", userID), c.Request.URL.Query())
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "统计任务失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"count": count})
 }
 
 // 获取任务详情
 func (tc *TaskController) GetTask(c *gin.Context) {
-	userID := utils.GetUserID(c)
-	taskID := c.Param("id")
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	taskID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	query, ok := applyTaskExpand(c, tc.DB.WithContext(c))
+	if !ok {
+		return
+	}
 
 	var task models.Task
-	if err := tc.DB.Preload("Category").Preload("Project").
-		Where("id = ? AND user_id = ?", taskID, userID).First(&task).Error; err != nil {
+	if err := query.Where("id = ? AND user_id = ?", taskID, taskOwnerID(c, userID)).First(&task).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			utils.ErrorResponse(c, http.StatusNotFound, "任务不存在", nil)
 		} else {
@@ -154,23 +602,46 @@ func (tc *TaskController) GetTask(c *gin.Context) {
 		return
 	}
 
+	// 评论数量
+	var commentCount int64
+	tc.DB.WithContext(c).Model(&models.Comment{}).Where("task_id = ?", task.ID).Count(&commentCount)
+	task.CommentCount = &commentCount
+
+	// 按需返回描述的HTML渲染结果，原始描述保持不变
+	if c.Query("render") == "html" {
+		rendered := utils.RenderMarkdown(task.Description)
+		task.DescriptionHTML = &rendered
+	}
+
+	// 内容未变化时返回304，节省轮询客户端的流量
+	if etag, err := utils.ComputeETag(task); err == nil && utils.NotModified(c, etag) {
+		return
+	}
+
 	utils.SuccessResponse(c, task)
 }
 
 // 更新任务
 func (tc *TaskController) UpdateTask(c *gin.Context) {
-	userID := utils.GetUserID(c)
-	taskID := c.Param("id")
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	taskID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
 
 	var req models.TaskRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "请求参数错误", err)
+		utils.BindErrorResponse(c, err)
 		return
 	}
 
-	// 查找任务
+	// 查找任务（共享项目的编辑者修改的是项目中他人的任务，分类和项目也需属于任务创建者）
+	ownerID := taskOwnerID(c, userID)
 	var task models.Task
-	if err := tc.DB.Where("id = ? AND user_id = ?", taskID, userID).First(&task).Error; err != nil {
+	if err := tc.DB.WithContext(c).Where("id = ? AND user_id = ?", taskID, ownerID).First(&task).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			utils.ErrorResponse(c, http.StatusNotFound, "任务不存在", nil)
 		} else {
@@ -182,7 +653,7 @@ func (tc *TaskController) UpdateTask(c *gin.Context) {
 	// 验证分类归属
 	if req.CategoryID != nil {
 		var category models.Category
-		if err := tc.DB.Where("id = ? AND user_id = ?", *req.CategoryID, userID).First(&category).Error; err != nil {
+		if err := tc.DB.WithContext(c).Where("id = ? AND user_id = ?", *req.CategoryID, ownerID).First(&category).Error; err != nil {
 			utils.ErrorResponse(c, http.StatusBadRequest, "分类不存在或无权限", err)
 			return
 		}
@@ -191,45 +662,80 @@ func (tc *TaskController) UpdateTask(c *gin.Context) {
 	// 验证项目归属
 	if req.ProjectID != nil {
 		var project models.Project
-		if err := tc.DB.Where("id = ? AND user_id = ?", *req.ProjectID, userID).First(&project).Error; err != nil {
+		if err := tc.DB.WithContext(c).Where("id = ? AND user_id = ?", *req.ProjectID, ownerID).First(&project).Error; err != nil {
 			utils.ErrorResponse(c, http.StatusBadRequest, "项目不存在或无权限", err)
 			return
 		}
 	}
 
+	// 乐观锁：传入的版本号必须与当前版本一致
+	if req.Version != nil && *req.Version != task.Version {
+		utils.ErrorResponse(c, http.StatusConflict, errTaskVersionConflict.Error(), nil)
+		return
+	}
+
 	// 更新任务
+	original := task
+	task.Version++
 	task.Title = req.Title
 	task.Description = req.Description
 	task.Priority = req.Priority
+	task.StartDate = req.StartDate
 	task.DueDate = req.DueDate
 	task.CategoryID = req.CategoryID
 	task.ProjectID = req.ProjectID
+	task.Flagged = req.Flagged
+	task.Color = req.Color
+	task.EstimatedMinutes = req.EstimatedMinutes
 
-	if err := tc.DB.Save(&task).Error; err != nil {
+	err := tc.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// 只在版本号未变化时更新，防止并发修改互相覆盖
+		result := tx.Model(&task).Where("version = ?", original.Version).
+			Select("title", "description", "priority", "start_date", "due_date", "category_id", "project_id", "flagged", "color", "estimated_minutes", "version").
+			Updates(&task)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errTaskVersionConflict
+		}
+		return recordTaskHistory(tx, task.ID, userID, diffTask(original, task))
+	})
+	if err == errTaskVersionConflict {
+		utils.ErrorResponse(c, http.StatusConflict, err.Error(), nil)
+		return
+	}
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "任务更新失败", err)
 		return
 	}
 
 	// 重新查询以获取关联数据
-	tc.DB.Preload("Category").Preload("Project").First(&task, task.ID)
+	tc.DB.WithContext(c).Preload("Category").Preload("Project").First(&task, task.ID)
 
 	utils.SuccessResponse(c, task)
 }
 
 // 更新任务状态
 func (tc *TaskController) UpdateTaskStatus(c *gin.Context) {
-	userID := utils.GetUserID(c)
-	taskID := c.Param("id")
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	taskID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
 
 	var req models.TaskStatusRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "请求参数错误", err)
+		utils.BindErrorResponse(c, err)
 		return
 	}
 
 	// 查找任务
 	var task models.Task
-	if err := tc.DB.Where("id = ? AND user_id = ?", taskID, userID).First(&task).Error; err != nil {
+	if err := tc.DB.WithContext(c).Where("id = ? AND user_id = ?", taskID, taskOwnerID(c, userID)).First(&task).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			utils.ErrorResponse(c, http.StatusNotFound, "任务不存在", nil)
 		} else {
@@ -238,18 +744,37 @@ func (tc *TaskController) UpdateTaskStatus(c *gin.Context) {
 		return
 	}
 
+	// 状态未变化时直接返回，不重复记录完成时间
+	if task.Status == req.Status {
+		utils.SuccessResponse(c, task)
+		return
+	}
+
+	if !utils.CanTransitionTask(task.Status, req.Status, req.Reopen) {
+		utils.ErrorResponse(c, http.StatusConflict, fmt.Sprintf("不允许将任务状态从 %s 变更为 %s", task.Status, req.Status), nil)
+		return
+	}
+
 	// 更新状态
+	original := task
 	task.Status = req.Status
+	task.Version++
 
 	// 如果标记为完成，设置完成时间
-	if req.Status == "completed" && task.CompletedAt == nil {
-		now := time.Now()
+	if req.Status == utils.CompletedTaskStatus() && task.CompletedAt == nil {
+		now := tc.Clock.Now()
 		task.CompletedAt = &now
-	} else if req.Status != "completed" {
+	} else if req.Status != utils.CompletedTaskStatus() {
 		task.CompletedAt = nil
 	}
 
-	if err := tc.DB.Save(&task).Error; err != nil {
+	err := tc.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&task).Error; err != nil {
+			return err
+		}
+		return recordTaskHistory(tx, task.ID, userID, diffTask(original, task))
+	})
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "状态更新失败", err)
 		return
 	}
@@ -257,13 +782,395 @@ func (tc *TaskController) UpdateTaskStatus(c *gin.Context) {
 	utils.SuccessResponse(c, task)
 }
 
-// 删除任务
+// 将任务移动到其他项目，project_id 为null时移出项目
+func (tc *TaskController) MoveTaskProject(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	taskID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		ProjectID *uint `json:"project_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	var task models.Task
+	if err := tc.DB.WithContext(c).Where("id = ? AND user_id = ?", taskID, userID).First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "任务不存在", nil)
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+		}
+		return
+	}
+
+	// 验证目标项目归属
+	if req.ProjectID != nil {
+		var project models.Project
+		if err := tc.DB.WithContext(c).Where("id = ? AND user_id = ?", *req.ProjectID, userID).First(&project).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "项目不存在或无权限", err)
+			return
+		}
+	}
+
+	original := task
+	task.ProjectID = req.ProjectID
+	changes := diffTask(original, task)
+	if len(changes) > 0 {
+		task.Version++
+		err := tc.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&task).Error; err != nil {
+				return err
+			}
+			return recordTaskHistory(tx, task.ID, userID, changes)
+		})
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "任务移动失败", err)
+			return
+		}
+	}
+
+	tc.DB.WithContext(c).Preload("Project").First(&task, task.ID)
+
+	utils.SuccessResponse(c, task)
+}
+
+// 归档任务
+func (tc *TaskController) ArchiveTask(c *gin.Context) {
+	tc.setTaskArchived(c, true)
+}
+
+// 取消归档任务
+func (tc *TaskController) UnarchiveTask(c *gin.Context) {
+	tc.setTaskArchived(c, false)
+}
+
+func (tc *TaskController) setTaskArchived(c *gin.Context, archived bool) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	taskID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var task models.Task
+	if err := tc.DB.WithContext(c).Where("id = ? AND user_id = ?", taskID, taskOwnerID(c, userID)).First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "任务不存在", nil)
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+		}
+		return
+	}
+
+	// 状态未变化时直接返回
+	if task.Archived == archived {
+		utils.SuccessResponse(c, task)
+		return
+	}
+
+	original := task
+	task.Archived = archived
+	task.Version++
+	task.ArchivedAt = nil
+	if archived {
+		now := tc.Clock.Now()
+		task.ArchivedAt = &now
+	}
+
+	err := tc.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&task).Error; err != nil {
+			return err
+		}
+		return recordTaskHistory(tx, task.ID, userID, diffTask(original, task))
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "任务归档状态更新失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, task)
+}
+
+// 延后任务的截止日期（until 指定新的截止时间，by 按时长延后）
+// by 从原截止日期和当前时间中较晚的一个开始计算；已完成的任务不能延后
+func (tc *TaskController) SnoozeTask(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	taskID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.TaskSnoozeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+	if (req.Until == nil) == (req.By == "") {
+		utils.ErrorResponse(c, http.StatusBadRequest, "until 和 by 必须且只能提供一个", nil)
+		return
+	}
+	var by time.Duration
+	if req.By != "" {
+		parsed, err := utils.ParseDuration(req.By)
+		if err != nil || parsed <= 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "延后时长格式错误，示例: 1d、2h", err)
+			return
+		}
+		by = parsed
+	}
+
+	var task models.Task
+	if err := tc.DB.WithContext(c).Where("id = ? AND user_id = ?", taskID, taskOwnerID(c, userID)).First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "任务不存在", nil)
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+		}
+		return
+	}
+
+	if task.Status == utils.CompletedTaskStatus() {
+		utils.ErrorResponse(c, http.StatusConflict, "已完成的任务不能延后", nil)
+		return
+	}
+
+	now := tc.Clock.Now()
+	var dueDate time.Time
+	if req.Until != nil {
+		dueDate = req.Until.UTC()
+		if !dueDate.After(now) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "延后时间必须晚于当前时间", nil)
+			return
+		}
+	} else {
+		base := now
+		if task.DueDate != nil && task.DueDate.After(now) {
+			base = *task.DueDate
+		}
+		dueDate = base.Add(by)
+	}
+
+	previous := task.DueDate
+	task.DueDate = &dueDate
+	task.Version++
+	err := tc.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&task).Error; err != nil {
+			return err
+		}
+		return recordTaskHistory(tx, task.ID, userID, []taskChange{{
+			Field:    "snoozed",
+			OldValue: formatHistoryTime(previous),
+			NewValue: formatHistoryTime(task.DueDate),
+		}})
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "任务延后失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, task)
+}
+
+// 复制任务
+func (tc *TaskController) DuplicateTask(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	taskID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	// 可选的截止日期偏移量
+	var shift time.Duration
+	if shiftStr := c.Query("shift"); shiftStr != "" {
+		parsed, err := utils.ParseDuration(shiftStr)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "偏移量格式错误，示例: 1d、12h", err)
+			return
+		}
+		shift = parsed
+	}
+
+	// 查找原任务
+	var original models.Task
+	if err := tc.DB.WithContext(c).Where("id = ? AND user_id = ?", taskID, userID).First(&original).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "任务不存在", nil)
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+		}
+		return
+	}
+
+	task := models.Task{
+		Title:            original.Title + " (copy)",
+		Description:      original.Description,
+		Priority:         original.Priority,
+		UserID:           userID,
+		CategoryID:       original.CategoryID,
+		ProjectID:        original.ProjectID,
+		Flagged:          original.Flagged,
+		Color:            original.Color,
+		EstimatedMinutes: original.EstimatedMinutes,
+		Status:           utils.InitialTaskStatus(),
+		Version:          1,
+	}
+	if original.StartDate != nil {
+		startDate := original.StartDate.Add(shift)
+		task.StartDate = &startDate
+	}
+	if original.DueDate != nil {
+		dueDate := original.DueDate.Add(shift)
+		task.DueDate = &dueDate
+	}
+
+	err := tc.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		return insertTask(tx, &task, tc.Config.Task.MaxPerUser)
+	})
+	if errors.Is(err, errTaskQuotaExceeded) {
+		taskQuotaExceededResponse(c, tc.Config.Task.MaxPerUser)
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "任务复制失败", err)
+		return
+	}
+
+	// 重新查询以获取关联数据
+	tc.DB.WithContext(c).Preload("Category").Preload("Project").First(&task, task.ID)
+
+	utils.CreatedResponse(c, fmt.Sprintf("/api/tasks/%d", task.ID), task)
+}
+
+// 调整任务顺序
+// 只重排请求中的任务：沿用它们原有的位置集合按新顺序重新分配，其他任务位置不变
+func (tc *TaskController) ReorderTasks(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.TaskReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	// 检查重复ID
+	seen := make(map[uint]bool, len(req.TaskIDs))
+	for _, id := range req.TaskIDs {
+		if seen[id] {
+			utils.ErrorResponse(c, http.StatusBadRequest, "任务ID重复", nil)
+			return
+		}
+		seen[id] = true
+	}
+
+	err := tc.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var tasks []models.Task
+		if err := tx.Select("id", "position").Where("id IN ? AND user_id = ?", req.TaskIDs, userID).Find(&tasks).Error; err != nil {
+			return err
+		}
+		if len(tasks) != len(req.TaskIDs) {
+			return errTaskNotOwned
+		}
+
+		positions := make([]int, 0, len(tasks))
+		for _, task := range tasks {
+			positions = append(positions, task.Position)
+		}
+		sort.Ints(positions)
+
+		// 位置重复（如历史数据都为0）时，从最小位置开始连续分配；
+		// 补出的位置及之后的其他任务整体后移一位，避免与不在本次排序中的任务位置冲突
+		for i := 1; i < len(positions); i++ {
+			if positions[i] > positions[i-1] {
+				continue
+			}
+			slot := positions[i-1] + 1
+			if err := tx.Model(&models.Task{}).Where("user_id = ? AND position >= ? AND id NOT IN ?", userID, slot, req.TaskIDs).
+				UpdateColumn("position", gorm.Expr("position + 1")).Error; err != nil {
+				return err
+			}
+			for j := i + 1; j < len(positions); j++ {
+				if positions[j] >= slot {
+					positions[j]++
+				}
+			}
+			positions[i] = slot
+		}
+
+		for i, id := range req.TaskIDs {
+			if err := tx.Model(&models.Task{}).Where("id = ? AND user_id = ?", id, userID).
+				UpdateColumn("position", positions[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err == errTaskNotOwned {
+		utils.ErrorResponse(c, http.StatusBadRequest, "部分任务不存在或无权限", nil)
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "任务排序失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message":  "任务排序成功",
+		"task_ids": req.TaskIDs,
+	})
+}
+
+// 删除任务（默认软删除，permanent=true 时彻底删除）
 func (tc *TaskController) DeleteTask(c *gin.Context) {
-	userID := utils.GetUserID(c)
-	taskID := c.Param("id")
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	taskID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	// permanent=true 时彻底删除任务（包括已软删除的任务）及其评论、附件和变更历史
+	if c.Query("permanent") == "true" {
+		var task models.Task
+		if err := tc.DB.WithContext(c).Unscoped().Select("id").Where("id = ? AND user_id = ?", taskID, userID).First(&task).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				utils.ErrorResponse(c, http.StatusNotFound, "任务不存在", nil)
+			} else {
+				utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+			}
+			return
+		}
+		if _, err := jobs.PurgeTasks(tc.DB.WithContext(c), tc.Config.Upload.Dir, []uint{task.ID}); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "任务删除失败", err)
+			return
+		}
+		utils.SuccessResponse(c, gin.H{"message": "任务已彻底删除"})
+		return
+	}
 
 	// 软删除任务
-	if err := tc.DB.Where("id = ? AND user_id = ?", taskID, userID).Delete(&models.Task{}).Error; err != nil {
+	if err := tc.DB.WithContext(c).Where("id = ? AND user_id = ?", taskID, userID).Delete(&models.Task{}).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "任务删除失败", err)
 		return
 	}
@@ -272,60 +1179,254 @@ func (tc *TaskController) DeleteTask(c *gin.Context) {
 }
 
 // 批量更新任务状态
+// 任一任务不存在或不属于当前用户时整体拒绝并列出这些ID，任一任务不允许变更为目标状态时返回409并列出这些ID
+// 成功后返回更新后的任务（含分类和项目）
 func (tc *TaskController) BatchUpdateTaskStatus(c *gin.Context) {
-	userID := utils.GetUserID(c)
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
 
 	var req struct {
-		TaskIDs []uint `json:"task_ids" binding:"required"`
-		Status  string `json:"status" binding:"required,oneof=pending in_progress completed"`
+		TaskIDs []uint `json:"task_ids" binding:"required,min=1,max=100"`
+		Status  string `json:"status" binding:"required,task_status"`
+		Reopen  bool   `json:"reopen"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "请求参数错误", err)
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	// 校验任务归属，存在无权限的任务时整体拒绝
+	taskIDs := uniqueTaskIDs(req.TaskIDs)
+	unowned, err := unownedTaskIDs(tc.DB.WithContext(c), userID, taskIDs)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+		return
+	}
+	if len(unowned) > 0 {
+		respondUnownedTasks(c, unowned)
+		return
+	}
+
+	// 校验状态流转，存在不允许的变更时整体拒绝
+	var tasks []models.Task
+	if err := tc.DB.WithContext(c).Select("id", "status").Where("id IN ? AND user_id = ?", taskIDs, userID).Find(&tasks).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+		return
+	}
+	var changedIDs, illegalIDs []uint
+	for _, task := range tasks {
+		if task.Status == req.Status {
+			continue
+		}
+		if !utils.CanTransitionTask(task.Status, req.Status, req.Reopen) {
+			illegalIDs = append(illegalIDs, task.ID)
+			continue
+		}
+		changedIDs = append(changedIDs, task.ID)
+	}
+	if len(illegalIDs) > 0 {
+		c.JSON(http.StatusConflict, models.Response{
+			Code:      http.StatusConflict,
+			Message:   "部分任务不允许变更为该状态",
+			Data:      gin.H{"illegal_ids": illegalIDs},
+			Timestamp: time.Now().UTC(),
+		})
 		return
 	}
 
-	// 验证任务归属并更新
 	updates := map[string]interface{}{
-		"status": req.Status,
+		"status":  req.Status,
+		"version": gorm.Expr("version + 1"),
 	}
 
-	if req.Status == "completed" {
-		updates["completed_at"] = time.Now()
+	if req.Status == utils.CompletedTaskStatus() {
+		updates["completed_at"] = tc.Clock.Now()
 	} else {
 		updates["completed_at"] = nil
 	}
 
-	result := tc.DB.Model(&models.Task{}).
-		Where("id IN ? AND user_id = ?", req.TaskIDs, userID).
-		Updates(updates)
+	var affected int64
+	updated := []models.Task{}
+	err = tc.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// 只更新状态实际变化的任务，已处于目标状态的任务保持原完成时间
+		if len(changedIDs) > 0 {
+			result := tx.Model(&models.Task{}).
+				Where("id IN ? AND user_id = ? AND status != ?", changedIDs, userID, req.Status).
+				Updates(updates)
+			if result.Error != nil {
+				return result.Error
+			}
+			affected = result.RowsAffected
+		}
 
-	if result.Error != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "批量更新失败", result.Error)
+		for _, task := range tasks {
+			if task.Status == req.Status {
+				continue
+			}
+			change := taskChange{Field: "status", OldValue: task.Status, NewValue: req.Status}
+			if err := recordTaskHistory(tx, task.ID, userID, []taskChange{change}); err != nil {
+				return err
+			}
+		}
+
+		return tx.Preload("Category").Preload("Project").
+			Where("id IN ? AND user_id = ?", taskIDs, userID).
+			Order("id asc").Find(&updated).Error
+	})
+
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "批量更新失败", err)
 		return
 	}
 
 	utils.SuccessResponse(c, gin.H{
 		"message":        "批量更新成功",
-		"affected_count": result.RowsAffected,
+		"affected_count": affected,
+		"tasks":          updated,
+	})
+}
+
+// 批量归档或取消归档任务
+// 任一任务不存在或不属于当前用户时整体拒绝并列出这些ID，已处于目标状态的任务不计入影响数
+func (tc *TaskController) BatchArchiveTasks(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		TaskIDs  []uint `json:"task_ids" binding:"required,min=1,max=100"`
+		Archived *bool  `json:"archived" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+	archived := *req.Archived
+
+	// 校验任务归属，存在无权限的任务时整体拒绝
+	taskIDs := uniqueTaskIDs(req.TaskIDs)
+	unowned, err := unownedTaskIDs(tc.DB.WithContext(c), userID, taskIDs)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+		return
+	}
+	if len(unowned) > 0 {
+		respondUnownedTasks(c, unowned)
+		return
+	}
+
+	updates := map[string]interface{}{
+		"archived":    archived,
+		"archived_at": nil,
+		"version":     gorm.Expr("version + 1"),
+	}
+	if archived {
+		updates["archived_at"] = tc.Clock.Now()
+	}
+
+	var affected int64
+	err = tc.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// 只更新归档状态需要变化的任务
+		var changedIDs []uint
+		if err := tx.Model(&models.Task{}).
+			Where("id IN ? AND user_id = ? AND archived = ?", taskIDs, userID, !archived).
+			Pluck("id", &changedIDs).Error; err != nil {
+			return err
+		}
+		if len(changedIDs) == 0 {
+			return nil
+		}
+
+		result := tx.Model(&models.Task{}).
+			Where("id IN ? AND user_id = ?", changedIDs, userID).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		affected = result.RowsAffected
+
+		change := taskChange{Field: "archived", OldValue: strconv.FormatBool(!archived), NewValue: strconv.FormatBool(archived)}
+		for _, id := range changedIDs {
+			if err := recordTaskHistory(tx, id, userID, []taskChange{change}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "批量归档失败", err)
+		return
+	}
+
+	message := "批量归档成功"
+	if !archived {
+		message = "批量取消归档成功"
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message":        message,
+		"affected_count": affected,
 	})
 }
 
 // 批量删除任务
+// 任一任务不存在或不属于当前用户时整体拒绝并列出这些ID；dry_run=true 时只返回将被删除的任务
 func (tc *TaskController) BatchDeleteTasks(c *gin.Context) {
-	userID := utils.GetUserID(c)
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
 
 	var req struct {
-		TaskIDs []uint `json:"task_ids" binding:"required"`
+		TaskIDs []uint `json:"task_ids" binding:"required,min=1,max=100"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "请求参数错误", err)
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	// 校验任务归属，存在无权限的任务时整体拒绝
+	taskIDs := uniqueTaskIDs(req.TaskIDs)
+	unowned, err := unownedTaskIDs(tc.DB.WithContext(c), userID, taskIDs)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+		return
+	}
+	if len(unowned) > 0 {
+		respondUnownedTasks(c, unowned)
+		return
+	}
+
+	// 预览模式：只返回将被删除的任务，不做任何修改
+	if c.Query("dry_run") == "true" {
+		var tasks []struct {
+			ID    uint   `json:"id"`
+			Title string `json:"title"`
+		}
+		if err := tc.DB.WithContext(c).Model(&models.Task{}).Select("id", "title").
+			Where("id IN ? AND user_id = ?", taskIDs, userID).
+			Order("id asc").Find(&tasks).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+			return
+		}
+
+		utils.SuccessResponse(c, gin.H{
+			"dry_run":        true,
+			"affected_count": len(tasks),
+			"tasks":          tasks,
+		})
 		return
 	}
 
 	// 批量软删除
-	result := tc.DB.Where("id IN ? AND user_id = ?", req.TaskIDs, userID).Delete(&models.Task{})
+	result := tc.DB.WithContext(c).Where("id IN ? AND user_id = ?", taskIDs, userID).Delete(&models.Task{})
 
 	if result.Error != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "批量删除失败", result.Error)
@@ -0,0 +1,380 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"personaltask/config"
+	"personaltask/models"
+	"personaltask/testutil"
+	"personaltask/utils"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func withTaskID(id string) func(*gin.Context) {
+	return func(c *gin.Context) {
+		c.Params = gin.Params{{Key: "id", Value: id}}
+	}
+}
+
+func newUpdateTaskFakeDB(t *testing.T) (*TaskController, *testutil.FakeDB) {
+	db, fake := testutil.NewFakeDB(t)
+	fake.On("FROM `tasks`", []string{"id", "title", "status", "priority", "user_id", "version"},
+		[]driver.Value{int64(7), "旧标题", "pending", "low", int64(1), int64(3)},
+	)
+	fake.On("FROM `categories`", []string{"id", "name", "user_id"}, []driver.Value{int64(4), "工作", int64(1)})
+	fake.On("FROM `projects`", []string{"id", "name", "user_id"}, []driver.Value{int64(9), "网站改版", int64(1)})
+	return &TaskController{DB: db, Clock: utils.NewFakeClock(time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC))}, fake
+}
+
+// 请求中的每个可编辑字段都必须写入数据库，新增字段时漏加到更新列表会导致修改被静默丢弃
+func TestUpdateTaskPersistsEveryRequestField(t *testing.T) {
+	tc, fake := newUpdateTaskFakeDB(t)
+
+	start := time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC)
+	due := time.Date(2024, 3, 15, 18, 0, 0, 0, time.UTC)
+	categoryID, projectID, minutes, version := uint(4), uint(9), 45, 3
+	req := models.TaskRequest{
+		Title:            "新标题",
+		Description:      "新描述",
+		Priority:         "urgent",
+		StartDate:        &start,
+		DueDate:          &due,
+		CategoryID:       &categoryID,
+		ProjectID:        &projectID,
+		Flagged:          true,
+		Color:            "#ff8800",
+		EstimatedMinutes: &minutes,
+		Version:          &version,
+	}
+	want := map[string]driver.Value{
+		"title":             "新标题",
+		"description":       "新描述",
+		"priority":          "urgent",
+		"start_date":        start,
+		"due_date":          due,
+		"category_id":       int64(4),
+		"project_id":        int64(9),
+		"flagged":           true,
+		"color":             "#ff8800",
+		"estimated_minutes": int64(45),
+		"version":           int64(4),
+	}
+
+	// 确保测试覆盖 TaskRequest 的全部字段
+	reqType := reflect.TypeOf(req)
+	for i := 0; i < reqType.NumField(); i++ {
+		column := strings.Split(reqType.Field(i).Tag.Get("json"), ",")[0]
+		if _, ok := want[column]; !ok {
+			t.Fatalf("TaskRequest 新增了字段 %s，请补充到本测试中", column)
+		}
+	}
+
+	w := serveTest(t, tc.UpdateTask, "PUT", "/api/tasks/7", req, 1, withTaskID("7"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	updates := fake.Updated("tasks")
+	if len(updates) != 1 {
+		t.Fatalf("执行了 %d 次任务更新，want 1", len(updates))
+	}
+	for column, value := range want {
+		got, ok := updates[0][column]
+		if !ok {
+			t.Errorf("更新语句缺少列 %s", column)
+			continue
+		}
+		if gotTime, isTime := got.(time.Time); isTime {
+			if !gotTime.Equal(value.(time.Time)) {
+				t.Errorf("%s = %v, want %v", column, got, value)
+			}
+		} else if got != value {
+			t.Errorf("%s = %v, want %v", column, got, value)
+		}
+	}
+
+	// 修改的字段记录到任务历史
+	if history := fake.Inserted("task_histories"); len(history) == 0 {
+		t.Error("应记录任务修改历史")
+	}
+}
+
+func TestUpdateTaskRejectsStaleVersion(t *testing.T) {
+	tc, fake := newUpdateTaskFakeDB(t)
+
+	version := 2
+	w := serveTest(t, tc.UpdateTask, "PUT", "/api/tasks/7", models.TaskRequest{Title: "新标题", Version: &version}, 1, withTaskID("7"))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if updates := fake.Updated("tasks"); len(updates) != 0 {
+		t.Errorf("版本号不一致时不应更新任务，got %v", updates)
+	}
+}
+
+// 返回各任务被写入的新位置（任务ID到位置）
+func reorderedPositions(fake *testutil.FakeDB) map[int64]int64 {
+	positions := map[int64]int64{}
+	for _, stmt := range fake.Find("UPDATE `tasks` SET `position`=?") {
+		positions[stmt.Args[1].(int64)] = stmt.Args[0].(int64)
+	}
+	return positions
+}
+
+func TestReorderTasksPersistsRequestOrder(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	fake.On("FROM `tasks`", []string{"id", "position"},
+		[]driver.Value{int64(1), int64(10)},
+		[]driver.Value{int64(2), int64(20)},
+		[]driver.Value{int64(3), int64(30)},
+	)
+	tc := &TaskController{DB: db}
+
+	w := serveTest(t, tc.ReorderTasks, "PUT", "/api/tasks/reorder", models.TaskReorderRequest{TaskIDs: []uint{3, 1, 2}}, 1)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	// 沿用原有的位置集合，按请求顺序重新分配
+	want := map[int64]int64{3: 10, 1: 20, 2: 30}
+	if got := reorderedPositions(fake); !reflect.DeepEqual(got, want) {
+		t.Errorf("positions = %v, want %v", got, want)
+	}
+	if shifts := fake.Find("position + 1"); len(shifts) != 0 {
+		t.Errorf("位置不重复时不应移动其他任务，got %v", shifts)
+	}
+}
+
+func TestReorderTasksRepairsDuplicatePositions(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	fake.On("FROM `tasks`", []string{"id", "position"},
+		[]driver.Value{int64(1), int64(0)},
+		[]driver.Value{int64(2), int64(0)},
+		[]driver.Value{int64(3), int64(0)},
+	)
+	tc := &TaskController{DB: db}
+
+	w := serveTest(t, tc.ReorderTasks, "PUT", "/api/tasks/reorder", models.TaskReorderRequest{TaskIDs: []uint{2, 3, 1}}, 1)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	want := map[int64]int64{2: 0, 3: 1, 1: 2}
+	if got := reorderedPositions(fake); !reflect.DeepEqual(got, want) {
+		t.Errorf("positions = %v, want %v", got, want)
+	}
+
+	// 每补出一个位置，其他任务中位于该位置及之后的都后移一位
+	shifts := fake.Find("SET `position`=position + 1")
+	if len(shifts) != 2 {
+		t.Fatalf("执行了 %d 次后移，want 2", len(shifts))
+	}
+	for i, shift := range shifts {
+		if !strings.Contains(shift.SQL, "id NOT IN") || !containsArg(shift.Args, int64(i+1)) {
+			t.Errorf("第 %d 次后移 = %s %v, want 排除本次排序的任务并从位置 %d 开始", i+1, shift.SQL, shift.Args, i+1)
+		}
+	}
+}
+
+func TestReorderTasksRejectsUnownedTasks(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	fake.On("FROM `tasks`", []string{"id", "position"}, []driver.Value{int64(1), int64(0)})
+	tc := &TaskController{DB: db}
+
+	w := serveTest(t, tc.ReorderTasks, "PUT", "/api/tasks/reorder", models.TaskReorderRequest{TaskIDs: []uint{1, 2}}, 1)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if updates := fake.Find("UPDATE `tasks`"); len(updates) != 0 {
+		t.Errorf("存在无权限的任务时不应修改位置，got %v", updates)
+	}
+}
+
+func TestGetTasksOrdersByPosition(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	tc := &TaskController{DB: db, Clock: utils.NewFakeClock(time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC))}
+
+	w := serveTest(t, tc.GetTasks, "GET", "/api/tasks?order_by=position", nil, 1)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	// 位置相同时按ID排序，保证顺序稳定
+	queries := fake.Find("ORDER BY")
+	if len(queries) != 1 || !strings.Contains(queries[0].SQL, "ORDER BY position asc,id asc") {
+		t.Errorf("列表查询 = %v, want 按 position、id 升序", queries)
+	}
+}
+
+func TestDuplicateTaskCreatesShiftedCopy(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	due := time.Date(2024, 3, 15, 18, 0, 0, 0, time.UTC)
+	fake.On("id = ? AND user_id = ?", []string{"id", "title", "description", "status", "priority", "due_date", "user_id", "position"},
+		[]driver.Value{int64(7), "周报", "整理本周进展", "completed", "high", due, int64(1), int64(3)},
+	)
+	fake.On("MAX(position)", []string{"max"}, []driver.Value{int64(5)})
+	tc := &TaskController{DB: db, Config: &config.Config{}}
+
+	w := serveTest(t, tc.DuplicateTask, "POST", "/api/tasks/7/duplicate?shift=7d", nil, 1, withTaskID("7"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s, want 201", w.Code, w.Body.String())
+	}
+	var task models.Task
+	decodeResponse(t, w, &task)
+	if got, want := w.Header().Get("Location"), fmt.Sprintf("/api/tasks/%d", testutil.FakeFirstInsertID); got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+
+	rows := fake.Inserted("tasks")
+	if len(rows) != 1 {
+		t.Fatalf("插入了 %d 个任务，want 1", len(rows))
+	}
+	row := rows[0]
+	// 副本重置为初始状态，截止日期按偏移量顺延，排在列表末尾
+	want := map[string]driver.Value{
+		"title":       "周报 (copy)",
+		"description": "整理本周进展",
+		"priority":    "high",
+		"status":      utils.InitialTaskStatus(),
+		"due_date":    due.AddDate(0, 0, 7),
+		"user_id":     int64(1),
+		"position":    int64(6),
+	}
+	for column, value := range want {
+		if row[column] != value {
+			t.Errorf("%s = %v, want %v", column, row[column], value)
+		}
+	}
+	if len(fake.Inserted("task_histories")) != 1 {
+		t.Errorf("应记录一条创建历史")
+	}
+}
+
+func TestDuplicateTaskRejectsInvalidShift(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	tc := &TaskController{DB: db, Config: &config.Config{}}
+
+	w := serveTest(t, tc.DuplicateTask, "POST", "/api/tasks/7/duplicate?shift=soon", nil, 1, withTaskID("7"))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if inserts := fake.Find("INSERT INTO"); len(inserts) != 0 {
+		t.Errorf("偏移量无效时不应创建任务，got %v", inserts)
+	}
+}
+
+// 排序参数会拼接进SQL，不合法时直接返回400，不执行任何查询
+func TestTaskListsRejectInvalidOrderParams(t *testing.T) {
+	targets := []string{
+		"order_by=created_at%3BDROP%20TABLE%20tasks",
+		"order_by=password",
+		"order_by=due_date&order_dir=desc%2Cid",
+	}
+	for _, query := range targets {
+		db, fake := testutil.NewFakeDB(t)
+		tc := &TaskController{DB: db, Clock: utils.NewFakeClock(time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC))}
+		pc := &ProjectController{DB: db}
+		handlers := []struct {
+			name    string
+			handler gin.HandlerFunc
+			target  string
+		}{
+			{"GetTasks", tc.GetTasks, "/api/tasks?" + query},
+			{"CountTasks", tc.CountTasks, "/api/tasks/count?" + query},
+			{"GetProjectTasks", pc.GetProjectTasks, "/api/projects/9/tasks?" + query},
+		}
+		for _, h := range handlers {
+			w := serveTest(t, h.handler, "GET", h.target, nil, 1, withTaskID("9"))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%s %s: status = %d, want 400", h.name, query, w.Code)
+			}
+		}
+		if queries := fake.Find(""); len(queries) != 0 {
+			t.Errorf("%s: 参数不合法时不应执行查询，got %v", query, queries)
+		}
+	}
+}
+
+func TestGetProjectTasksOrdersByWhitelistedColumn(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	fake.On("FROM `projects`", []string{"id", "user_id"}, []driver.Value{int64(9), int64(1)})
+	pc := &ProjectController{DB: db}
+
+	w := serveTest(t, pc.GetProjectTasks, "GET", "/api/projects/9/tasks?order_by=position", nil, 1, withTaskID("9"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	queries := fake.Find("SELECT * FROM `tasks`")
+	if len(queries) != 1 || !strings.Contains(queries[0].SQL, "ORDER BY position asc,id asc") {
+		t.Errorf("列表查询 = %v, want 按 position、id 升序", queries)
+	}
+}
+
+func TestDeleteTaskSoftDeletes(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	tc := &TaskController{DB: db, Config: &config.Config{}}
+
+	w := serveTest(t, tc.DeleteTask, "DELETE", "/api/tasks/7", nil, 1, withTaskID("7"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	updates := fake.Find("UPDATE `tasks` SET `deleted_at`=?")
+	if len(updates) != 1 || !containsArg(updates[0].Args, int64(7)) || !containsArg(updates[0].Args, int64(1)) {
+		t.Errorf("软删除语句 = %v, want 按任务ID和用户ID设置 deleted_at", updates)
+	}
+	if deletes := fake.Find("DELETE"); len(deletes) != 0 {
+		t.Errorf("软删除不应物理删除记录: %v", deletes)
+	}
+}
+
+func TestDeleteTaskPermanentPurgesInOneTransaction(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	fake.On("FROM `tasks`", []string{"id"}, []driver.Value{int64(7)})
+	fake.On("SELECT `storage_path` FROM `attachments`", []string{"storage_path"}, []driver.Value{"uploads/report.pdf"})
+	uploadDir := t.TempDir()
+	file := filepath.Join(uploadDir, "report.pdf")
+	if err := os.WriteFile(file, []byte("pdf"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tc := &TaskController{DB: db, Config: &config.Config{Upload: config.UploadConfig{Dir: uploadDir}}}
+
+	w := serveTest(t, tc.DeleteTask, "DELETE", "/api/tasks/7?permanent=true", nil, 1, withTaskID("7"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	// 关联记录先于任务删除，且全部位于同一事务中
+	var got []string
+	inTx := false
+	for _, stmt := range fake.Find("") {
+		switch {
+		case stmt.SQL == "BEGIN":
+			inTx = true
+		case stmt.SQL == "COMMIT":
+			inTx = false
+		case strings.HasPrefix(stmt.SQL, "DELETE"):
+			if !inTx {
+				t.Errorf("语句不在事务中: %s", stmt.SQL)
+			}
+			got = append(got, stmt.SQL[strings.Index(stmt.SQL, "`"):strings.Index(stmt.SQL, " WHERE")])
+		}
+	}
+	want := []string{"`attachments`", "`comments`", "`task_histories`", "`tasks`"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("删除顺序 = %v, want %v", got, want)
+	}
+	if n := len(fake.Find("BEGIN")); n != 1 {
+		t.Errorf("开启了 %d 个事务，want 1", n)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("附件文件应被删除, stat err = %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"net/http"
+	"personaltask/models"
+	"personaltask/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 截止日期列表允许查询的最大天数
+const maxDueDateRangeDays = 366
+
+// 有任务到期的日期及当天到期的任务数
+type dueDateCount struct {
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
+
+// 获取 from 到 to（含）之间有任务到期的日期列表（用于日历标记），日期按 tz 时区划分
+// 默认只统计未完成且未归档的任务，include_completed=true 时包含已完成任务
+func (tc *TaskController) GetTaskDueDates(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+
+	loc, ok := statsLocation(c, tc.DB)
+	if !ok {
+		return
+	}
+
+	// 默认查询当月
+	now := tc.Clock.Now().In(loc)
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	to := from.AddDate(0, 1, -1)
+	for key, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		if value := c.Query(key); value != "" {
+			day, err := time.ParseInLocation("2006-01-02", value, loc)
+			if err != nil {
+				utils.ErrorResponse(c, http.StatusBadRequest, key+" 日期格式无效，应为 YYYY-MM-DD", err)
+				return
+			}
+			*target = day
+		}
+	}
+	if to.Before(from) || to.Sub(from) > maxDueDateRangeDays*24*time.Hour {
+		utils.ErrorResponse(c, http.StatusBadRequest, "日期范围无效，to 不能早于 from 且跨度不能超过366天", nil)
+		return
+	}
+
+	base := tc.DB.WithContext(c).Model(&models.Task{}).Where("user_id = ? AND archived = ?", userID, false)
+	if c.Query("include_completed") != "true" {
+		base = base.Where("status != ?", utils.CompletedTaskStatus())
+	}
+
+	dates := []dueDateCount{}
+
+	// 数据库按UTC存储，按时区偏移量平移后再 GROUP BY DATE；
+	// 偏移量相同的连续日期合并为一次查询，夏令时切换当天单独计数
+	var runStart, runEnd time.Time
+	runOffset := 0
+	flush := func() error {
+		if runStart.IsZero() {
+			return nil
+		}
+		var rows []struct {
+			Day   time.Time
+			Count int64
+		}
+		err := base.Session(&gorm.Session{}).
+			Select("DATE(due_date + INTERVAL ? SECOND) AS day, COUNT(*) AS count", runOffset).
+			Where("due_date >= ? AND due_date < ?", runStart, runEnd).
+			Group("day").Order("day asc").
+			Scan(&rows).Error
+		for _, row := range rows {
+			dates = append(dates, dueDateCount{Date: row.Day.Format("2006-01-02"), Count: row.Count})
+		}
+		runStart = time.Time{}
+		return err
+	}
+
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		dayStart, dayEnd := utils.DayRange(day)
+		_, startOffset := dayStart.Zone()
+		_, endOffset := dayEnd.Zone()
+
+		if startOffset != endOffset {
+			if err := flush(); err != nil {
+				utils.ErrorResponse(c, http.StatusInternalServerError, "查询截止日期失败", err)
+				return
+			}
+			var count int64
+			if err := base.Session(&gorm.Session{}).
+				Where("due_date >= ? AND due_date < ?", dayStart, dayEnd).
+				Count(&count).Error; err != nil {
+				utils.ErrorResponse(c, http.StatusInternalServerError, "查询截止日期失败", err)
+				return
+			}
+			if count > 0 {
+				dates = append(dates, dueDateCount{Date: dayStart.Format("2006-01-02"), Count: count})
+			}
+			continue
+		}
+
+		if !runStart.IsZero() && startOffset != runOffset {
+			if err := flush(); err != nil {
+				utils.ErrorResponse(c, http.StatusInternalServerError, "查询截止日期失败", err)
+				return
+			}
+		}
+		if runStart.IsZero() {
+			runStart, runOffset = dayStart, startOffset
+		}
+		runEnd = dayEnd
+	}
+	if err := flush(); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询截止日期失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"from":  from.Format("2006-01-02"),
+		"to":    to.Format("2006-01-02"),
+		"dates": dates,
+	})
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"personaltask/testutil"
+	"personaltask/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+type dueDatesResponse struct {
+	From  string         `json:"from"`
+	To    string         `json:"to"`
+	Dates []dueDateCount `json:"dates"`
+}
+
+func TestGetTaskDueDatesExcludesCompletedByDefault(t *testing.T) {
+	tests := []struct {
+		query          string
+		wantStatusCond bool
+	}{
+		{"", true},
+		{"&include_completed=false", true},
+		{"&include_completed=true", false},
+	}
+	for _, tt := range tests {
+		db, fake := testutil.NewFakeDB(t)
+		fake.On("DATE(due_date", []string{"day", "count"},
+			[]driver.Value{time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), int64(2)},
+		)
+		tc := &TaskController{DB: db, Clock: utils.NewFakeClock(time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC))}
+
+		var resp dueDatesResponse
+		decodeResponse(t, serveTest(t, tc.GetTaskDueDates, "GET", "/api/tasks/due-dates?tz=UTC"+tt.query, nil, 1), &resp)
+
+		// 默认查询当月
+		if resp.From != "2024-03-01" || resp.To != "2024-03-31" {
+			t.Errorf("%q: 范围 = %s ~ %s, want 2024-03-01 ~ 2024-03-31", tt.query, resp.From, resp.To)
+		}
+		if len(resp.Dates) != 1 || resp.Dates[0] != (dueDateCount{Date: "2024-03-05", Count: 2}) {
+			t.Errorf("%q: dates = %+v", tt.query, resp.Dates)
+		}
+
+		queries := fake.Find("DATE(due_date")
+		if len(queries) != 1 {
+			t.Fatalf("%q: 执行了 %d 次分组查询，want 1", tt.query, len(queries))
+		}
+		hasStatusCond := strings.Contains(queries[0].SQL, "status != ?")
+		if hasStatusCond != tt.wantStatusCond {
+			t.Errorf("%q: 包含状态条件 = %v, want %v，SQL = %s", tt.query, hasStatusCond, tt.wantStatusCond, queries[0].SQL)
+		}
+		if tt.wantStatusCond && !containsArg(queries[0].Args, utils.CompletedTaskStatus()) {
+			t.Errorf("%q: 状态条件参数应为 %s，args = %v", tt.query, utils.CompletedTaskStatus(), queries[0].Args)
+		}
+	}
+}
+
+func TestGetTaskDueDatesSplitsDSTDay(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	fake.On("count(*)", []string{"count"}, []driver.Value{int64(3)})
+	tc := &TaskController{DB: db, Clock: utils.NewFakeClock(time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC))}
+
+	// 2024-03-10 纽约进入夏令时：前后的日期按各自的偏移量分组，切换当天单独计数
+	var resp dueDatesResponse
+	decodeResponse(t, serveTest(t, tc.GetTaskDueDates, "GET", "/api/tasks/due-dates?tz=America/New_York&from=2024-03-08&to=2024-03-12", nil, 1), &resp)
+
+	groups := fake.Find("DATE(due_date")
+	if len(groups) != 2 {
+		t.Fatalf("执行了 %d 次分组查询，want 2", len(groups))
+	}
+	for i, want := range []int64{-5 * 3600, -4 * 3600} {
+		if groups[i].Args[0] != want {
+			t.Errorf("第 %d 次分组查询的偏移量 = %v, want %d", i+1, groups[i].Args[0], want)
+		}
+	}
+
+	counts := fake.Find("count(*)")
+	if len(counts) != 1 {
+		t.Fatalf("执行了 %d 次单日计数，want 1", len(counts))
+	}
+	args := counts[0].Args
+	start, end := args[len(args)-2].(time.Time), args[len(args)-1].(time.Time)
+	if !start.Equal(time.Date(2024, 3, 10, 5, 0, 0, 0, time.UTC)) || end.Sub(start) != 23*time.Hour {
+		t.Errorf("切换当天的范围 = %v ~ %v", start, end)
+	}
+	if len(resp.Dates) != 1 || resp.Dates[0] != (dueDateCount{Date: "2024-03-10", Count: 3}) {
+		t.Errorf("dates = %+v", resp.Dates)
+	}
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"net/http"
+	"personaltask/models"
+	"personaltask/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 单个字段的变更
+type taskChange struct {
+	Field    string
+	OldValue string
+	NewValue string
+}
+
+// 对比任务前后差异，返回发生变化的字段
+func diffTask(before, after models.Task) []taskChange {
+	var changes []taskChange
+	add := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, taskChange{Field: field, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+
+	add("title", before.Title, after.Title)
+	add("description", before.Description, after.Description)
+	add("status", before.Status, after.Status)
+	add("priority", before.Priority, after.Priority)
+	add("start_date", formatHistoryTime(before.StartDate), formatHistoryTime(after.StartDate))
+	add("due_date", formatHistoryTime(before.DueDate), formatHistoryTime(after.DueDate))
+	add("category_id", formatHistoryID(before.CategoryID), formatHistoryID(after.CategoryID))
+	add("project_id", formatHistoryID(before.ProjectID), formatHistoryID(after.ProjectID))
+	add("archived", strconv.FormatBool(before.Archived), strconv.FormatBool(after.Archived))
+	add("flagged", strconv.FormatBool(before.Flagged), strconv.FormatBool(after.Flagged))
+	add("color", before.Color, after.Color)
+	add("estimated_minutes", formatHistoryInt(before.EstimatedMinutes), formatHistoryInt(after.EstimatedMinutes))
+
+	return changes
+}
+
+// 在给定事务中写入任务变更历史
+func recordTaskHistory(tx *gorm.DB, taskID, userID uint, changes []taskChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	entries := make([]models.TaskHistory, 0, len(changes))
+	for _, change := range changes {
+		entries = append(entries, models.TaskHistory{
+			TaskID:   taskID,
+			UserID:   userID,
+			Field:    change.Field,
+			OldValue: change.OldValue,
+			NewValue: change.NewValue,
+		})
+	}
+	return tx.Create(&entries).Error
+}
+
+func formatHistoryTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatHistoryID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
+
+func formatHistoryInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+// 获取任务变更历史
+func (tc *TaskController) GetTaskHistory(c *gin.Context) {
+	taskID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+	page, pageSize, offset := utils.GetPaginationParams(c)
+
+	query := tc.DB.WithContext(c).Model(&models.TaskHistory{}).Where("task_id = ?", taskID)
+
+	// 获取总数
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务历史失败", err)
+		return
+	}
+
+	// 分页查询
+	history := []models.TaskHistory{}
+	if err := query.Order("created_at desc, id desc").Offset(offset).Limit(pageSize).Find(&history).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务历史失败", err)
+		return
+	}
+
+	utils.PaginatedResponse(c, history, total, page, pageSize)
+}
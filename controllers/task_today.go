@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"net/http"
+	"personaltask/models"
+	"personaltask/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 获取"今天"视图：今天到期和已逾期的未完成任务（不含已归档任务），附带分类和项目
+// 今天按 tz 时区和一天的起始小时（day_start_hour）划分，任务按优先级从高到低、再按截止时间排序
+func (tc *TaskController) GetTodayTasks(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+
+	loc, ok := statsLocation(c, tc.DB)
+	if !ok {
+		return
+	}
+	dayStartHour, ok := dayStartHourParam(c, tc.DB)
+	if !ok {
+		return
+	}
+
+	now := tc.Clock.Now().In(loc)
+	todayStart, todayEnd := utils.DayRangeFrom(now, dayStartHour)
+
+	tasks := []models.Task{}
+	if err := tc.DB.WithContext(c).Preload("Category").Preload("Project").
+		Where("user_id = ? AND status != ? AND archived = ?", userID, utils.CompletedTaskStatus(), false).
+		Where("due_date < ?", todayEnd).
+		Order(utils.PriorityWeightSQL() + " desc").Order("due_date asc").Order("id asc").
+		Find(&tasks).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+		return
+	}
+
+	// 截止时间早于当前时间的任务为逾期
+	var overdue int
+	for _, task := range tasks {
+		if task.DueDate.Before(now) {
+			overdue++
+		}
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"date":          todayStart.Format("2006-01-02"),
+		"overdue_count": overdue,
+		"today_count":   len(tasks) - overdue,
+		"tasks":         tasks,
+	})
+}
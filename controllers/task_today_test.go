@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"personaltask/testutil"
+	"personaltask/utils"
+	"testing"
+	"time"
+)
+
+func TestGetTodayTasksIncludesOverdueInTimezone(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		wantDate     string
+		wantTodayEnd time.Time
+	}{
+		// UTC 3月10日20:00，上海已是3月11日04:00
+		{"按时区划分", "tz=Asia/Shanghai", "2024-03-11", time.Date(2024, 3, 11, 16, 0, 0, 0, time.UTC)},
+		// 一天从5点开始时，上海04:00仍属于3月10日
+		{"按起始小时划分", "tz=Asia/Shanghai&day_start_hour=5", "2024-03-10", time.Date(2024, 3, 10, 21, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := testutil.NewFakeDB(t)
+			fake.On("FROM `tasks`", []string{"id", "title", "status", "user_id", "due_date"},
+				[]driver.Value{int64(1), "前天到期", "pending", int64(1), time.Date(2024, 3, 9, 1, 0, 0, 0, time.UTC)},
+				[]driver.Value{int64(2), "今天早些时候到期", "pending", int64(1), time.Date(2024, 3, 10, 17, 0, 0, 0, time.UTC)},
+				[]driver.Value{int64(3), "今天稍后到期", "in_progress", int64(1), time.Date(2024, 3, 11, 2, 0, 0, 0, time.UTC)},
+			)
+			tc := &TaskController{DB: db, Clock: utils.NewFakeClock(time.Date(2024, 3, 10, 20, 0, 0, 0, time.UTC))}
+
+			var resp struct {
+				Date         string `json:"date"`
+				OverdueCount int    `json:"overdue_count"`
+				TodayCount   int    `json:"today_count"`
+				Tasks        []struct {
+					ID uint `json:"id"`
+				} `json:"tasks"`
+			}
+			decodeResponse(t, serveTest(t, tc.GetTodayTasks, "GET", "/api/tasks/today?"+tt.query, nil, 1), &resp)
+
+			if resp.Date != tt.wantDate {
+				t.Errorf("date = %s, want %s", resp.Date, tt.wantDate)
+			}
+			// 截止时间早于当前时间的任务计为逾期，不论是否在今天之内
+			if resp.OverdueCount != 2 || resp.TodayCount != 1 || len(resp.Tasks) != 3 {
+				t.Errorf("overdue = %d, today = %d, tasks = %d, want 2, 1, 3", resp.OverdueCount, resp.TodayCount, len(resp.Tasks))
+			}
+
+			queries := fake.Find("FROM `tasks`")
+			if len(queries) != 1 {
+				t.Fatalf("执行了 %d 次任务查询，want 1", len(queries))
+			}
+			args := queries[0].Args
+			if !containsArg(args, utils.CompletedTaskStatus()) || !containsArg(args, false) {
+				t.Errorf("应排除已完成和已归档的任务，args = %v", args)
+			}
+			// 只有截止时间上限，已逾期的任务也包含在内
+			if end, ok := args[len(args)-1].(time.Time); !ok || !end.Equal(tt.wantTodayEnd) {
+				t.Errorf("截止时间上限 = %v, want %v", args[len(args)-1], tt.wantTodayEnd)
+			}
+		})
+	}
+}
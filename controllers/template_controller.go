@@ -0,0 +1,295 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"personaltask/config"
+	"personaltask/middleware"
+	"personaltask/models"
+	"personaltask/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TemplateController struct {
+	DB     *gorm.DB
+	Config *config.Config
+	Clock  utils.Clock
+}
+
+func NewTemplateController(db *gorm.DB, cfg *config.Config) *TemplateController {
+	return &TemplateController{DB: db, Config: cfg, Clock: utils.NewRealClock()}
+}
+
+// 将项目当前的未归档任务保存为模板
+// 截止日期保存为相对项目开始日期（未设置时为项目创建日期）的天数
+func (tc *TemplateController) SaveProjectTemplate(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.ProjectTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	// 检查模板名称是否已存在
+	var existingTemplate models.ProjectTemplate
+	if err := tc.DB.WithContext(c).Where("name = ? AND user_id = ?", req.Name, userID).First(&existingTemplate).Error; err == nil {
+		utils.ErrorResponse(c, http.StatusConflict, "模板名称已存在", nil)
+		return
+	}
+
+	var project models.Project
+	if err := tc.DB.WithContext(c).First(&project, projectID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询项目失败", err)
+		return
+	}
+
+	var tasks []models.Task
+	if err := tc.DB.WithContext(c).Where("project_id = ? AND user_id = ? AND archived = ?", projectID, project.UserID, false).
+		Order("position asc").Order("id asc").Find(&tasks).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+		return
+	}
+
+	base := project.CreatedAt
+	if project.StartDate != nil {
+		base = *project.StartDate
+	}
+	baseDay, _ := utils.DayRange(base.UTC())
+
+	template := models.ProjectTemplate{
+		Name:        req.Name,
+		Description: req.Description,
+		UserID:      userID,
+		Items:       make([]models.ProjectTemplateItem, 0, len(tasks)),
+	}
+	for i, task := range tasks {
+		item := models.ProjectTemplateItem{
+			Title:       task.Title,
+			Description: task.Description,
+			Priority:    task.Priority,
+			Position:    i,
+		}
+		if task.DueDate != nil {
+			dueDay, _ := utils.DayRange(task.DueDate.UTC())
+			offset := int(dueDay.Sub(baseDay).Hours() / 24)
+			item.DueOffsetDays = &offset
+		}
+		template.Items = append(template.Items, item)
+	}
+
+	if err := tc.DB.WithContext(c).Create(&template).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "模板保存失败", err)
+		return
+	}
+
+	utils.CreatedResponse(c, fmt.Sprintf("/api/templates/%d", template.ID), template)
+}
+
+// 获取模板列表
+func (tc *TemplateController) GetTemplates(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+
+	templates := []models.ProjectTemplate{}
+	if err := tc.DB.WithContext(c).Where("user_id = ?", userID).Order("name asc").Find(&templates).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询模板失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, templates)
+}
+
+// 获取模板详情（含模板任务）
+func (tc *TemplateController) GetTemplate(c *gin.Context) {
+	template, ok := tc.findTemplate(c)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, template)
+}
+
+// 删除模板
+func (tc *TemplateController) DeleteTemplate(c *gin.Context) {
+	template, ok := tc.findTemplate(c)
+	if !ok {
+		return
+	}
+
+	err := tc.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("template_id = ?", template.ID).Delete(&models.ProjectTemplateItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&template).Error
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "模板删除失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "模板删除成功"})
+}
+
+// 按模板生成任务：提供 project_id 时添加到已有项目（需为项目创建者或编辑者），否则按 name 新建项目
+// 任务截止日期 = 基准日期 + 模板中的相对天数，基准日期依次取 start_date、项目开始日期、当天
+func (tc *TemplateController) InstantiateTemplate(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.TemplateInstantiateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	template, ok := tc.findTemplate(c)
+	if !ok {
+		return
+	}
+
+	var project models.Project
+	if req.ProjectID != nil {
+		err := tc.DB.WithContext(c).First(&project, *req.ProjectID).Error
+		canEdit := false
+		if err == nil {
+			canEdit, err = tc.canEditProject(c, project, userID)
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "查询项目失败", err)
+			return
+		}
+		if !canEdit {
+			utils.ErrorResponse(c, http.StatusBadRequest, "项目不存在或无权限", nil)
+			return
+		}
+	} else {
+		if req.Name == "" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "未指定 project_id 时必须提供新项目名称", nil)
+			return
+		}
+		var existingProject models.Project
+		if err := tc.DB.WithContext(c).Where("name = ? AND user_id = ?", req.Name, userID).First(&existingProject).Error; err == nil {
+			utils.ErrorResponse(c, http.StatusConflict, "项目名称已存在", nil)
+			return
+		}
+		project = models.Project{
+			Name:        req.Name,
+			Description: template.Description,
+			StartDate:   req.StartDate,
+			UserID:      userID,
+		}
+	}
+
+	base := tc.Clock.Now()
+	if req.StartDate != nil {
+		base = *req.StartDate
+	} else if project.StartDate != nil {
+		base = *project.StartDate
+	}
+	baseDay, _ := utils.DayRange(base.UTC())
+
+	tasks := make([]models.Task, 0, len(template.Items))
+	err := tc.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if project.ID == 0 {
+			if err := tx.Create(&project).Error; err != nil {
+				return err
+			}
+		}
+
+		// 项目任务归属于项目创建者，计入其任务配额并追加到其任务列表末尾
+		if err := checkTaskQuota(tx, project.UserID, tc.Config.Task.MaxPerUser, len(template.Items)); err != nil {
+			return err
+		}
+		position, err := nextTaskPosition(tx, project.UserID)
+		if err != nil {
+			return err
+		}
+
+		for i, item := range template.Items {
+			task := models.Task{
+				Title:       item.Title,
+				Description: item.Description,
+				Priority:    item.Priority,
+				Status:      utils.InitialTaskStatus(),
+				UserID:      project.UserID,
+				ProjectID:   &project.ID,
+				Position:    position + i,
+				Version:     1,
+			}
+			if item.DueOffsetDays != nil {
+				dueDate := baseDay.AddDate(0, 0, *item.DueOffsetDays)
+				task.DueDate = &dueDate
+			}
+			if err := tx.Create(&task).Error; err != nil {
+				return err
+			}
+			if err := recordTaskHistory(tx, task.ID, userID, []taskChange{{Field: "created", NewValue: task.Title}}); err != nil {
+				return err
+			}
+			tasks = append(tasks, task)
+		}
+		return nil
+	})
+	if errors.Is(err, errTaskQuotaExceeded) {
+		taskQuotaExceededResponse(c, tc.Config.Task.MaxPerUser)
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "模板实例化失败", err)
+		return
+	}
+
+	utils.CreatedResponse(c, fmt.Sprintf("/api/projects/%d", project.ID), gin.H{
+		"project": project,
+		"tasks":   tasks,
+	})
+}
+
+// 当前用户是否可以向项目添加任务（项目创建者或编辑者），角色判断与 ProjectAccess 中间件一致
+func (tc *TemplateController) canEditProject(c *gin.Context, project models.Project, userID uint) (bool, error) {
+	role, err := middleware.ProjectRole(tc.DB.WithContext(c), project, userID)
+	if err != nil {
+		return false, err
+	}
+	return role == "owner" || role == "editor", nil
+}
+
+// 查找当前用户的模板（含模板任务）
+func (tc *TemplateController) findTemplate(c *gin.Context) (models.ProjectTemplate, bool) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return models.ProjectTemplate{}, false
+	}
+	templateID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return models.ProjectTemplate{}, false
+	}
+
+	var template models.ProjectTemplate
+	if err := tc.DB.WithContext(c).Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position asc")
+	}).Where("id = ? AND user_id = ?", templateID, userID).First(&template).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "模板不存在", nil)
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "查询模板失败", err)
+		}
+		return models.ProjectTemplate{}, false
+	}
+	return template, true
+}
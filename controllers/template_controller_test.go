@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"personaltask/config"
+	"personaltask/models"
+	"personaltask/testutil"
+	"personaltask/utils"
+	"testing"
+	"time"
+)
+
+// 模板3包含三个任务，截止日期分别为基准日期后0天、3天和不设置
+func newTemplateFakeDB(t *testing.T) (*TemplateController, *testutil.FakeDB) {
+	db, fake := testutil.NewFakeDB(t)
+	fake.On("FROM `project_templates`", []string{"id", "name", "description", "user_id"},
+		[]driver.Value{int64(3), "发布流程", "每次发布的检查项", int64(2)})
+	fake.On("FROM `project_template_items`", []string{"id", "template_id", "title", "priority", "due_offset_days", "position"},
+		[]driver.Value{int64(1), int64(3), "冻结代码", "high", int64(0), int64(0)},
+		[]driver.Value{int64(2), int64(3), "回归测试", "medium", int64(3), int64(1)},
+		[]driver.Value{int64(3), int64(3), "复盘", "low", nil, int64(2)},
+	)
+	tc := &TemplateController{DB: db, Config: &config.Config{}, Clock: utils.NewFakeClock(time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC))}
+	return tc, fake
+}
+
+func TestInstantiateTemplateShiftsDueDates(t *testing.T) {
+	tc, fake := newTemplateFakeDB(t)
+
+	start := time.Date(2024, 3, 11, 15, 0, 0, 0, time.UTC)
+	req := models.TemplateInstantiateRequest{Name: "四月发布", StartDate: &start}
+	w := serveTest(t, tc.InstantiateTemplate, "POST", "/api/templates/3/instantiate", req, 2, withTaskID("3"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s, want 201", w.Code, w.Body.String())
+	}
+
+	projects := fake.Inserted("projects")
+	if len(projects) != 1 || projects[0]["name"] != "四月发布" || projects[0]["user_id"] != int64(2) {
+		t.Fatalf("新建项目 = %v", projects)
+	}
+	tasks := fake.Inserted("tasks")
+	if len(tasks) != 3 {
+		t.Fatalf("创建了 %d 个任务，want 3", len(tasks))
+	}
+	// 截止日期以开始日期当天零点为基准按相对天数顺延
+	want := []driver.Value{
+		time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC),
+		nil,
+	}
+	for i, task := range tasks {
+		if task["due_date"] != want[i] {
+			t.Errorf("任务 %v 的截止日期 = %v, want %v", task["title"], task["due_date"], want[i])
+		}
+		if task["project_id"] != int64(testutil.FakeFirstInsertID) || task["user_id"] != int64(2) {
+			t.Errorf("任务 %v 应属于新项目和当前用户，got %v", task["title"], task)
+		}
+	}
+}
+
+func TestInstantiateTemplateIntoSharedProject(t *testing.T) {
+	tests := []struct {
+		name       string
+		memberRole string
+		want       int
+	}{
+		{"editor", "editor", http.StatusCreated},
+		{"viewer", "viewer", http.StatusBadRequest},
+		{"非成员", "", http.StatusBadRequest},
+		// 早期写入的 owner 成员与 ProjectAccess 一致按 editor 处理
+		{"早期写入的owner成员", "owner", http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc, fake := newTemplateFakeDB(t)
+			fake.On("FROM `projects`", []string{"id", "name", "user_id"}, []driver.Value{int64(9), "网站改版", int64(1)})
+			if tt.memberRole != "" {
+				fake.On("FROM `project_members`", []string{"id", "project_id", "user_id", "role"},
+					[]driver.Value{int64(1), int64(9), int64(2), tt.memberRole})
+			}
+
+			projectID := uint(9)
+			w := serveTest(t, tc.InstantiateTemplate, "POST", "/api/templates/3/instantiate", models.TemplateInstantiateRequest{ProjectID: &projectID}, 2, withTaskID("3"))
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, body = %s, want %d", w.Code, w.Body.String(), tt.want)
+			}
+
+			tasks := fake.Inserted("tasks")
+			if tt.want != http.StatusCreated {
+				if len(tasks) != 0 {
+					t.Errorf("无权限时不应创建任务，got %v", tasks)
+				}
+				return
+			}
+			// 项目任务归属于项目创建者
+			if len(tasks) != 3 || tasks[0]["user_id"] != int64(1) || tasks[0]["project_id"] != int64(9) {
+				t.Errorf("创建的任务 = %v", tasks)
+			}
+		})
+	}
+}
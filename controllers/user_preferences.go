@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"personaltask/models"
+	"personaltask/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 请求上下文中缓存当前用户偏好设置的键
+const userPreferencesKey = "user_preferences"
+
+// 用户尚未保存偏好设置时使用的默认值
+func defaultUserPreferences(userID uint) models.UserPreferences {
+	return models.UserPreferences{
+		UserID:    userID,
+		Timezone:  "UTC",
+		WeekStart: "monday",
+		Theme:     "system",
+	}
+}
+
+// 查询用户的偏好设置，尚未保存过时返回默认值
+func findUserPreferences(db *gorm.DB, userID uint) (models.UserPreferences, error) {
+	var prefs models.UserPreferences
+	err := db.Where("user_id = ?", userID).First(&prefs).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return defaultUserPreferences(userID), nil
+	}
+	return prefs, err
+}
+
+// 获取当前用户的偏好设置，同一请求内只查询一次
+func currentUserPreferences(c *gin.Context, db *gorm.DB) (models.UserPreferences, bool) {
+	if cached, exists := c.Get(userPreferencesKey); exists {
+		return cached.(models.UserPreferences), true
+	}
+
+	prefs, err := findUserPreferences(db.WithContext(c), utils.GetUserID(c))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询偏好设置失败", err)
+		return models.UserPreferences{}, false
+	}
+	c.Set(userPreferencesKey, prefs)
+	return prefs, true
+}
+
+// 获取偏好设置
+func (ac *AuthController) GetPreferences(c *gin.Context) {
+	if _, ok := utils.RequireUserID(c); !ok {
+		return
+	}
+
+	prefs, ok := currentUserPreferences(c, ac.DB)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, ac.withDefaultPriority(prefs))
+}
+
+// 更新偏好设置（只更新请求中出现的字段）
+func (ac *AuthController) UpdatePreferences(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UserPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	if req.Timezone != nil {
+		if _, err := utils.ParseTimezone(*req.Timezone); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "时区无效", err)
+			return
+		}
+	}
+
+	prefs, err := findUserPreferences(ac.DB.WithContext(c), userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询偏好设置失败", err)
+		return
+	}
+
+	if req.Timezone != nil {
+		prefs.Timezone = *req.Timezone
+	}
+	if req.WeekStart != nil {
+		prefs.WeekStart = *req.WeekStart
+	}
+	if req.DayStartHour != nil {
+		prefs.DayStartHour = *req.DayStartHour
+	}
+	if req.DefaultPriority != nil {
+		prefs.DefaultPriority = *req.DefaultPriority
+	}
+	if req.EmailOptOut != nil {
+		prefs.EmailOptOut = *req.EmailOptOut
+	}
+	if req.Theme != nil {
+		prefs.Theme = *req.Theme
+	}
+
+	// 首次保存时创建记录
+	if err := ac.DB.WithContext(c).Save(&prefs).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "偏好设置更新失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, ac.withDefaultPriority(prefs))
+}
+
+// 未设置默认优先级时返回服务端配置的默认优先级
+func (ac *AuthController) withDefaultPriority(prefs models.UserPreferences) models.UserPreferences {
+	if prefs.DefaultPriority == "" {
+		prefs.DefaultPriority = ac.Config.Task.DefaultPriority
+	}
+	return prefs
+}
@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"personaltask/models"
+	"personaltask/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ViewController struct {
+	DB *gorm.DB
+}
+
+func NewViewController(db *gorm.DB) *ViewController {
+	return &ViewController{DB: db}
+}
+
+// 允许排序的任务字段
+var taskOrderFields = []string{"created_at", "updated_at", "start_date", "due_date", "completed_at", "priority", "status", "title", "position", "smart"}
+
+// 保存视图允许的筛选参数及其取值校验，排序字段也在此限定，避免拼接任意SQL
+var viewFilterRules = map[string]func(string) bool{
+	"status":           utils.IsValidTaskStatus,
+	"priority":         utils.IsValidTaskPriority,
+	"category_id":      isValidFilterID,
+	"project_id":       isValidFilterID,
+	"keyword":          func(v string) bool { return len(v) <= 200 },
+	"match":            utils.IsValidKeywordMatch,
+	"start_date":       isValidFilterDate,
+	"end_date":         isValidFilterDate,
+	"due_before":       isValidFilterDate,
+	"completed_after":  isValidFilterDate,
+	"completed_before": isValidFilterDate,
+	"order_by":         func(v string) bool { return utils.Contains(taskOrderFields, v) },
+	"order_dir":        func(v string) bool { return v == "asc" || v == "desc" },
+	"archived":         isValidFilterBool,
+	"include_archived": isValidFilterBool,
+	"flagged":          isValidFilterBool,
+}
+
+func isValidFilterID(value string) bool {
+	id, err := strconv.ParseUint(value, 10, 32)
+	return err == nil && id > 0
+}
+
+func isValidFilterBool(value string) bool {
+	return value == "true" || value == "false"
+}
+
+func isValidFilterDate(value string) bool {
+	_, _, err := utils.ParseDate(value)
+	return err == nil
+}
+
+// 校验视图筛选条件，拒绝未知参数和非法取值
+func validateViewFilters(filters map[string]string) error {
+	for key, value := range filters {
+		valid, known := viewFilterRules[key]
+		if !known {
+			return fmt.Errorf("不支持的筛选参数: %s", key)
+		}
+		if !valid(value) {
+			return fmt.Errorf("筛选参数 %s 的值无效", key)
+		}
+	}
+	return nil
+}
+
+// 获取保存的视图列表
+func (vc *ViewController) GetViews(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+
+	views := []models.SavedView{}
+	if err := vc.DB.WithContext(c).Where("user_id = ?", userID).Order("name asc").Find(&views).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询视图失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, views)
+}
+
+// 创建视图
+func (vc *ViewController) CreateView(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.SavedViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	if err := validateViewFilters(req.Filters); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "请求参数错误", err)
+		return
+	}
+
+	// 检查视图名称是否已存在
+	var existingView models.SavedView
+	if err := vc.DB.WithContext(c).Where("name = ? AND user_id = ?", req.Name, userID).First(&existingView).Error; err == nil {
+		utils.ErrorResponse(c, http.StatusConflict, "视图名称已存在", nil)
+		return
+	}
+
+	view := models.SavedView{
+		Name:    req.Name,
+		Filters: req.Filters,
+		UserID:  userID,
+	}
+
+	if err := vc.DB.WithContext(c).Create(&view).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "视图创建失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, view)
+}
+
+// 获取视图详情
+func (vc *ViewController) GetView(c *gin.Context) {
+	view, ok := vc.findView(c)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, view)
+}
+
+// 更新视图
+func (vc *ViewController) UpdateView(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.SavedViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindErrorResponse(c, err)
+		return
+	}
+
+	if err := validateViewFilters(req.Filters); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "请求参数错误", err)
+		return
+	}
+
+	view, ok := vc.findView(c)
+	if !ok {
+		return
+	}
+
+	// 检查视图名称是否已存在（排除当前视图）
+	var existingView models.SavedView
+	if err := vc.DB.WithContext(c).Where("name = ? AND user_id = ? AND id != ?", req.Name, userID, view.ID).First(&existingView).Error; err == nil {
+		utils.ErrorResponse(c, http.StatusConflict, "视图名称已存在", nil)
+		return
+	}
+
+	view.Name = req.Name
+	view.Filters = req.Filters
+
+	if err := vc.DB.WithContext(c).Save(&view).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "视图更新失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, view)
+}
+
+// 删除视图
+func (vc *ViewController) DeleteView(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	viewID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := vc.DB.WithContext(c).Where("id = ? AND user_id = ?", viewID, userID).Delete(&models.SavedView{}).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "视图删除失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "视图删除成功"})
+}
+
+// 按保存的筛选条件获取任务
+func (vc *ViewController) GetViewTasks(c *gin.Context) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return
+	}
+	page, pageSize, offset := utils.GetPaginationParams(c)
+
+	view, ok := vc.findView(c)
+	if !ok {
+		return
+	}
+
+	// 入库前已校验，这里再次校验以防历史数据不合法
+	if err := validateViewFilters(view.Filters); err != nil {
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, "视图筛选条件无效", err)
+		return
+	}
+
+	params := url.Values{}
+	for key, value := range view.Filters {
+		params.Set(key, value)
+	}
+
+	query := applyTaskFilters(vc.DB.WithContext(c).Model(&models.Task{}).Where("user_id = ?", userID), params)
+
+	// 获取总数
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+		return
+	}
+
+	// 分页查询
+	tasks := []models.Task{}
+	if err := query.Preload("Category").Preload("Project").
+		Offset(offset).Limit(pageSize).Find(&tasks).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "查询任务失败", err)
+		return
+	}
+
+	utils.PaginatedResponse(c, tasks, total, page, pageSize)
+}
+
+// 查找当前用户的视图
+func (vc *ViewController) findView(c *gin.Context) (models.SavedView, bool) {
+	userID, ok := utils.RequireUserID(c)
+	if !ok {
+		return models.SavedView{}, false
+	}
+	viewID, ok := utils.ParseID(c, "id")
+	if !ok {
+		return models.SavedView{}, false
+	}
+
+	var view models.SavedView
+	if err := vc.DB.WithContext(c).Where("id = ? AND user_id = ?", viewID, userID).First(&view).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "视图不存在", nil)
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "查询视图失败", err)
+		}
+		return models.SavedView{}, false
+	}
+	return view, true
+}
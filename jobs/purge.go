@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"personaltask/config"
+	"personaltask/models"
+	"personaltask/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 每批彻底删除的任务数量，避免单个事务过大
+const purgeBatchSize = 500
+
+// 启动软删除任务的定期清理，未开启时不做任何事；保留期按 clock 的当前时间计算
+func StartTaskPurge(db *gorm.DB, cfg *config.Config, clock utils.Clock) {
+	if !cfg.Purge.Enabled {
+		return
+	}
+
+	run := func() {
+		purged, err := PurgeDeletedTasks(db, cfg.Upload.Dir, clock.Now().Add(-cfg.Purge.Retention))
+		if err != nil {
+			log.Printf("清理已删除任务失败: %v", err)
+			return
+		}
+		log.Printf("已彻底删除 %d 个软删除超过 %s 的任务", purged, cfg.Purge.Retention)
+	}
+
+	go func() {
+		run()
+		ticker := time.NewTicker(cfg.Purge.Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			run()
+		}
+	}()
+}
+
+// 彻底删除在 before 之前被软删除的任务，连同其评论、附件（含文件）和变更历史，返回删除的任务数
+func PurgeDeletedTasks(db *gorm.DB, uploadDir string, before time.Time) (int64, error) {
+	var purged int64
+	for {
+		var taskIDs []uint
+		if err := db.Unscoped().Model(&models.Task{}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+			Limit(purgeBatchSize).Pluck("id", &taskIDs).Error; err != nil {
+			return purged, err
+		}
+		if len(taskIDs) == 0 {
+			return purged, nil
+		}
+
+		n, err := PurgeTasks(db, uploadDir, taskIDs)
+		purged += n
+		if err != nil {
+			return purged, err
+		}
+	}
+}
+
+// 彻底删除指定的任务（包括已软删除的），连同其评论、附件（含文件）和变更历史，返回删除的任务数
+// 调用方负责确认任务归属
+func PurgeTasks(db *gorm.DB, uploadDir string, taskIDs []uint) (int64, error) {
+	var purged int64
+	var storagePaths []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Attachment{}).Where("task_id IN ?", taskIDs).Pluck("storage_path", &storagePaths).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("task_id IN ?", taskIDs).Delete(&models.Attachment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("task_id IN ?", taskIDs).Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("task_id IN ?", taskIDs).Delete(&models.TaskHistory{}).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where("id IN ?", taskIDs).Delete(&models.Task{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// 数据库记录删除成功后再删除附件文件
+	for _, storagePath := range storagePaths {
+		if err := os.Remove(filepath.Join(uploadDir, filepath.Base(storagePath))); err != nil && !os.IsNotExist(err) {
+			log.Printf("删除附件文件 %s 失败: %v", storagePath, err)
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,91 @@
+package jobs
+
+import (
+	"database/sql/driver"
+	"personaltask/config"
+	"personaltask/testutil"
+	"personaltask/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPurgeDeletedTasksOnlyPurgesBeforeCutoff(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	fake.OnOnce("SELECT `id` FROM `tasks`", []string{"id"}, []driver.Value{int64(1)}, []driver.Value{int64(2)})
+	before := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, err := PurgeDeletedTasks(db, t.TempDir(), before); err != nil {
+		t.Fatalf("PurgeDeletedTasks: %v", err)
+	}
+
+	// 只选出软删除时间早于截止时间的任务，未删除的任务不受影响
+	selects := fake.Find("SELECT `id` FROM `tasks`")
+	if len(selects) == 0 {
+		t.Fatal("没有查询待清理的任务")
+	}
+	for _, stmt := range selects {
+		if !strings.Contains(stmt.SQL, "deleted_at IS NOT NULL AND deleted_at < ?") || !containsArg(stmt.Args, before) {
+			t.Errorf("待清理任务查询 = %s %v, want 按截止时间 %v 过滤已软删除的任务", stmt.SQL, stmt.Args, before)
+		}
+	}
+	deletes := fake.Find("DELETE FROM `tasks`")
+	if len(deletes) != 1 || !containsArg(deletes[0].Args, int64(1)) || !containsArg(deletes[0].Args, int64(2)) {
+		t.Errorf("deletes = %v, want 彻底删除任务1和2", deletes)
+	}
+}
+
+func TestPurgeDeletedTasksBatchesUntilNoneLeft(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	fake.OnOnce("SELECT `id` FROM `tasks`", []string{"id"}, []driver.Value{int64(1)}, []driver.Value{int64(2)})
+	fake.OnOnce("SELECT `id` FROM `tasks`", []string{"id"}, []driver.Value{int64(3)})
+
+	if _, err := PurgeDeletedTasks(db, t.TempDir(), time.Now()); err != nil {
+		t.Fatalf("PurgeDeletedTasks: %v", err)
+	}
+
+	// 每批最多 purgeBatchSize 个，查询不到待清理的任务时结束
+	selects := fake.Find("SELECT `id` FROM `tasks`")
+	if len(selects) != 3 {
+		t.Fatalf("执行了 %d 次待清理任务查询，want 3", len(selects))
+	}
+	for _, stmt := range selects {
+		if !strings.Contains(stmt.SQL, "LIMIT 500") {
+			t.Errorf("待清理任务查询应分批，SQL = %s", stmt.SQL)
+		}
+	}
+	if deletes := fake.Find("DELETE FROM `tasks`"); len(deletes) != 2 {
+		t.Errorf("执行了 %d 批删除，want 2", len(deletes))
+	}
+}
+
+func TestStartTaskPurgeUsesClock(t *testing.T) {
+	db, fake := testutil.NewFakeDB(t)
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	cfg := &config.Config{Purge: config.PurgeConfig{Enabled: true, Retention: 30 * 24 * time.Hour, Interval: time.Hour}}
+
+	StartTaskPurge(db, cfg, utils.NewFakeClock(now))
+
+	// 启动后立即在后台执行一次清理
+	deadline := time.Now().Add(time.Second)
+	for len(fake.Find("SELECT `id` FROM `tasks`")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("启动后没有执行清理")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stmt := fake.Find("SELECT `id` FROM `tasks`")[0]
+	if want := now.Add(-cfg.Purge.Retention); !containsArg(stmt.Args, want) {
+		t.Errorf("截止时间参数 = %v, want %v", stmt.Args, want)
+	}
+}
+
+// 参数列表中是否包含 want
+func containsArg(args []driver.Value, want driver.Value) bool {
+	for _, arg := range args {
+		if arg == want {
+			return true
+		}
+	}
+	return false
+}
@@ -3,8 +3,10 @@ package main
 import (
 	"log"
 	"personaltask/config"
+	"personaltask/jobs"
 	"personaltask/models"
 	"personaltask/routes"
+	"personaltask/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,17 +18,22 @@ func main() {
 	// 初始化数据库
 	db := config.InitDB(cfg)
 
+	// 空邮箱改为NULL，以便建立邮箱唯一索引
+	if db.Migrator().HasTable(&models.User{}) {
+		if err := db.Unscoped().Model(&models.User{}).Where("email = ?", "").Update("email", nil).Error; err != nil {
+			log.Fatal("邮箱数据清理失败:", err)
+		}
+	}
+
 	// 自动迁移数据库表
-	err := db.AutoMigrate(
-		&models.User{},
-		&models.Category{},
-		&models.Project{},
-		&models.Task{},
-	)
+	err := db.AutoMigrate(models.MigrationModels()...)
 	if err != nil {
 		log.Fatal("数据库迁移失败:", err)
 	}
 
+	// 定期彻底删除超过保留期的软删除任务
+	jobs.StartTaskPurge(db, cfg, utils.NewRealClock())
+
 	// 设置Gin模式
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -37,7 +44,8 @@ func main() {
 
 	// 启动服务器
 	log.Printf("服务器启动在端口 %s", cfg.ServerPort)
-	if err := router.Run(":" + cfg.ServerPort); err != nil {
+	server := routes.NewServer(router, cfg)
+	if err := server.ListenAndServe(); err != nil {
 		log.Fatal("服务器启动失败:", err)
 	}
 }
\ No newline at end of file
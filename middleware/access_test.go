@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"personaltask/config"
+	"personaltask/testutil"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 以 userID 身份经过 access 中间件访问资源5，返回状态码
+// 项目9及其中的任务5都由用户1创建，memberRole 为当前用户在项目9中的成员角色（空表示非成员）
+func serveAccess(t *testing.T, method string, userID uint, memberRole string, access func(*gorm.DB) gin.HandlerFunc) int {
+	t.Helper()
+	db, fake := testutil.NewFakeDB(t)
+	fake.On("FROM `tasks`", []string{"id", "user_id", "project_id"}, []driver.Value{int64(5), int64(1), int64(9)})
+	fake.On("FROM `projects`", []string{"id", "user_id"}, []driver.Value{int64(9), int64(1)})
+	if memberRole != "" {
+		fake.On("FROM `project_members`", []string{"id", "project_id", "user_id", "role"},
+			[]driver.Value{int64(1), int64(9), int64(userID), memberRole})
+	}
+
+	setUser := func(c *gin.Context) { c.Set("user_id", userID) }
+	req := httptest.NewRequest(method, "/api/resources/5", nil)
+	return serveWithMiddleware(method, "/api/resources/:id", req, setUser, access(db)).Code
+}
+
+var accessTestUsers = []struct {
+	name       string
+	userID     uint
+	memberRole string
+}{
+	{"创建者", 1, ""},
+	{"editor", 2, "editor"},
+	{"viewer", 3, "viewer"},
+	{"非成员", 4, ""},
+	{"早期写入的owner成员", 5, "owner"},
+}
+
+// 与 routes.go 中的注册方式一致
+func TestTaskAccessRoles(t *testing.T) {
+	cfg := &config.Config{}
+	routes := []struct {
+		method string
+		access func(*gorm.DB) gin.HandlerFunc
+		want   []int // 按 accessTestUsers 的顺序
+	}{
+		{http.MethodGet, func(db *gorm.DB) gin.HandlerFunc { return TaskAccess(db, cfg) },
+			[]int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusForbidden, http.StatusOK}},
+		{http.MethodPut, func(db *gorm.DB) gin.HandlerFunc { return TaskAccess(db, cfg, "owner", "editor") },
+			[]int{http.StatusOK, http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusOK}},
+		// 删除任务只允许创建者
+		{http.MethodDelete, func(db *gorm.DB) gin.HandlerFunc { return ResourceOwnership(db, cfg, "task") },
+			[]int{http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	}
+	for _, route := range routes {
+		for i, user := range accessTestUsers {
+			t.Run(route.method+" "+user.name, func(t *testing.T) {
+				if got := serveAccess(t, route.method, user.userID, user.memberRole, route.access); got != route.want[i] {
+					t.Errorf("status = %d, want %d", got, route.want[i])
+				}
+			})
+		}
+	}
+}
+
+func TestProjectAccessRoles(t *testing.T) {
+	cfg := &config.Config{}
+	routes := []struct {
+		method string
+		access func(*gorm.DB) gin.HandlerFunc
+		want   []int // 按 accessTestUsers 的顺序
+	}{
+		{http.MethodGet, func(db *gorm.DB) gin.HandlerFunc { return ProjectAccess(db, cfg) },
+			[]int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusForbidden, http.StatusOK}},
+		{http.MethodPut, func(db *gorm.DB) gin.HandlerFunc { return ProjectAccess(db, cfg, "owner", "editor") },
+			[]int{http.StatusOK, http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusOK}},
+		// 成员记录中的 owner 不能获得创建者权限
+		{http.MethodDelete, func(db *gorm.DB) gin.HandlerFunc { return ProjectAccess(db, cfg, "owner") },
+			[]int{http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	}
+	for _, route := range routes {
+		for i, user := range accessTestUsers {
+			t.Run(route.method+" "+user.name, func(t *testing.T) {
+				if got := serveAccess(t, route.method, user.userID, user.memberRole, route.access); got != route.want[i] {
+					t.Errorf("status = %d, want %d", got, route.want[i])
+				}
+			})
+		}
+	}
+}
+
+func TestResourceOwnership(t *testing.T) {
+	tests := []struct {
+		name    string
+		owner   int64 // 0 表示资源不存在
+		hide    bool
+		want    int
+		wantRun bool
+	}{
+		{"资源不存在", 0, false, http.StatusNotFound, false},
+		{"资源不存在且隐藏存在性", 0, true, http.StatusForbidden, false},
+		{"其他用户的资源", 2, false, http.StatusForbidden, false},
+		{"其他用户的资源且隐藏存在性", 2, true, http.StatusForbidden, false},
+		{"自己的资源", 1, false, http.StatusOK, true},
+		{"自己的资源且隐藏存在性", 1, true, http.StatusOK, true},
+	}
+	for _, resource := range []string{"task", "category", "project", "view", "template"} {
+		for _, tt := range tests {
+			t.Run(resource+" "+tt.name, func(t *testing.T) {
+				db, fake := testutil.NewFakeDB(t)
+				if tt.owner != 0 {
+					fake.On("SELECT `user_id` FROM", []string{"user_id"}, []driver.Value{tt.owner})
+				}
+				cfg := &config.Config{Security: config.SecurityConfig{HideResourceExistence: tt.hide}}
+
+				ran := false
+				setUser := func(c *gin.Context) { c.Set("user_id", uint(1)) }
+				req := httptest.NewRequest(http.MethodDelete, "/api/resources/5", nil)
+				w := serveWithMiddleware(http.MethodDelete, "/api/resources/:id", req, setUser,
+					ResourceOwnership(db, cfg, resource), func(c *gin.Context) { ran = true })
+
+				if w.Code != tt.want || ran != tt.wantRun {
+					t.Errorf("status = %d, handler ran %v, want %d, %v", w.Code, ran, tt.want, tt.wantRun)
+				}
+				// 不存在和无权限的响应一致时，响应内容也不能区分两者
+				if tt.hide && !tt.wantRun && !strings.Contains(w.Body.String(), "无权访问该资源") {
+					t.Errorf("body = %s, want 无权访问该资源", w.Body.String())
+				}
+			})
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"personaltask/config"
 	"personaltask/models"
@@ -16,9 +18,30 @@ import (
 	"gorm.io/gorm"
 )
 
-// JWT认证中间件
-func JWTAuth(cfg *config.Config) gin.HandlerFunc {
+// JWT认证中间件（同时支持 X-API-Key 请求头认证）
+func JWTAuth(db *gorm.DB, cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// 优先使用API密钥认证
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			var key models.APIKey
+			if err := db.WithContext(c).Preload("User").Where("key_hash = ?", utils.HashAPIKey(apiKey)).First(&key).Error; err != nil {
+				utils.ErrorResponse(c, http.StatusUnauthorized, "API密钥无效", nil)
+				c.Abort()
+				return
+			}
+
+			// 记录最近使用时间，记录失败不影响本次认证
+			now := time.Now()
+			if err := db.WithContext(c).Model(&key).UpdateColumn("last_used_at", now).Error; err != nil {
+				log.Printf("更新API密钥 %d 的最近使用时间失败: %v", key.ID, err)
+			}
+
+			c.Set("user_id", key.UserID)
+			c.Set("username", key.User.Username)
+			c.Next()
+			return
+		}
+
 		// 从请求头获取token
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -67,13 +90,34 @@ func CORS() gin.HandlerFunc {
 	return cors.New(cors.Config{
 		AllowOrigins:     []string{"*"}, // 生产环境应该限制具体域名
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "If-None-Match"},
+		ExposeHeaders:    []string{"Content-Length", "ETag", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-DB-Queries", "X-DB-Time-Ms"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	})
 }
 
+// 安全响应头中间件
+func SecurityHeaders(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("X-Frame-Options", "DENY")
+		c.Header("Referrer-Policy", "strict-origin-when-cross-origin")
+
+		if cfg.Security.EnableCSP && cfg.Security.ContentSecurityPolicy != "" {
+			c.Header("Content-Security-Policy", cfg.Security.ContentSecurityPolicy)
+		}
+
+		// HSTS只在生产环境的HTTPS请求中下发（包括反向代理终止TLS的情况）
+		if cfg.Security.EnableHSTS && cfg.Environment == "production" &&
+			(c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https") {
+			c.Header("Strict-Transport-Security", fmt.Sprintf("max-age=%d; includeSubDomains", cfg.Security.HSTSMaxAge))
+		}
+
+		c.Next()
+	}
+}
+
 // 日志中间件
 func Logger() gin.HandlerFunc {
 	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
@@ -104,11 +148,50 @@ func ErrorHandler() gin.HandlerFunc {
 	}
 }
 
-// 限流中间件（简单实现）
-func RateLimit() gin.HandlerFunc {
+// 限流中间件
+// 已通过认证的请求按用户ID计数，否则按客户端IP计数，因此需要注册在 JWTAuth 之后才能按用户限流
+// 匹配到的路由在 RateLimit.ExemptPaths 中时直接放行，且不计入配额
+func RateLimit(limiter *utils.RateLimiter, cfg *config.Config) gin.HandlerFunc {
+	exempt := make(map[string]bool, len(cfg.RateLimit.ExemptPaths))
+	for _, path := range cfg.RateLimit.ExemptPaths {
+		exempt[path] = true
+	}
+
 	return func(c *gin.Context) {
-		// 这里可以实现更复杂的限流逻辑
-		// 例如使用Redis存储访问计数
+		// 按路由模板判断，避免 /api/health-report 之类的路径被误放行
+		if route := c.FullPath(); route != "" && exempt[route] {
+			c.Next()
+			return
+		}
+
+		var key string
+		var limit int
+		if userID, exists := c.Get("user_id"); exists {
+			key = fmt.Sprintf("user:%v", userID)
+			limit = cfg.RateLimit.UserLimit
+		} else {
+			key = "ip:" + c.ClientIP()
+			limit = cfg.RateLimit.IPLimit
+		}
+
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		// 每个受限流的响应都附带配额信息，Reset 为窗口重置时间的Unix时间戳（秒）
+		allowed, remaining, resetAt := limiter.Allow(key, limit)
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+		if !allowed {
+			retryAfter := int(time.Until(resetAt).Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			utils.ErrorResponse(c, http.StatusTooManyRequests, "请求过于频繁，请稍后再试", nil)
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -125,7 +208,7 @@ func RequireAuth(db *gorm.DB) gin.HandlerFunc {
 
 		// 验证用户是否存在
 		var user models.User
-		if err := db.First(&user, userID).Error; err != nil {
+		if err := db.WithContext(c).First(&user, userID).Error; err != nil {
 			utils.ErrorResponse(c, http.StatusUnauthorized, "用户不存在", err)
 			c.Abort()
 			return
@@ -137,37 +220,242 @@ func RequireAuth(db *gorm.DB) gin.HandlerFunc {
 }
 
 // 资源所有权验证中间件
-func ResourceOwnership(db *gorm.DB, resourceType string) gin.HandlerFunc {
+// 资源不存在返回404，属于其他用户返回403；开启 HideResourceExistence 时两者均返回403
+func ResourceOwnership(db *gorm.DB, cfg *config.Config, resourceType string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userID, _ := c.Get("user_id")
-		resourceIDStr := c.Param("id")
-		resourceID, err := strconv.ParseUint(resourceIDStr, 10, 32)
-		if err != nil {
-			utils.ErrorResponse(c, http.StatusBadRequest, "无效的资源ID", err)
-			c.Abort()
+		userID, ok := utils.RequireUserID(c)
+		if !ok {
+			return
+		}
+		resourceID, ok := utils.ParseID(c, "id")
+		if !ok {
 			return
 		}
 
-		var count int64
+		var model interface{}
 		switch resourceType {
 		case "task":
-			db.Model(&models.Task{}).Where("id = ? AND user_id = ?", resourceID, userID).Count(&count)
+			model = &models.Task{}
 		case "category":
-			db.Model(&models.Category{}).Where("id = ? AND user_id = ?", resourceID, userID).Count(&count)
+			model = &models.Category{}
 		case "project":
-			db.Model(&models.Project{}).Where("id = ? AND user_id = ?", resourceID, userID).Count(&count)
+			model = &models.Project{}
+		case "view":
+			model = &models.SavedView{}
+		case "template":
+			model = &models.ProjectTemplate{}
 		default:
 			utils.ErrorResponse(c, http.StatusBadRequest, "不支持的资源类型", nil)
 			c.Abort()
 			return
 		}
 
-		if count == 0 {
+		var owner struct {
+			UserID uint
+		}
+		result := db.WithContext(c).Model(model).Select("user_id").Where("id = ?", resourceID).Limit(1).Scan(&owner)
+		if result.Error != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "查询资源失败", result.Error)
+			c.Abort()
+			return
+		}
+
+		if result.RowsAffected == 0 && !cfg.Security.HideResourceExistence {
+			utils.ErrorResponse(c, http.StatusNotFound, "资源不存在", nil)
+			c.Abort()
+			return
+		}
+
+		if result.RowsAffected == 0 || owner.UserID != userID {
+			utils.ErrorResponse(c, http.StatusForbidden, "无权访问该资源", nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// 任务访问权限中间件
+// 任务创建者视为 owner；任务属于共享项目时，项目成员按其成员角色访问（viewer 只读，editor 可修改）
+// roles 为空时任意角色均可访问；任务不存在时的响应与 ResourceOwnership 一致
+// 通过后在上下文中设置 task_owner_id（任务所属用户ID）和 task_role
+func TaskAccess(db *gorm.DB, cfg *config.Config, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := utils.RequireUserID(c)
+		if !ok {
+			return
+		}
+		taskID, ok := utils.ParseID(c, "id")
+		if !ok {
+			return
+		}
+
+		var task models.Task
+		if err := db.WithContext(c).Select("id", "user_id", "project_id").First(&task, taskID).Error; err != nil {
+			switch {
+			case !errors.Is(err, gorm.ErrRecordNotFound):
+				utils.ErrorResponse(c, http.StatusInternalServerError, "查询资源失败", err)
+			case cfg.Security.HideResourceExistence:
+				utils.ErrorResponse(c, http.StatusForbidden, "无权访问该资源", nil)
+			default:
+				utils.ErrorResponse(c, http.StatusNotFound, "资源不存在", nil)
+			}
+			c.Abort()
+			return
+		}
+
+		role := ""
+		if task.UserID == userID {
+			role = "owner"
+		} else if task.ProjectID != nil {
+			var project models.Project
+			err := db.WithContext(c).Select("id", "user_id").First(&project, *task.ProjectID).Error
+			if err == nil {
+				role, err = ProjectRole(db.WithContext(c), project, userID)
+			}
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.ErrorResponse(c, http.StatusInternalServerError, "查询资源失败", err)
+				c.Abort()
+				return
+			}
+		}
+		if role == "" {
+			utils.ErrorResponse(c, http.StatusForbidden, "无权访问该资源", nil)
+			c.Abort()
+			return
+		}
+
+		if len(roles) > 0 && !utils.Contains(roles, role) {
+			utils.ErrorResponse(c, http.StatusForbidden, "当前角色无权执行该操作", nil)
+			c.Abort()
+			return
+		}
+
+		c.Set("task_owner_id", task.UserID)
+		c.Set("task_role", role)
+		c.Next()
+	}
+}
+
+// 项目访问权限中间件
+// 项目创建者视为 owner，其他用户按项目成员角色判断；roles 为空时任意成员均可访问
+func ProjectAccess(db *gorm.DB, cfg *config.Config, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := utils.RequireUserID(c)
+		if !ok {
+			return
+		}
+		projectID, ok := utils.ParseID(c, "id")
+		if !ok {
+			return
+		}
+
+		// 与 ResourceOwnership 一致：项目不存在返回404，开启 HideResourceExistence 时返回403
+		var project models.Project
+		if err := db.WithContext(c).Select("id", "user_id").First(&project, projectID).Error; err != nil {
+			switch {
+			case !errors.Is(err, gorm.ErrRecordNotFound):
+				utils.ErrorResponse(c, http.StatusInternalServerError, "查询资源失败", err)
+			case cfg.Security.HideResourceExistence:
+				utils.ErrorResponse(c, http.StatusForbidden, "无权访问该资源", nil)
+			default:
+				utils.ErrorResponse(c, http.StatusNotFound, "资源不存在", nil)
+			}
+			c.Abort()
+			return
+		}
+
+		role, err := ProjectRole(db.WithContext(c), project, userID)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "查询资源失败", err)
+			c.Abort()
+			return
+		}
+		if role == "" {
 			utils.ErrorResponse(c, http.StatusForbidden, "无权访问该资源", nil)
 			c.Abort()
 			return
 		}
 
+		if len(roles) > 0 && !utils.Contains(roles, role) {
+			utils.ErrorResponse(c, http.StatusForbidden, "当前角色无权执行该操作", nil)
+			c.Abort()
+			return
+		}
+
+		c.Set("project_role", role)
+		c.Next()
+	}
+}
+
+// ProjectRole 返回用户在项目中的角色：创建者为 owner，成员为其成员角色，非成员返回空字符串
+func ProjectRole(db *gorm.DB, project models.Project, userID uint) (string, error) {
+	if project.UserID == userID {
+		return "owner", nil
+	}
+	var member models.ProjectMember
+	err := db.Where("project_id = ? AND user_id = ?", project.ID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	// 成员不能拥有 owner 权限，早期写入的 owner 成员按 editor 处理
+	if member.Role == "owner" {
+		return "editor", nil
+	}
+	return member.Role, nil
+}
+
+// 数据库查询统计中间件（仅开发环境启用）
+// 在响应头 X-DB-Queries 和 X-DB-Time-Ms 中返回本次请求的查询次数和总耗时，需配合 utils.DBMetricsPlugin 使用
+func DBMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stats := &utils.DBQueryStats{}
+		c.Set(utils.DBQueryStatsKey, stats)
+		writer := &dbMetricsWriter{ResponseWriter: c.Writer, stats: stats}
+		c.Writer = writer
+
 		c.Next()
+
+		// 没有响应体的请求在这里补充响应头，由gin在请求结束时统一写出
+		writer.setHeaders()
+	}
+}
+
+// 在写出响应头之前填入查询统计的响应包装
+type dbMetricsWriter struct {
+	gin.ResponseWriter
+	stats *utils.DBQueryStats
+}
+
+func (w *dbMetricsWriter) setHeaders() {
+	if w.Written() {
+		return
 	}
+	count, duration := w.stats.Snapshot()
+	w.Header().Set("X-DB-Queries", strconv.Itoa(count))
+	w.Header().Set("X-DB-Time-Ms", strconv.FormatFloat(float64(duration.Microseconds())/1000, 'f', 2, 64))
+}
+
+func (w *dbMetricsWriter) WriteHeaderNow() {
+	w.setHeaders()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *dbMetricsWriter) Write(data []byte) (int, error) {
+	w.setHeaders()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *dbMetricsWriter) WriteString(s string) (int, error) {
+	w.setHeaders()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *dbMetricsWriter) Flush() {
+	w.setHeaders()
+	w.ResponseWriter.Flush()
 }
\ No newline at end of file
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"personaltask/config"
+	"personaltask/testutil"
+	"personaltask/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 通过 gin 路由以指定中间件处理一次请求，通过中间件的请求返回200
+func serveWithMiddleware(method, route string, req *http.Request, handlers ...gin.HandlerFunc) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handlers = append(handlers, func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.Handle(method, route, handlers...)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment string
+		enableCSP   bool
+		enableHSTS  bool
+		tls         bool
+		proto       string
+		wantCSP     bool
+		wantHSTS    bool
+	}{
+		{"开发环境不下发HSTS", "development", true, true, true, "", true, false},
+		{"生产环境HTTPS下发HSTS", "production", true, true, true, "", true, true},
+		{"代理终止TLS时按 X-Forwarded-Proto 判断", "production", true, true, false, "https", true, true},
+		{"生产环境HTTP不下发HSTS", "production", true, true, false, "http", true, false},
+		{"关闭HSTS", "production", true, false, true, "", true, false},
+		{"关闭CSP", "production", false, true, true, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Environment: tt.environment,
+				Security: config.SecurityConfig{
+					EnableCSP:             tt.enableCSP,
+					ContentSecurityPolicy: "default-src 'none'",
+					EnableHSTS:            tt.enableHSTS,
+					HSTSMaxAge:            600,
+				},
+			}
+			req := httptest.NewRequest(http.MethodGet, "/api/tasks", nil)
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			if tt.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			w := serveWithMiddleware(http.MethodGet, "/api/tasks", req, SecurityHeaders(cfg))
+
+			for header, want := range map[string]string{
+				"X-Content-Type-Options": "nosniff",
+				"X-Frame-Options":        "DENY",
+				"Referrer-Policy":        "strict-origin-when-cross-origin",
+			} {
+				if got := w.Header().Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+			if got := w.Header().Get("Content-Security-Policy"); (got != "") != tt.wantCSP {
+				t.Errorf("Content-Security-Policy = %q, want present %v", got, tt.wantCSP)
+			}
+			wantHSTS := ""
+			if tt.wantHSTS {
+				wantHSTS = "max-age=600; includeSubDomains"
+			}
+			if got := w.Header().Get("Strict-Transport-Security"); got != wantHSTS {
+				t.Errorf("Strict-Transport-Security = %q, want %q", got, wantHSTS)
+			}
+		})
+	}
+}
+
+const testAPIKey = "pt_0123456789abcdef"
+
+// 以 X-API-Key 认证访问受保护的路由，通过认证时返回当前用户ID
+func serveWithAPIKey(t *testing.T, fake func(*testutil.FakeDB)) (*httptest.ResponseRecorder, *testutil.FakeDB) {
+	t.Helper()
+	db, f := testutil.NewFakeDB(t)
+	fake(f)
+	cfg := &config.Config{JWT: config.JWTConfig{SecretKey: strings.Repeat("s", 32)}}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/tasks", JWTAuth(db, cfg), func(c *gin.Context) {
+		c.String(http.StatusOK, "%d %s", c.GetUint("user_id"), c.GetString("username"))
+	})
+	req := httptest.NewRequest(http.MethodGet, "/api/tasks", nil)
+	req.Header.Set("X-API-Key", testAPIKey)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w, f
+}
+
+func TestJWTAuthAcceptsValidAPIKey(t *testing.T) {
+	w, fake := serveWithAPIKey(t, func(f *testutil.FakeDB) {
+		f.On("FROM `api_keys`", []string{"id", "name", "key_hash", "user_id"},
+			[]driver.Value{int64(3), "脚本", utils.HashAPIKey(testAPIKey), int64(7)})
+		f.On("FROM `users`", []string{"id", "username"}, []driver.Value{int64(7), "alice"})
+	})
+
+	if w.Code != http.StatusOK || w.Body.String() != "7 alice" {
+		t.Fatalf("status = %d, body = %q, want 200 \"7 alice\"", w.Code, w.Body.String())
+	}
+
+	// 按密钥哈希查找，且排除已撤销（软删除）的密钥
+	lookups := fake.Find("FROM `api_keys`")
+	if len(lookups) != 1 {
+		t.Fatalf("执行了 %d 次密钥查询，want 1", len(lookups))
+	}
+	if args := lookups[0].Args; len(args) == 0 || args[0] != utils.HashAPIKey(testAPIKey) {
+		t.Errorf("密钥查询参数 = %v, want 密钥哈希", args)
+	}
+	if !strings.Contains(lookups[0].SQL, "`api_keys`.`deleted_at` IS NULL") {
+		t.Errorf("密钥查询应排除已撤销的密钥，SQL = %s", lookups[0].SQL)
+	}
+
+	updates := fake.Updated("api_keys")
+	if len(updates) != 1 {
+		t.Fatalf("执行了 %d 次密钥更新，want 1", len(updates))
+	}
+	if _, ok := updates[0]["last_used_at"].(time.Time); !ok {
+		t.Errorf("应更新 last_used_at，got %v", updates[0])
+	}
+}
+
+func TestJWTAuthRejectsUnknownOrRevokedAPIKey(t *testing.T) {
+	// 已撤销的密钥被软删除，查询不到
+	w, fake := serveWithAPIKey(t, func(*testutil.FakeDB) {})
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if updates := fake.Updated("api_keys"); len(updates) != 0 {
+		t.Errorf("认证失败时不应更新密钥，got %v", updates)
+	}
+}
+
+func TestJWTAuthIgnoresLastUsedUpdateFailure(t *testing.T) {
+	w, _ := serveWithAPIKey(t, func(f *testutil.FakeDB) {
+		f.OnError("UPDATE `api_keys`", errors.New("数据库只读"))
+		f.On("FROM `api_keys`", []string{"id", "key_hash", "user_id"},
+			[]driver.Value{int64(3), utils.HashAPIKey(testAPIKey), int64(7)})
+		f.On("FROM `users`", []string{"id", "username"}, []driver.Value{int64(7), "alice"})
+	})
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s, want 200", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"personaltask/config"
+	"personaltask/utils"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 以指定来源IP（userID 不为0时以该用户身份）经过限流中间件访问 route
+func serveRateLimited(limiter *utils.RateLimiter, cfg *config.Config, route, target string, userID uint, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.RemoteAddr = ip + ":40000"
+	setUser := func(c *gin.Context) {
+		if userID != 0 {
+			c.Set("user_id", userID)
+		}
+	}
+	return serveWithMiddleware(http.MethodGet, route, req, setUser, RateLimit(limiter, cfg))
+}
+
+func newRateLimitConfig() *config.Config {
+	return &config.Config{RateLimit: config.RateLimitConfig{IPLimit: 1, UserLimit: 2, Window: time.Minute}}
+}
+
+func TestRateLimitCountsPerUserAndPerIP(t *testing.T) {
+	limiter := utils.NewRateLimiter(time.Minute)
+	cfg := newRateLimitConfig()
+	serve := func(userID uint, ip string) int {
+		return serveRateLimited(limiter, cfg, "/api/tasks", "/api/tasks", userID, ip).Code
+	}
+
+	// 已认证请求按用户计数：同一用户换IP仍共享配额，用户配额与IP配额互不影响
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if got := serve(1, "192.0.2."+strconv.Itoa(i+1)); got != want {
+			t.Errorf("用户1第 %d 次请求 = %d, want %d", i+1, got, want)
+		}
+	}
+	if got := serve(2, "192.0.2.1"); got != http.StatusOK {
+		t.Errorf("其他用户同一IP = %d, want 200", got)
+	}
+
+	// 未认证请求按IP计数
+	if got := serve(0, "192.0.2.1"); got != http.StatusOK {
+		t.Errorf("匿名请求 = %d, want 200（不应计入用户配额）", got)
+	}
+	if got := serve(0, "192.0.2.1"); got != http.StatusTooManyRequests {
+		t.Errorf("同一IP超出配额 = %d, want 429", got)
+	}
+	if got := serve(0, "192.0.2.9"); got != http.StatusOK {
+		t.Errorf("其他IP = %d, want 200", got)
+	}
+}
+
+func TestRateLimitRejectsWithRetryAfter(t *testing.T) {
+	limiter := utils.NewRateLimiter(time.Minute)
+	cfg := newRateLimitConfig()
+	serveRateLimited(limiter, cfg, "/api/auth/login", "/api/auth/login", 0, "192.0.2.1")
+
+	w := serveRateLimited(limiter, cfg, "/api/auth/login", "/api/auth/login", 0, "192.0.2.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", w.Code)
+	}
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 1 || retryAfter > 61 {
+		t.Errorf("Retry-After = %q, want 1-61 秒", w.Header().Get("Retry-After"))
+	}
+}
+
+func TestRateLimitDisabledWhenLimitIsZero(t *testing.T) {
+	limiter := utils.NewRateLimiter(time.Minute)
+	cfg := &config.Config{RateLimit: config.RateLimitConfig{Window: time.Minute}}
+
+	for i := 0; i < 3; i++ {
+		if w := serveRateLimited(limiter, cfg, "/api/tasks", "/api/tasks", 1, "192.0.2.1"); w.Code != http.StatusOK {
+			t.Fatalf("第 %d 次请求 = %d, want 200", i+1, w.Code)
+		}
+	}
+}
+
+func TestRateLimitExemptPathsMatchExactRoute(t *testing.T) {
+	tests := []struct {
+		name       string
+		route      string
+		target     string
+		wantExempt bool
+	}{
+		{"列出的路由", "/api/health", "/api/health", true},
+		{"带参数的路由模板", "/api/tasks/:id", "/api/tasks/5", true},
+		{"前缀相同的其他路由", "/api/health-report", "/api/health-report", false},
+		{"列出路由的子路径", "/api/health/details", "/api/health/details", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := utils.NewRateLimiter(time.Minute)
+			cfg := newRateLimitConfig()
+			cfg.RateLimit.ExemptPaths = []string{"/api/health", "/api/tasks/:id"}
+
+			serveRateLimited(limiter, cfg, tt.route, tt.target, 0, "192.0.2.1")
+			w := serveRateLimited(limiter, cfg, tt.route, tt.target, 0, "192.0.2.1")
+
+			// 放行的请求不计入配额，也不返回配额信息
+			if exempt := w.Code == http.StatusOK && w.Header().Get("X-RateLimit-Limit") == ""; exempt != tt.wantExempt {
+				t.Errorf("status = %d, X-RateLimit-Limit = %q, want exempt %v", w.Code, w.Header().Get("X-RateLimit-Limit"), tt.wantExempt)
+			}
+		})
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	limiter := utils.NewRateLimiter(time.Minute)
+	cfg := newRateLimitConfig()
+
+	for i, want := range []struct {
+		code      int
+		remaining string
+	}{
+		{http.StatusOK, "1"},
+		{http.StatusOK, "0"},
+		{http.StatusTooManyRequests, "0"},
+	} {
+		w := serveRateLimited(limiter, cfg, "/api/tasks", "/api/tasks", 1, "192.0.2.1")
+		if w.Code != want.code {
+			t.Fatalf("第 %d 次请求 status = %d, want %d", i+1, w.Code, want.code)
+		}
+		if got := w.Header().Get("X-RateLimit-Limit"); got != "2" {
+			t.Errorf("第 %d 次请求 X-RateLimit-Limit = %q, want 2", i+1, got)
+		}
+		if got := w.Header().Get("X-RateLimit-Remaining"); got != want.remaining {
+			t.Errorf("第 %d 次请求 X-RateLimit-Remaining = %q, want %s", i+1, got, want.remaining)
+		}
+		// Reset 为窗口重置时间的Unix时间戳（秒）
+		reset, err := strconv.ParseInt(w.Header().Get("X-RateLimit-Reset"), 10, 64)
+		if until := time.Until(time.Unix(reset, 0)); err != nil || until < -time.Second || until > time.Minute {
+			t.Errorf("第 %d 次请求 X-RateLimit-Reset = %q, want 一个窗口期内的时间戳", i+1, w.Header().Get("X-RateLimit-Reset"))
+		}
+		if got := w.Header().Get("Retry-After"); (got != "") != (want.code == http.StatusTooManyRequests) {
+			t.Errorf("第 %d 次请求 Retry-After = %q", i+1, got)
+		}
+	}
+}
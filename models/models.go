@@ -6,15 +6,38 @@ import (
 	"gorm.io/gorm"
 )
 
+// 需要自动迁移的数据模型，按依赖顺序排列
+func MigrationModels() []interface{} {
+	return []interface{}{
+		&User{},
+		&Category{},
+		&Project{},
+		&ProjectMember{},
+		&Task{},
+		&APIKey{},
+		&Comment{},
+		&Attachment{},
+		&TaskHistory{},
+		&SavedView{},
+		&PasswordResetToken{},
+		&ProjectTemplate{},
+		&ProjectTemplateItem{},
+		&UserPreferences{},
+	}
+}
+
 // 用户模型
+// Username 在写入前统一规范化为小写；数据库唯一索引应使用大小写不敏感的排序规则（如 utf8mb4_general_ci）
+// Email 同样规范化为小写，未填写时存为NULL，以便唯一索引允许多个用户不填邮箱
 type User struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Username  string         `json:"username" gorm:"uniqueIndex;size:50;not null"`
-	Password  string         `json:"-" gorm:"size:255;not null"`
-	Email     string         `json:"email" gorm:"size:100"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Username    string         `json:"username" gorm:"uniqueIndex;size:50;not null"`
+	Password    string         `json:"-" gorm:"size:255;not null"`
+	Email       *string        `json:"email" gorm:"uniqueIndex;size:100"`
+	LastLoginAt *time.Time     `json:"last_login_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// 关联关系
 	Categories []Category `json:"categories,omitempty" gorm:"foreignKey:UserID"`
@@ -56,32 +79,181 @@ type Project struct {
 	Tasks []Task `json:"tasks,omitempty" gorm:"foreignKey:ProjectID"`
 }
 
+// 项目成员模型（项目创建者即所有者，不在此表中记录）
+type ProjectMember struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ProjectID uint      `json:"project_id" gorm:"not null;uniqueIndex:idx_project_member"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_project_member"`
+	Role      string    `json:"role" gorm:"type:enum('owner','editor','viewer');default:viewer"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// 关联关系
+	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
 // 任务模型
 type Task struct {
+	ID               uint           `json:"id" gorm:"primaryKey"`
+	Title            string         `json:"title" gorm:"size:200;not null"`
+	Description      string         `json:"description" gorm:"type:text"`
+	Status           string         `json:"status" gorm:"size:20;not null;default:pending"` // 可选值由配置的任务状态集合决定
+	Priority         string         `json:"priority" gorm:"type:enum('low','medium','high','urgent');default:medium"`
+	StartDate        *time.Time     `json:"start_date"`
+	DueDate          *time.Time     `json:"due_date"`
+	CompletedAt      *time.Time     `json:"completed_at"`
+	UserID           uint           `json:"user_id" gorm:"not null"`
+	CategoryID       *uint          `json:"category_id"`
+	ProjectID        *uint          `json:"project_id"`
+	Position         int            `json:"position" gorm:"not null;default:0;index"`
+	Archived         bool           `json:"archived" gorm:"not null;default:false;index"`
+	ArchivedAt       *time.Time     `json:"archived_at"`
+	Version          int            `json:"version" gorm:"not null;default:1"`
+	Flagged          bool           `json:"flagged" gorm:"not null;default:false;index"`
+	Color            string         `json:"color" gorm:"size:7"` // 任务标记颜色（#RRGGBB），为空表示不标记
+	EstimatedMinutes *int           `json:"estimated_minutes"`   // 预计耗时（分钟），为空表示未估计
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// 关联关系
+	User     User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Category *Category `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+	Project  *Project  `json:"project,omitempty" gorm:"foreignKey:ProjectID"`
+	Comments []Comment `json:"comments,omitempty" gorm:"foreignKey:TaskID"`
+
+	// 评论数量（仅在任务详情中填充）
+	CommentCount *int64 `json:"comment_count,omitempty" gorm:"-"`
+
+	// 描述的HTML渲染结果（仅在任务详情指定 render=html 时填充）
+	DescriptionHTML *string `json:"description_html,omitempty" gorm:"-"`
+}
+
+// 任务评论模型
+type Comment struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	TaskID    uint           `json:"task_id" gorm:"not null;index"`
+	UserID    uint           `json:"user_id" gorm:"not null"`
+	Body      string         `json:"body" gorm:"type:text;not null"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// 关联关系
+	Task Task `json:"-" gorm:"foreignKey:TaskID"`
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// API密钥模型（仅保存密钥哈希，撤销即软删除）
+type APIKey struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	Name       string         `json:"name" gorm:"size:100;not null"`
+	KeyHash    string         `json:"-" gorm:"uniqueIndex;size:64;not null"`
+	Prefix     string         `json:"prefix" gorm:"size:16"`
+	UserID     uint           `json:"user_id" gorm:"not null;index"`
+	LastUsedAt *time.Time     `json:"last_used_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// 关联关系
+	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// 密码重置令牌模型（仅保存令牌哈希，使用后记录 UsedAt 防止重复使用）
+type PasswordResetToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;size:64;not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// 任务附件模型
+type Attachment struct {
 	ID          uint           `json:"id" gorm:"primaryKey"`
-	Title       string         `json:"title" gorm:"size:200;not null"`
-	Description string         `json:"description" gorm:"type:text"`
-	Status      string         `json:"status" gorm:"type:enum('pending','in_progress','completed');default:pending"`
-	Priority    string         `json:"priority" gorm:"type:enum('low','medium','high','urgent');default:medium"`
-	DueDate     *time.Time     `json:"due_date"`
-	CompletedAt *time.Time     `json:"completed_at"`
+	TaskID      uint           `json:"task_id" gorm:"not null;index"`
 	UserID      uint           `json:"user_id" gorm:"not null"`
-	CategoryID  *uint          `json:"category_id"`
-	ProjectID   *uint          `json:"project_id"`
+	Filename    string         `json:"filename" gorm:"size:255;not null"`
+	StoragePath string         `json:"-" gorm:"size:255;not null"`
+	Size        int64          `json:"size"`
+	ContentType string         `json:"content_type" gorm:"size:100"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// 关联关系
-	User     User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	Category *Category `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
-	Project  *Project  `json:"project,omitempty" gorm:"foreignKey:ProjectID"`
+	Task Task `json:"-" gorm:"foreignKey:TaskID"`
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// 任务变更历史模型
+type TaskHistory struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TaskID    uint      `json:"task_id" gorm:"not null;index"`
+	UserID    uint      `json:"user_id" gorm:"not null"`
+	Field     string    `json:"field" gorm:"size:50;not null"`
+	OldValue  string    `json:"old_value" gorm:"type:text"`
+	NewValue  string    `json:"new_value" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 保存的任务视图（筛选条件与任务列表的查询参数一致）
+type SavedView struct {
+	ID        uint              `json:"id" gorm:"primaryKey"`
+	Name      string            `json:"name" gorm:"size:100;not null"`
+	Filters   map[string]string `json:"filters" gorm:"serializer:json;type:text"`
+	UserID    uint              `json:"user_id" gorm:"not null;index"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	DeletedAt gorm.DeletedAt    `json:"-" gorm:"index"`
+}
+
+// 项目任务模板（按用户保存，实例化时按相对截止日期生成任务）
+type ProjectTemplate struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"size:100;not null"`
+	Description string         `json:"description" gorm:"type:text"`
+	UserID      uint           `json:"user_id" gorm:"not null;index"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// 关联关系
+	Items []ProjectTemplateItem `json:"items,omitempty" gorm:"foreignKey:TemplateID"`
+}
+
+// 模板中的任务，DueOffsetDays 为截止日期相对项目开始日期的天数，为空表示没有截止日期
+type ProjectTemplateItem struct {
+	ID            uint   `json:"id" gorm:"primaryKey"`
+	TemplateID    uint   `json:"template_id" gorm:"not null;index"`
+	Title         string `json:"title" gorm:"size:200;not null"`
+	Description   string `json:"description" gorm:"type:text"`
+	Priority      string `json:"priority" gorm:"type:enum('low','medium','high','urgent');default:medium"`
+	DueOffsetDays *int   `json:"due_offset_days"`
+	Position      int    `json:"position" gorm:"not null;default:0"`
+}
+
+// 用户偏好设置（与用户一对一，未保存过时按默认值返回）
+// DefaultPriority 为空表示使用服务端配置的默认优先级
+type UserPreferences struct {
+	ID              uint      `json:"-" gorm:"primaryKey"`
+	UserID          uint      `json:"user_id" gorm:"uniqueIndex;not null"`
+	Timezone        string    `json:"timezone" gorm:"size:64;not null;default:UTC"`
+	WeekStart       string    `json:"week_start" gorm:"size:10;not null;default:monday"`
+	DayStartHour    int       `json:"day_start_hour" gorm:"not null;default:0"` // 一天的起始小时（0-23），如4表示凌晨4点前仍算前一天
+	DefaultPriority string    `json:"default_priority" gorm:"size:10"`
+	EmailOptOut     bool      `json:"email_opt_out" gorm:"not null;default:false"`
+	Theme           string    `json:"theme" gorm:"size:10;not null;default:system"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
 }
 
 // 用户注册请求
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"` // 长度和字符要求由密码策略校验
 	Email    string `json:"email" binding:"omitempty,email"`
 }
 
@@ -91,19 +263,78 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// 忘记密码请求
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// 重置密码请求
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"` // 长度和字符要求由密码策略校验
+}
+
+// 修改密码请求
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"` // 长度和字符要求由密码策略校验
+}
+
+// API密钥创建请求
+type APIKeyRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// 偏好设置更新请求，只更新请求中出现的字段
+type UserPreferencesRequest struct {
+	Timezone        *string `json:"timezone" binding:"omitempty,min=1,max=64"`
+	WeekStart       *string `json:"week_start" binding:"omitempty,oneof=monday sunday"`
+	DayStartHour    *int    `json:"day_start_hour" binding:"omitempty,min=0,max=23"`
+	DefaultPriority *string `json:"default_priority" binding:"omitempty,oneof=low medium high urgent"`
+	EmailOptOut     *bool   `json:"email_opt_out"`
+	Theme           *string `json:"theme" binding:"omitempty,oneof=light dark system"`
+}
+
 // 任务创建/更新请求
 type TaskRequest struct {
-	Title       string     `json:"title" binding:"required,max=200"`
-	Description string     `json:"description"`
-	Priority    string     `json:"priority" binding:"omitempty,oneof=low medium high urgent"`
-	DueDate     *time.Time `json:"due_date"`
-	CategoryID  *uint      `json:"category_id"`
-	ProjectID   *uint      `json:"project_id"`
+	Title            string     `json:"title" binding:"required,max=200"`
+	Description      string     `json:"description"`
+	Priority         string     `json:"priority" binding:"omitempty,oneof=low medium high urgent"`
+	StartDate        *time.Time `json:"start_date"`
+	DueDate          *time.Time `json:"due_date"`
+	CategoryID       *uint      `json:"category_id"`
+	ProjectID        *uint      `json:"project_id"`
+	Flagged          bool       `json:"flagged"`
+	Color            string     `json:"color" binding:"omitempty,hexcolor,len=7"`
+	EstimatedMinutes *int       `json:"estimated_minutes" binding:"omitempty,min=0,max=100000"`
+	Version          *int       `json:"version"` // 更新时可传入当前版本号，与数据库不一致时返回409
+}
+
+// 任务排序请求
+type TaskReorderRequest struct {
+	TaskIDs []uint `json:"task_ids" binding:"required,min=1"`
 }
 
 // 任务状态更新请求
 type TaskStatusRequest struct {
-	Status string `json:"status" binding:"required,oneof=pending in_progress completed"`
+	Status string `json:"status" binding:"required,task_status"`
+	Reopen bool   `json:"reopen"` // 配置了状态流转规则时，将已完成的任务改为其他状态需设为true
+}
+
+// 快速记录任务请求，标题中可包含快捷标记：#分类 !优先级 ^截止日期
+type QuickTaskRequest struct {
+	Title string `json:"title" binding:"required,max=200"`
+}
+
+// 任务延后请求，until（延后到的时间）和 by（延后时长，如 1d、2h）二选一
+type TaskSnoozeRequest struct {
+	Until *time.Time `json:"until"`
+	By    string     `json:"by"`
+}
+
+// 评论创建请求
+type CommentRequest struct {
+	Body string `json:"body" binding:"required,max=5000"`
 }
 
 // 分类创建/更新请求
@@ -113,6 +344,13 @@ type CategoryRequest struct {
 	Color       string `json:"color" binding:"omitempty,len=7"`
 }
 
+// 分类部分更新请求，只更新请求中出现的字段
+type CategoryPatchRequest struct {
+	Name        *string `json:"name" binding:"omitempty,min=1,max=50"`
+	Description *string `json:"description"`
+	Color       *string `json:"color" binding:"omitempty,len=7"`
+}
+
 // 项目创建/更新请求
 type ProjectRequest struct {
 	Name        string     `json:"name" binding:"required,max=100"`
@@ -122,13 +360,47 @@ type ProjectRequest struct {
 	EndDate     *time.Time `json:"end_date"`
 }
 
+// 项目部分更新请求，只更新请求中出现的字段
+type ProjectPatchRequest struct {
+	Name        *string    `json:"name" binding:"omitempty,min=1,max=100"`
+	Description *string    `json:"description"`
+	Status      *string    `json:"status" binding:"omitempty,oneof=active completed archived"`
+	StartDate   *time.Time `json:"start_date"`
+	EndDate     *time.Time `json:"end_date"`
+}
+
+// 项目成员邀请请求（owner 只属于项目创建者，不能授予成员）
+type ProjectMemberRequest struct {
+	Username string `json:"username" binding:"required"`
+	Role     string `json:"role" binding:"omitempty,oneof=editor viewer"`
+}
+
+// 保存视图创建/更新请求
+type SavedViewRequest struct {
+	Name    string            `json:"name" binding:"required,max=100"`
+	Filters map[string]string `json:"filters"`
+}
+
+// 将项目任务保存为模板的请求
+type ProjectTemplateRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description"`
+}
+
+// 模板实例化请求，提供 project_id 时添加到已有项目，否则按 name 新建项目
+type TemplateInstantiateRequest struct {
+	ProjectID *uint      `json:"project_id"`
+	Name      string     `json:"name" binding:"max=100"`
+	StartDate *time.Time `json:"start_date"` // 截止日期的计算基准，默认为项目开始日期，项目未设置时为当天
+}
+
 // API响应结构
 type Response struct {
 	Code      int         `json:"code"`
 	Message   string      `json:"message"`
 	Data      interface{} `json:"data,omitempty"`
 	Error     string      `json:"error,omitempty"`
-	Timestamp time.Time   `json:"timestamp"`
+	Timestamp time.Time   `json:"timestamp"` // UTC时间，序列化为RFC 3339格式（以Z结尾）
 }
 
 // 分页响应结构
@@ -138,6 +410,10 @@ type PaginatedResponse struct {
 	Page       int         `json:"page"`
 	PageSize   int         `json:"page_size"`
 	TotalPages int         `json:"total_pages"`
+	HasNext    bool        `json:"has_next"`
+	HasPrev    bool        `json:"has_prev"`
+	NextPage   *int        `json:"next_page"` // 没有下一页时为null
+	PrevPage   *int        `json:"prev_page"` // 没有上一页时为null
 }
 
 // 统计响应结构
@@ -149,6 +425,10 @@ type StatsOverview struct {
 	TotalProjects   int64 `json:"total_projects"`
 	ActiveProjects  int64 `json:"active_projects"`
 	TotalCategories int64 `json:"total_categories"`
+
+	// 各优先级任务数量，以及未完成任务的优先级权重之和（衡量待办负荷）
+	PriorityCounts map[string]int64 `json:"priority_counts"`
+	WorkloadScore  int64            `json:"workload_score"`
 }
 
 // 每日统计
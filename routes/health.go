@@ -0,0 +1,97 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"personaltask/config"
+	"personaltask/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 健康检查时数据库 Ping 的超时时间
+const healthCheckTimeout = 2 * time.Second
+
+// 存活检查：进程能处理请求即返回200，不依赖数据库
+func livenessHandler(startedAt time.Time) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "ok",
+			"version": config.Version,
+			"uptime":  int64(time.Since(startedAt).Seconds()),
+		})
+	}
+}
+
+// 构建版本信息
+func versionHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"version":    config.Version,
+		"commit":     config.Commit,
+		"build_time": config.BuildTime,
+	})
+}
+
+// 就绪检查：数据库可达且数据表已迁移才返回200，否则返回503
+func readinessHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := checkReadiness(c.Request.Context(), db); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status": "not_ready",
+				"error":  err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+	}
+}
+
+// 健康检查（兼容旧接口）：合并存活与就绪检查，未就绪时返回503
+func healthHandler(db *gorm.DB, startedAt time.Time) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, message, code := "ok", "Personal Task Management API is running", http.StatusOK
+		if err := checkReadiness(c.Request.Context(), db); err != nil {
+			status, message, code = "degraded", err.Error(), http.StatusServiceUnavailable
+		}
+
+		c.JSON(code, gin.H{
+			"status":  status,
+			"message": message,
+			"version": config.Version,
+			"uptime":  int64(time.Since(startedAt).Seconds()),
+		})
+	}
+}
+
+// 检查数据库连接和迁移状态
+func checkReadiness(ctx context.Context, db *gorm.DB) error {
+	if err := pingDB(ctx, db); err != nil {
+		return fmt.Errorf("数据库连接不可用")
+	}
+
+	migrator := db.Migrator()
+	for _, model := range models.MigrationModels() {
+		if !migrator.HasTable(model) {
+			return fmt.Errorf("数据库表尚未迁移")
+		}
+	}
+	return nil
+}
+
+func pingDB(ctx context.Context, db *gorm.DB) error {
+	if db == nil {
+		return gorm.ErrInvalidDB
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
@@ -0,0 +1,376 @@
+package routes
+
+import (
+	"net/http"
+	"personaltask/config"
+	"personaltask/models"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 接口文档元数据，路径和方法取自实际注册的路由，这里只补充说明和请求体
+type apiOperation struct {
+	Summary string
+	Request interface{}
+	Status  int // 成功时的状态码，默认200
+}
+
+var apiOperations = map[string]apiOperation{
+	"POST /api/auth/register":        {Summary: "用户注册", Request: models.RegisterRequest{}, Status: http.StatusCreated},
+	"POST /api/auth/login":           {Summary: "用户登录", Request: models.LoginRequest{}},
+	"POST /api/auth/forgot-password": {Summary: "忘记密码（发送重置令牌）", Request: models.ForgotPasswordRequest{}},
+	"POST /api/auth/reset-password":  {Summary: "使用令牌重置密码", Request: models.ResetPasswordRequest{}},
+	"GET /api/auth/profile":          {Summary: "获取用户信息"},
+	"PUT /api/auth/profile":          {Summary: "更新用户信息"},
+	"PUT /api/auth/password":         {Summary: "修改密码", Request: models.ChangePasswordRequest{}},
+	"GET /api/auth/preferences":      {Summary: "获取偏好设置（未保存过时返回默认值）"},
+	"PUT /api/auth/preferences":      {Summary: "更新偏好设置（只更新请求中出现的字段）", Request: models.UserPreferencesRequest{}},
+	"GET /api/auth/export":           {Summary: "导出账号全部数据（默认JSON文档，format=zip 时为CSV压缩包）"},
+	"POST /api/auth/import":          {Summary: "从导出文件恢复数据（mode=merge 合并，mode=replace 替换现有数据）"},
+	"GET /api/auth/keys":             {Summary: "获取API密钥列表"},
+	"POST /api/auth/keys":            {Summary: "创建API密钥", Request: models.APIKeyRequest{}},
+	"DELETE /api/auth/keys/:id":      {Summary: "撤销API密钥"},
+
+	"GET /api/tasks":                                  {Summary: "获取任务列表（expand=category,project,comments 展开关联）"},
+	"POST /api/tasks":                                 {Summary: "创建任务", Request: models.TaskRequest{}, Status: http.StatusCreated},
+	"POST /api/tasks/quick":                           {Summary: "快速记录任务（标题支持 #分类 !优先级 ^截止日期 快捷标记）", Request: models.QuickTaskRequest{}, Status: http.StatusCreated},
+	"GET /api/tasks/count":                            {Summary: "统计符合筛选条件的任务数量"},
+	"GET /api/tasks/buckets":                          {Summary: "按截止日期分组获取未完成任务（逾期/今天/本周/以后/无截止日期）"},
+	"GET /api/tasks/due-dates":                        {Summary: "获取有任务到期的日期及数量（日历标记，from/to 按 tz 时区划分）"},
+	"GET /api/tasks/today":                            {Summary: "获取今天到期和已逾期的未完成任务（按 tz 时区和 day_start_hour 划分今天）"},
+	"GET /api/tasks/:id":                              {Summary: "获取任务详情（expand 展开关联，render=html 时附带描述的HTML渲染）"},
+	"HEAD /api/tasks/:id":                             {Summary: "获取任务详情的响应头（不返回响应体）"},
+	"OPTIONS /api/tasks/:id":                          {Summary: "获取任务详情路由支持的方法（Allow 响应头）", Status: http.StatusNoContent},
+	"PUT /api/tasks/:id":                              {Summary: "更新任务", Request: models.TaskRequest{}},
+	"DELETE /api/tasks/:id":                           {Summary: "删除任务（默认软删除，permanent=true 时连同评论、附件和历史彻底删除）"},
+	"PATCH /api/tasks/:id/status":                     {Summary: "更新任务状态", Request: models.TaskStatusRequest{}},
+	"PATCH /api/tasks/:id/project":                    {Summary: "移动任务到其他项目（project_id 为null时移出项目）"},
+	"POST /api/tasks/:id/snooze":                      {Summary: "延后任务截止日期（until 指定时间或 by 指定时长）", Request: models.TaskSnoozeRequest{}},
+	"GET /api/tasks/:id/history":                      {Summary: "获取任务变更历史"},
+	"POST /api/tasks/:id/duplicate":                   {Summary: "复制任务"},
+	"POST /api/tasks/:id/archive":                     {Summary: "归档任务"},
+	"POST /api/tasks/:id/unarchive":                   {Summary: "取消归档任务"},
+	"GET /api/tasks/:id/comments":                     {Summary: "获取任务评论"},
+	"POST /api/tasks/:id/comments":                    {Summary: "添加任务评论", Request: models.CommentRequest{}},
+	"DELETE /api/tasks/:id/comments/:commentId":       {Summary: "删除任务评论"},
+	"GET /api/tasks/:id/attachments":                  {Summary: "获取任务附件"},
+	"POST /api/tasks/:id/attachments":                 {Summary: "上传任务附件（multipart字段 file）"},
+	"GET /api/tasks/:id/attachments/:attachmentId":    {Summary: "下载任务附件"},
+	"DELETE /api/tasks/:id/attachments/:attachmentId": {Summary: "删除任务附件"},
+	"PATCH /api/tasks/reorder":                        {Summary: "调整任务顺序", Request: models.TaskReorderRequest{}},
+	"PATCH /api/tasks/batch/status":                   {Summary: "批量更新任务状态"},
+	"PATCH /api/tasks/batch/archive":                  {Summary: "批量归档或取消归档任务"},
+	"DELETE /api/tasks/batch":                         {Summary: "批量删除任务（dry_run=true 时仅预览）"},
+
+	"GET /api/categories":           {Summary: "获取分类列表"},
+	"POST /api/categories":          {Summary: "创建分类", Request: models.CategoryRequest{}, Status: http.StatusCreated},
+	"GET /api/categories/:id":       {Summary: "获取分类详情"},
+	"PUT /api/categories/:id":       {Summary: "更新分类", Request: models.CategoryRequest{}},
+	"PATCH /api/categories/:id":     {Summary: "部分更新分类（只更新请求中出现的字段）", Request: models.CategoryPatchRequest{}},
+	"DELETE /api/categories/:id":    {Summary: "删除分类"},
+	"GET /api/categories/:id/stats": {Summary: "获取分类统计"},
+
+	"GET /api/projects":                   {Summary: "获取项目列表"},
+	"POST /api/projects":                  {Summary: "创建项目", Request: models.ProjectRequest{}, Status: http.StatusCreated},
+	"GET /api/projects/stats":             {Summary: "获取所有项目的任务统计"},
+	"GET /api/projects/:id":               {Summary: "获取项目详情（with_tasks=true 时附带任务列表、任务统计和进度）"},
+	"PUT /api/projects/:id":               {Summary: "更新项目", Request: models.ProjectRequest{}},
+	"PATCH /api/projects/:id":             {Summary: "部分更新项目", Request: models.ProjectPatchRequest{}},
+	"DELETE /api/projects/:id":            {Summary: "删除项目"},
+	"POST /api/projects/:id/archive":      {Summary: "归档项目"},
+	"POST /api/projects/:id/unarchive":    {Summary: "取消归档项目"},
+	"POST /api/projects/:id/reopen":       {Summary: "重新打开已完成或已归档的项目"},
+	"GET /api/projects/:id/tasks":         {Summary: "获取项目任务"},
+	"GET /api/projects/:id/stats":         {Summary: "获取项目统计"},
+	"GET /api/projects/:id/gantt":         {Summary: "获取项目甘特图数据"},
+	"POST /api/projects/:id/templates":    {Summary: "将项目当前任务保存为模板", Request: models.ProjectTemplateRequest{}, Status: http.StatusCreated},
+	"GET /api/templates":                  {Summary: "获取项目模板列表"},
+	"GET /api/templates/:id":              {Summary: "获取项目模板详情"},
+	"DELETE /api/templates/:id":           {Summary: "删除项目模板"},
+	"POST /api/templates/:id/instantiate": {Summary: "按模板生成任务（添加到已有项目或新建项目）", Request: models.TemplateInstantiateRequest{}, Status: http.StatusCreated},
+
+	"GET /api/projects/:id/members":              {Summary: "获取项目成员"},
+	"POST /api/projects/:id/members":             {Summary: "邀请项目成员", Request: models.ProjectMemberRequest{}},
+	"DELETE /api/projects/:id/members/:username": {Summary: "移除项目成员"},
+
+	"GET /api/views":           {Summary: "获取保存的视图列表"},
+	"POST /api/views":          {Summary: "保存任务视图", Request: models.SavedViewRequest{}},
+	"GET /api/views/:id":       {Summary: "获取视图详情"},
+	"PUT /api/views/:id":       {Summary: "更新视图", Request: models.SavedViewRequest{}},
+	"DELETE /api/views/:id":    {Summary: "删除视图"},
+	"GET /api/views/:id/tasks": {Summary: "按视图筛选任务"},
+
+	"GET /api/stats/overview":     {Summary: "任务概览统计"},
+	"GET /api/stats/daily":        {Summary: "每日任务统计"},
+	"GET /api/stats/weekly":       {Summary: "每周任务统计"},
+	"GET /api/stats/productivity": {Summary: "工作效率分析"},
+	"GET /api/stats/monthly":      {Summary: "月度报告"},
+	"GET /api/stats/forecast":     {Summary: "未来几天（days，默认14）每天到期的未完成任务数和预计耗时"},
+
+	"GET /health":  {Summary: "健康检查（合并存活与就绪检查）"},
+	"GET /livez":   {Summary: "存活检查"},
+	"GET /readyz":  {Summary: "就绪检查"},
+	"GET /version": {Summary: "构建版本信息"},
+}
+
+// 无需认证的接口
+var publicOperations = map[string]bool{
+	"POST /api/auth/register":        true,
+	"POST /api/auth/login":           true,
+	"POST /api/auth/forgot-password": true,
+	"POST /api/auth/reset-password":  true,
+	"OPTIONS /api/tasks/:id":         true,
+}
+
+// 文档中收录的 /api 之外的系统接口
+var systemPaths = map[string]bool{
+	"/health":  true,
+	"/livez":   true,
+	"/readyz":  true,
+	"/version": true,
+}
+
+// 文档中公开的数据模型
+var apiSchemas = []interface{}{
+	models.User{},
+	models.Task{},
+	models.Category{},
+	models.Project{},
+	models.ProjectMember{},
+	models.Comment{},
+	models.Attachment{},
+	models.TaskHistory{},
+	models.SavedView{},
+	models.APIKey{},
+	models.UserPreferences{},
+	models.ProjectTemplate{},
+	models.Response{},
+	models.PaginatedResponse{},
+	models.StatsOverview{},
+	models.DailyStats{},
+}
+
+// 根据已注册路由生成 OpenAPI 3 文档
+func buildOpenAPISpec(router *gin.Engine) gin.H {
+	schemas := gin.H{}
+	for _, model := range apiSchemas {
+		schemaRef(reflect.TypeOf(model), schemas)
+	}
+
+	paths := gin.H{}
+	for _, route := range router.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/") && !systemPaths[route.Path] {
+			continue
+		}
+
+		key := route.Method + " " + route.Path
+		meta, ok := apiOperations[key]
+		if !ok {
+			meta.Summary = key
+		}
+
+		status := meta.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		operation := gin.H{
+			"summary": meta.Summary,
+			"tags":    []string{operationTag(route.Path)},
+			"responses": gin.H{
+				strconv.Itoa(status): gin.H{
+					"description": "成功",
+					"content": gin.H{
+						"application/json": gin.H{"schema": gin.H{"$ref": "#/components/schemas/Response"}},
+					},
+				},
+			},
+		}
+
+		var params []gin.H
+		segments := strings.Split(route.Path, "/")
+		for i, segment := range segments {
+			if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+				name := segment[1:]
+				segments[i] = "{" + name + "}"
+				params = append(params, gin.H{
+					"name":     name,
+					"in":       "path",
+					"required": true,
+					"schema":   gin.H{"type": "string"},
+				})
+			}
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+
+		if meta.Request != nil {
+			operation["requestBody"] = gin.H{
+				"required": true,
+				"content": gin.H{
+					"application/json": gin.H{"schema": schemaRef(reflect.TypeOf(meta.Request), schemas)},
+				},
+			}
+		}
+
+		if strings.HasPrefix(route.Path, "/api/") && !publicOperations[key] {
+			operation["security"] = []gin.H{{"bearerAuth": []string{}}, {"apiKeyAuth": []string{}}}
+		}
+
+		path := strings.Join(segments, "/")
+		item, ok := paths[path].(gin.H)
+		if !ok {
+			item = gin.H{}
+			paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = operation
+	}
+
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":   "Personal Task Management API",
+			"version": config.Version,
+		},
+		"paths": paths,
+		"components": gin.H{
+			"schemas": schemas,
+			"securitySchemes": gin.H{
+				"bearerAuth": gin.H{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"apiKeyAuth": gin.H{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+	}
+}
+
+// 接口分组标签（取 /api 后的第一段路径）
+func operationTag(path string) string {
+	segments := strings.Split(strings.TrimPrefix(path, "/api/"), "/")
+	if segments[0] == "" || systemPaths[path] {
+		return "system"
+	}
+	return segments[0]
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// 生成类型对应的 schema，结构体类型注册到 components 并返回引用
+func schemaRef(t reflect.Type, schemas gin.H) gin.H {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var schema gin.H
+	switch {
+	case t == timeType:
+		schema = gin.H{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		schema = gin.H{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		schema = gin.H{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		schema = gin.H{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		schema = gin.H{"type": "number"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		schema = gin.H{"type": "array", "items": schemaRef(t.Elem(), schemas)}
+	case t.Kind() == reflect.Map:
+		schema = gin.H{"type": "object", "additionalProperties": schemaRef(t.Elem(), schemas)}
+	case t.Kind() == reflect.Struct:
+		name := t.Name()
+		if _, exists := schemas[name]; !exists {
+			// 先占位，防止关联关系循环引用
+			schemas[name] = gin.H{}
+			schemas[name] = structSchema(t, schemas)
+		}
+		schema = gin.H{"$ref": "#/components/schemas/" + name}
+	default:
+		schema = gin.H{}
+	}
+
+	if nullable && schema["$ref"] == nil {
+		schema["nullable"] = true
+	}
+	return schema
+}
+
+// 生成结构体的 object schema
+func structSchema(t reflect.Type, schemas gin.H) gin.H {
+	properties := gin.H{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = schemaRef(field.Type, schemas)
+		if strings.Contains(field.Tag.Get("binding"), "required") {
+			required = append(required, name)
+		}
+	}
+
+	schema := gin.H{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// Swagger UI 页面
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Personal Task Management API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>`
+
+// 注册文档相关路由
+func registerDocsRoutes(router *gin.Engine) {
+	var spec gin.H
+
+	router.GET("/openapi.json", func(c *gin.Context) {
+		// 首次访问时生成，此时所有路由均已注册
+		if spec == nil {
+			spec = buildOpenAPISpec(router)
+		}
+		c.JSON(http.StatusOK, spec)
+	})
+
+	router.GET("/swagger/*any", func(c *gin.Context) {
+		// Swagger UI 从CDN加载资源，放宽该页面的CSP
+		c.Header("Content-Security-Policy", "default-src 'self'; script-src 'self' 'unsafe-inline' https://unpkg.com; style-src 'self' 'unsafe-inline' https://unpkg.com; img-src 'self' data: https://unpkg.com")
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+	})
+
+	router.GET("/docs", func(c *gin.Context) {
+		c.Redirect(http.StatusMovedPermanently, "/swagger/index.html")
+	})
+}
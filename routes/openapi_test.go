@@ -0,0 +1,127 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"personaltask/config"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 生成文档只需要已注册的路由，使用不连接数据库的 DryRun 模式
+func newDocsTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("ENVIRONMENT", "test")
+	t.Setenv("ENABLE_API_DOCS", "true")
+	t.Setenv("JWT_SECRET", strings.Repeat("s", 32))
+
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "test:test@tcp(127.0.0.1:1)/test", SkipInitializeWithVersion: true}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	return SetupRouter(db, config.Load())
+}
+
+var openAPIPathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+func TestOpenAPISpecIsValidAndListsTaskEndpoints(t *testing.T) {
+	router := newDocsTestRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Title   string `json:"title"`
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths map[string]map[string]struct {
+			Summary    string                     `json:"summary"`
+			Responses  map[string]json.RawMessage `json:"responses"`
+			Parameters []struct {
+				Name     string `json:"name"`
+				In       string `json:"in"`
+				Required bool   `json:"required"`
+			} `json:"parameters"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("文档不是合法的JSON: %v", err)
+	}
+
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, want 3.x", spec.OpenAPI)
+	}
+	if spec.Info.Title == "" || spec.Info.Version == "" {
+		t.Errorf("info 缺少 title 或 version: %+v", spec.Info)
+	}
+
+	validMethods := map[string]bool{"get": true, "put": true, "post": true, "delete": true, "options": true, "head": true, "patch": true, "trace": true}
+	for path, item := range spec.Paths {
+		if !strings.HasPrefix(path, "/") || strings.Contains(path, ":") {
+			t.Errorf("路径 %s 不是合法的 OpenAPI 路径模板", path)
+		}
+		for method, operation := range item {
+			if !validMethods[method] {
+				t.Errorf("%s 包含无效的方法 %s", path, method)
+			}
+			if len(operation.Responses) == 0 {
+				t.Errorf("%s %s 缺少 responses", method, path)
+			}
+			// 路径模板中的每个参数都必须声明为必填的 path 参数
+			declared := map[string]bool{}
+			for _, param := range operation.Parameters {
+				if param.In == "path" && param.Required {
+					declared[param.Name] = true
+				}
+			}
+			for _, m := range openAPIPathParamPattern.FindAllStringSubmatch(path, -1) {
+				if !declared[m[1]] {
+					t.Errorf("%s %s 未声明路径参数 %s", method, path, m[1])
+				}
+			}
+		}
+	}
+
+	// 所有 $ref 都必须指向已定义的 schema
+	refs := regexp.MustCompile(`"\$ref":"#/components/schemas/(\w+)"`).FindAllStringSubmatch(w.Body.String(), -1)
+	if len(refs) == 0 {
+		t.Error("文档中没有任何 schema 引用")
+	}
+	for _, m := range refs {
+		if _, ok := spec.Components.Schemas[m[1]]; !ok {
+			t.Errorf("引用了未定义的 schema %s", m[1])
+		}
+	}
+
+	for path, methods := range map[string][]string{
+		"/api/tasks":       {"get", "post"},
+		"/api/tasks/{id}":  {"get", "put", "delete"},
+		"/api/tasks/today": {"get"},
+	} {
+		for _, method := range methods {
+			if _, ok := spec.Paths[path][method]; !ok {
+				t.Errorf("文档缺少任务接口 %s %s", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
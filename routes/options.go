@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 响应 OPTIONS 请求，Allow 头列出当前路由已注册的全部方法
+// 跨域预检请求由 CORS 中间件处理，不会到达这里
+func optionsHandler(router *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.FullPath()
+		var methods []string
+		for _, route := range router.Routes() {
+			if route.Path == path {
+				methods = append(methods, route.Method)
+			}
+		}
+		sort.Strings(methods)
+
+		c.Header("Allow", strings.Join(methods, ", "))
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -1,9 +1,12 @@
 package routes
 
 import (
+	"log"
 	"personaltask/config"
 	"personaltask/controllers"
 	"personaltask/middleware"
+	"personaltask/utils"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -13,53 +16,138 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 	// 创建Gin引擎
 	router := gin.New()
 
+	// 只信任配置的代理转发的客户端IP，默认直接使用连接的远端地址
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatal("可信代理配置无效: ", err)
+	}
+
 	// 添加中间件
 	router.Use(middleware.Logger())
 	router.Use(middleware.ErrorHandler())
 	router.Use(middleware.CORS())
-	router.Use(middleware.RateLimit())
+	router.Use(middleware.SecurityHeaders(cfg))
+
+	// 非生产环境在响应头中返回每个请求的数据库查询次数和耗时，便于发现N+1查询
+	if cfg.Environment != "production" {
+		if err := db.Use(utils.DBMetricsPlugin{}); err != nil {
+			log.Printf("警告: 数据库查询统计插件注册失败（%v）", err)
+		} else {
+			router.Use(middleware.DBMetrics())
+		}
+	}
+
+	// 分页参数
+	utils.SetPaginationLimits(cfg.Pagination.DefaultPageSize, cfg.Pagination.MaxPageSize)
+
+	// 成功响应默认是否使用包装
+	utils.SetResponseEnvelope(cfg.Response.Envelope)
+
+	// 密码策略
+	utils.SetPasswordPolicy(utils.PasswordPolicy{
+		MinLength:      cfg.Password.MinLength,
+		RequireDigit:   cfg.Password.RequireDigit,
+		RequireUpper:   cfg.Password.RequireUpper,
+		RequireSpecial: cfg.Password.RequireSpecial,
+	})
+
+	// 任务状态集合（配置已在加载时校验）
+	if err := utils.SetTaskStatuses(cfg.Task.Statuses, cfg.Task.CompletedStatus); err != nil {
+		log.Printf("警告: 任务状态配置无效（%v），使用默认任务状态", err)
+	}
+	if err := utils.SetTaskTransitions(cfg.Task.Transitions); err != nil {
+		log.Printf("警告: 任务状态流转配置无效（%v），不限制状态流转", err)
+	}
+
+	// 限流器（未认证路由按IP计数，认证路由按用户计数）
+	rateLimiter := utils.NewRateLimiter(cfg.RateLimit.Window)
 
 	// 初始化控制器
 	authController := controllers.NewAuthController(db, cfg)
-	taskController := controllers.NewTaskController(db)
+	taskController := controllers.NewTaskController(db, cfg)
 	categoryController := controllers.NewCategoryController(db)
 	projectController := controllers.NewProjectController(db)
 	statsController := controllers.NewStatsController(db)
+	commentController := controllers.NewCommentController(db)
+	attachmentController := controllers.NewAttachmentController(db, cfg)
+	viewController := controllers.NewViewController(db)
+	templateController := controllers.NewTemplateController(db, cfg)
 
 	// API路由组
 	api := router.Group("/api")
 	{
 		// 认证路由（无需JWT认证）
 		auth := api.Group("/auth")
+		auth.Use(middleware.RateLimit(rateLimiter, cfg))
 		{
 			auth.POST("/register", authController.Register)
 			auth.POST("/login", authController.Login)
+			auth.POST("/forgot-password", authController.ForgotPassword)
+			auth.POST("/reset-password", authController.ResetPassword)
 		}
 
 		// 需要JWT认证的路由
 		protected := api.Group("/")
-		protected.Use(middleware.JWTAuth(cfg))
+		protected.Use(middleware.JWTAuth(db, cfg))
 		protected.Use(middleware.RequireAuth(db))
+		protected.Use(middleware.RateLimit(rateLimiter, cfg)) // 必须在认证之后，才能按用户ID限流
 		{
 			// 用户信息路由
 			userGroup := protected.Group("/auth")
 			{
 				userGroup.GET("/profile", authController.GetProfile)
 				userGroup.PUT("/profile", authController.UpdateProfile)
+				userGroup.PUT("/password", authController.ChangePassword)
+				userGroup.GET("/preferences", authController.GetPreferences)
+				userGroup.PUT("/preferences", authController.UpdatePreferences)
+				userGroup.GET("/export", authController.ExportAccount)
+				userGroup.POST("/import", authController.ImportAccount)
+
+				// API密钥管理
+				userGroup.GET("/keys", authController.GetAPIKeys)
+				userGroup.POST("/keys", authController.CreateAPIKey)
+				userGroup.DELETE("/keys/:id", authController.RevokeAPIKey)
 			}
 
 			// 任务管理路由
+			// 共享项目中的任务按项目成员角色访问：viewer 只读，editor 可修改；删除、移动和复制任务仅限任务创建者
 			taskGroup := protected.Group("/tasks")
 			{
 				taskGroup.GET("", taskController.GetTasks)
 				taskGroup.POST("", taskController.CreateTask)
-				taskGroup.GET("/:id", middleware.ResourceOwnership(db, "task"), taskController.GetTask)
-				taskGroup.PUT("/:id", middleware.ResourceOwnership(db, "task"), taskController.UpdateTask)
-				taskGroup.DELETE("/:id", middleware.ResourceOwnership(db, "task"), taskController.DeleteTask)
-				taskGroup.PATCH("/:id/status", middleware.ResourceOwnership(db, "task"), taskController.UpdateTaskStatus)
+				taskGroup.POST("/quick", taskController.QuickCreateTask)
+				taskGroup.GET("/count", taskController.CountTasks)
+				taskGroup.GET("/buckets", taskController.GetTaskBuckets)
+				taskGroup.GET("/due-dates", taskController.GetTaskDueDates)
+				taskGroup.GET("/today", taskController.GetTodayTasks)
+				taskGroup.GET("/:id", middleware.TaskAccess(db, cfg), taskController.GetTask)
+				taskGroup.HEAD("/:id", middleware.TaskAccess(db, cfg), taskController.GetTask)
+				taskGroup.PUT("/:id", middleware.TaskAccess(db, cfg, "owner", "editor"), taskController.UpdateTask)
+				taskGroup.DELETE("/:id", middleware.ResourceOwnership(db, cfg, "task"), taskController.DeleteTask)
+				taskGroup.PATCH("/:id/status", middleware.TaskAccess(db, cfg, "owner", "editor"), taskController.UpdateTaskStatus)
+				taskGroup.PATCH("/:id/project", middleware.ResourceOwnership(db, cfg, "task"), taskController.MoveTaskProject)
+				taskGroup.POST("/:id/snooze", middleware.TaskAccess(db, cfg, "owner", "editor"), taskController.SnoozeTask)
+				taskGroup.GET("/:id/history", middleware.TaskAccess(db, cfg), taskController.GetTaskHistory)
+				taskGroup.POST("/:id/duplicate", middleware.ResourceOwnership(db, cfg, "task"), taskController.DuplicateTask)
+				taskGroup.POST("/:id/archive", middleware.TaskAccess(db, cfg, "owner", "editor"), taskController.ArchiveTask)
+				taskGroup.POST("/:id/unarchive", middleware.TaskAccess(db, cfg, "owner", "editor"), taskController.UnarchiveTask)
+
+				// 任务评论
+				taskGroup.GET("/:id/comments", middleware.TaskAccess(db, cfg), commentController.GetComments)
+				taskGroup.POST("/:id/comments", middleware.TaskAccess(db, cfg, "owner", "editor"), commentController.CreateComment)
+				taskGroup.DELETE("/:id/comments/:commentId", middleware.TaskAccess(db, cfg, "owner", "editor"), commentController.DeleteComment)
+
+				// 任务附件
+				taskGroup.GET("/:id/attachments", middleware.TaskAccess(db, cfg), attachmentController.GetAttachments)
+				taskGroup.POST("/:id/attachments", middleware.TaskAccess(db, cfg, "owner", "editor"), attachmentController.UploadAttachment)
+				taskGroup.GET("/:id/attachments/:attachmentId", middleware.TaskAccess(db, cfg), attachmentController.DownloadAttachment)
+				taskGroup.DELETE("/:id/attachments/:attachmentId", middleware.TaskAccess(db, cfg, "owner", "editor"), attachmentController.DeleteAttachment)
 				
+				// 自定义排序
+				taskGroup.PATCH("/reorder", taskController.ReorderTasks)
+
 				// 批量操作
 				taskGroup.PATCH("/batch/status", taskController.BatchUpdateTaskStatus)
+				taskGroup.PATCH("/batch/archive", taskController.BatchArchiveTasks)
 				taskGroup.DELETE("/batch", taskController.BatchDeleteTasks)
 			}
 
@@ -68,10 +156,11 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 			{
 				categoryGroup.GET("", categoryController.GetCategories)
 				categoryGroup.POST("", categoryController.CreateCategory)
-				categoryGroup.GET("/:id", middleware.ResourceOwnership(db, "category"), categoryController.GetCategory)
-				categoryGroup.PUT("/:id", middleware.ResourceOwnership(db, "category"), categoryController.UpdateCategory)
-				categoryGroup.DELETE("/:id", middleware.ResourceOwnership(db, "category"), categoryController.DeleteCategory)
-				categoryGroup.GET("/:id/stats", middleware.ResourceOwnership(db, "category"), categoryController.GetCategoryStats)
+				categoryGroup.GET("/:id", middleware.ResourceOwnership(db, cfg, "category"), categoryController.GetCategory)
+				categoryGroup.PUT("/:id", middleware.ResourceOwnership(db, cfg, "category"), categoryController.UpdateCategory)
+				categoryGroup.PATCH("/:id", middleware.ResourceOwnership(db, cfg, "category"), categoryController.PatchCategory)
+				categoryGroup.DELETE("/:id", middleware.ResourceOwnership(db, cfg, "category"), categoryController.DeleteCategory)
+				categoryGroup.GET("/:id/stats", middleware.ResourceOwnership(db, cfg, "category"), categoryController.GetCategoryStats)
 			}
 
 			// 项目管理路由
@@ -79,11 +168,43 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 			{
 				projectGroup.GET("", projectController.GetProjects)
 				projectGroup.POST("", projectController.CreateProject)
-				projectGroup.GET("/:id", middleware.ResourceOwnership(db, "project"), projectController.GetProject)
-				projectGroup.PUT("/:id", middleware.ResourceOwnership(db, "project"), projectController.UpdateProject)
-				projectGroup.DELETE("/:id", middleware.ResourceOwnership(db, "project"), projectController.DeleteProject)
-				projectGroup.GET("/:id/tasks", middleware.ResourceOwnership(db, "project"), projectController.GetProjectTasks)
-				projectGroup.GET("/:id/stats", middleware.ResourceOwnership(db, "project"), projectController.GetProjectStats)
+				projectGroup.GET("/stats", projectController.GetAllProjectStats)
+				projectGroup.GET("/:id", middleware.ProjectAccess(db, cfg), projectController.GetProject)
+				projectGroup.PUT("/:id", middleware.ProjectAccess(db, cfg, "owner", "editor"), projectController.UpdateProject)
+				projectGroup.PATCH("/:id", middleware.ProjectAccess(db, cfg, "owner", "editor"), projectController.PatchProject)
+				projectGroup.DELETE("/:id", middleware.ProjectAccess(db, cfg, "owner"), projectController.DeleteProject)
+				projectGroup.POST("/:id/archive", middleware.ProjectAccess(db, cfg, "owner", "editor"), projectController.ArchiveProject)
+				projectGroup.POST("/:id/unarchive", middleware.ProjectAccess(db, cfg, "owner", "editor"), projectController.UnarchiveProject)
+				projectGroup.POST("/:id/reopen", middleware.ProjectAccess(db, cfg, "owner", "editor"), projectController.ReopenProject)
+				projectGroup.GET("/:id/tasks", middleware.ProjectAccess(db, cfg), projectController.GetProjectTasks)
+				projectGroup.GET("/:id/stats", middleware.ProjectAccess(db, cfg), projectController.GetProjectStats)
+				projectGroup.GET("/:id/gantt", middleware.ProjectAccess(db, cfg), projectController.GetProjectGantt)
+				projectGroup.POST("/:id/templates", middleware.ProjectAccess(db, cfg), templateController.SaveProjectTemplate)
+
+				// 项目成员（共享）
+				projectGroup.GET("/:id/members", middleware.ProjectAccess(db, cfg), projectController.GetProjectMembers)
+				projectGroup.POST("/:id/members", middleware.ProjectAccess(db, cfg, "owner"), projectController.AddProjectMember)
+				projectGroup.DELETE("/:id/members/:username", middleware.ProjectAccess(db, cfg, "owner"), projectController.RemoveProjectMember)
+			}
+
+			// 保存的任务视图
+			viewGroup := protected.Group("/views")
+			{
+				viewGroup.GET("", viewController.GetViews)
+				viewGroup.POST("", viewController.CreateView)
+				viewGroup.GET("/:id", middleware.ResourceOwnership(db, cfg, "view"), viewController.GetView)
+				viewGroup.PUT("/:id", middleware.ResourceOwnership(db, cfg, "view"), viewController.UpdateView)
+				viewGroup.DELETE("/:id", middleware.ResourceOwnership(db, cfg, "view"), viewController.DeleteView)
+				viewGroup.GET("/:id/tasks", middleware.ResourceOwnership(db, cfg, "view"), viewController.GetViewTasks)
+			}
+
+			// 项目任务模板
+			templateGroup := protected.Group("/templates")
+			{
+				templateGroup.GET("", templateController.GetTemplates)
+				templateGroup.GET("/:id", middleware.ResourceOwnership(db, cfg, "template"), templateController.GetTemplate)
+				templateGroup.DELETE("/:id", middleware.ResourceOwnership(db, cfg, "template"), templateController.DeleteTemplate)
+				templateGroup.POST("/:id/instantiate", middleware.ResourceOwnership(db, cfg, "template"), templateController.InstantiateTemplate)
 			}
 
 			// 统计分析路由
@@ -94,67 +215,24 @@ func SetupRouter(db *gorm.DB, cfg *config.Config) *gin.Engine {
 				statsGroup.GET("/weekly", statsController.GetWeeklyStats)
 				statsGroup.GET("/productivity", statsController.GetProductivityStats)
 				statsGroup.GET("/monthly", statsController.GetMonthlyReport)
+				statsGroup.GET("/forecast", statsController.GetWorkloadForecast)
 			}
 		}
+
+		// OPTIONS 请求只返回路由支持的方法，无需认证
+		api.OPTIONS("/tasks/:id", optionsHandler(router))
 	}
 
-	// 健康检查端点
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "ok",
-			"message": "Personal Task Management API is running",
-		})
-	})
+	// 健康检查端点（/livez 存活检查，/readyz 就绪检查，/health 为兼容旧接口的合并检查）
+	startedAt := time.Now()
+	router.GET("/livez", livenessHandler(startedAt))
+	router.GET("/readyz", readinessHandler(db))
+	router.GET("/health", healthHandler(db, startedAt))
+	router.GET("/version", versionHandler)
 
-	// API文档端点（开发环境）
-	if cfg.Environment == "development" {
-		router.GET("/docs", func(c *gin.Context) {
-			c.JSON(200, gin.H{
-				"message": "API Documentation",
-				"endpoints": gin.H{
-					"auth": gin.H{
-						"POST /api/auth/register":    "用户注册",
-						"POST /api/auth/login":       "用户登录",
-						"GET  /api/auth/profile":     "获取用户信息",
-						"PUT  /api/auth/profile":     "更新用户信息",
-					},
-					"tasks": gin.H{
-						"GET    /api/tasks":              "获取任务列表",
-						"POST   /api/tasks":              "创建任务",
-						"GET    /api/tasks/:id":          "获取任务详情",
-						"PUT    /api/tasks/:id":          "更新任务",
-						"DELETE /api/tasks/:id":          "删除任务",
-						"PATCH  /api/tasks/:id/status":   "更新任务状态",
-						"PATCH  /api/tasks/batch/status": "批量更新任务状态",
-						"DELETE /api/tasks/batch":        "批量删除任务",
-					},
-					"categories": gin.H{
-						"GET    /api/categories":        "获取分类列表",
-						"POST   /api/categories":        "创建分类",
-						"GET    /api/categories/:id":    "获取分类详情",
-						"PUT    /api/categories/:id":    "更新分类",
-						"DELETE /api/categories/:id":    "删除分类",
-						"GET    /api/categories/:id/stats": "获取分类统计",
-					},
-					"projects": gin.H{
-						"GET    /api/projects":           "获取项目列表",
-						"POST   /api/projects":           "创建项目",
-						"GET    /api/projects/:id":       "获取项目详情",
-						"PUT    /api/projects/:id":       "更新项目",
-						"DELETE /api/projects/:id":       "删除项目",
-						"GET    /api/projects/:id/tasks": "获取项目任务",
-						"GET    /api/projects/:id/stats": "获取项目统计",
-					},
-					"stats": gin.H{
-						"GET /api/stats/overview":     "任务概览统计",
-						"GET /api/stats/daily":        "每日任务统计",
-						"GET /api/stats/weekly":       "每周任务统计",
-						"GET /api/stats/productivity": "工作效率分析",
-						"GET /api/stats/monthly":      "月度报告",
-					},
-				},
-			})
-		})
+	// API文档端点（非生产环境或显式开启）
+	if cfg.EnableAPIDocs {
+		registerDocsRoutes(router)
 	}
 
 	return router
@@ -0,0 +1,44 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSetupRouterTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies string
+		remoteAddr     string
+		forwardedFor   string
+		want           string
+	}{
+		{"默认不信任代理", "", "10.0.0.5:40000", "203.0.113.7", "10.0.0.5"},
+		{"可信代理转发", "10.0.0.0/8", "10.0.0.5:40000", "203.0.113.7", "203.0.113.7"},
+		{"跳过链路中的可信代理", "10.0.0.0/8", "10.0.0.5:40000", "198.51.100.9, 10.0.0.6", "198.51.100.9"},
+		{"非可信来源伪造的请求头", "10.0.0.0/8", "192.0.2.1:40000", "203.0.113.7", "192.0.2.1"},
+		{"没有转发头", "10.0.0.0/8", "10.0.0.5:40000", "", "10.0.0.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXIES", tt.trustedProxies)
+			router := newDocsTestRouter(t)
+			router.GET("/client-ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+			req := httptest.NewRequest(http.MethodGet, "/client-ip", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"net/http"
+	"personaltask/config"
+)
+
+// 创建HTTP服务器，设置读写及空闲超时，避免慢速连接长期占用资源
+func NewServer(handler http.Handler, cfg *config.Config) *http.Server {
+	return &http.Server{
+		Addr:         ":" + cfg.ServerPort,
+		Handler:      handler,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+}
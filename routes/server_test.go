@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"net/http"
+	"personaltask/config"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewServerTimeouts(t *testing.T) {
+	tests := []struct {
+		name                string
+		env                 map[string]string
+		wantRead, wantWrite time.Duration
+		wantIdle            time.Duration
+	}{
+		{"默认值", nil, 15 * time.Second, 30 * time.Second, 60 * time.Second},
+		{"环境变量覆盖", map[string]string{"READ_TIMEOUT": "5", "WRITE_TIMEOUT": "10", "IDLE_TIMEOUT": "120"}, 5 * time.Second, 10 * time.Second, 120 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ENVIRONMENT", "test")
+			t.Setenv("JWT_SECRET", strings.Repeat("s", 32))
+			t.Setenv("SERVER_PORT", "9090")
+			for _, key := range []string{"READ_TIMEOUT", "WRITE_TIMEOUT", "IDLE_TIMEOUT"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			handler := http.NewServeMux()
+			server := NewServer(handler, config.Load())
+
+			if server.Addr != ":9090" || server.Handler != handler {
+				t.Errorf("Addr = %q, Handler = %v", server.Addr, server.Handler)
+			}
+			if server.ReadTimeout != tt.wantRead || server.WriteTimeout != tt.wantWrite || server.IdleTimeout != tt.wantIdle {
+				t.Errorf("超时 = %v/%v/%v, want %v/%v/%v", server.ReadTimeout, server.WriteTimeout, server.IdleTimeout, tt.wantRead, tt.wantWrite, tt.wantIdle)
+			}
+		})
+	}
+}
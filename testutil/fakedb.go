@@ -0,0 +1,272 @@
+// Package testutil 提供测试共用的工具：记录SQL的内存数据库驱动等
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// FakeDB 是测试用的内存数据库驱动：记录执行的SQL及参数，INSERT 按表分配自增ID，
+// SELECT 返回通过 On 预设的结果（未匹配时返回空结果），不实际解析SQL
+type FakeDB struct {
+	mu         sync.Mutex
+	rules      []fakeRule
+	statements []FakeStatement
+	nextIDs    map[string]int64
+}
+
+// FakeStatement 是执行过的一条SQL语句
+type FakeStatement struct {
+	SQL  string
+	Args []driver.Value
+}
+
+// 包含 match 的语句返回的结果，err 不为空时语句执行失败，once 为 true 时只匹配一次
+type fakeRule struct {
+	match   string
+	columns []string
+	rows    [][]driver.Value
+	err     error
+	once    bool
+}
+
+// FakeFirstInsertID 是自增ID的起始值，与测试数据中的ID区分开，便于验证关联ID被重新映射
+const FakeFirstInsertID = 1001
+
+var fakeInsertTablePattern = regexp.MustCompile("^INSERT INTO `(\\w+)`")
+
+// NewFakeDB 创建使用内存驱动的GORM连接
+func NewFakeDB(t testing.TB) (*gorm.DB, *FakeDB) {
+	t.Helper()
+	fake := &FakeDB{nextIDs: map[string]int64{}}
+	sqlDB := sql.OpenDB(fakeConnector{fake})
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	return db, fake
+}
+
+// On 预设查询结果，包含 match 的查询（按添加顺序匹配第一条）返回 rows
+func (f *FakeDB) On(match string, columns []string, rows ...[]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, fakeRule{match: match, columns: columns, rows: rows})
+}
+
+// OnOnce 与 On 相同，但只对下一条匹配的查询生效，用于模拟多次查询返回不同结果
+func (f *FakeDB) OnOnce(match string, columns []string, rows ...[]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, fakeRule{match: match, columns: columns, rows: rows, once: true})
+}
+
+// OnError 使包含 match 的语句（查询或修改，按添加顺序匹配第一条）执行失败并返回 err
+func (f *FakeDB) OnError(match string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, fakeRule{match: match, err: err})
+}
+
+// 返回第一条匹配 query 的规则，只匹配一次的规则匹配后即移除
+func (f *FakeDB) rule(query string) (fakeRule, bool) {
+	for i, rule := range f.rules {
+		if strings.Contains(query, rule.match) {
+			if rule.once {
+				f.rules = append(f.rules[:i:i], f.rules[i+1:]...)
+			}
+			return rule, true
+		}
+	}
+	return fakeRule{}, false
+}
+
+// Find 返回包含 substr 的已执行语句
+func (f *FakeDB) Find(substr string) []FakeStatement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var found []FakeStatement
+	for _, stmt := range f.statements {
+		if strings.Contains(stmt.SQL, substr) {
+			found = append(found, stmt)
+		}
+	}
+	return found
+}
+
+var fakeInsertColumnsPattern = regexp.MustCompile("^INSERT INTO `\\w+` \\(([^)]*)\\)")
+
+// Inserted 返回插入 table 的各行数据（列名到参数值），按执行顺序排列
+func (f *FakeDB) Inserted(table string) []map[string]driver.Value {
+	var rows []map[string]driver.Value
+	for _, stmt := range f.Find("INSERT INTO `" + table + "` ") {
+		m := fakeInsertColumnsPattern.FindStringSubmatch(stmt.SQL)
+		if m == nil {
+			continue
+		}
+		columns := strings.Split(strings.ReplaceAll(m[1], "`", ""), ",")
+		for start := 0; start+len(columns) <= len(stmt.Args); start += len(columns) {
+			row := make(map[string]driver.Value, len(columns))
+			for i, column := range columns {
+				row[column] = stmt.Args[start+i]
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+var fakeUpdateSetPattern = regexp.MustCompile("`(\\w+)`=\\?")
+
+// Updated 返回各次更新 table 时设置的列（列名到参数值），按执行顺序排列
+func (f *FakeDB) Updated(table string) []map[string]driver.Value {
+	var rows []map[string]driver.Value
+	for _, stmt := range f.Find("UPDATE `" + table + "` SET ") {
+		set := strings.SplitN(strings.TrimPrefix(stmt.SQL, "UPDATE `"+table+"` SET "), " WHERE ", 2)[0]
+		row := make(map[string]driver.Value)
+		for i, m := range fakeUpdateSetPattern.FindAllStringSubmatch(set, -1) {
+			row[m[1]] = stmt.Args[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (f *FakeDB) exec(query string, args []driver.Value) (driver.Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statements = append(f.statements, FakeStatement{SQL: query, Args: args})
+	if rule, ok := f.rule(query); ok && rule.err != nil {
+		return nil, rule.err
+	}
+
+	m := fakeInsertTablePattern.FindStringSubmatch(query)
+	if m == nil {
+		return fakeResult{rowsAffected: 1}, nil
+	}
+	if f.nextIDs[m[1]] == 0 {
+		f.nextIDs[m[1]] = FakeFirstInsertID
+	}
+	rows := int64(strings.Count(query, "),(") + 1)
+	id := f.nextIDs[m[1]]
+	f.nextIDs[m[1]] += rows
+	return fakeResult{lastInsertID: id, rowsAffected: rows}, nil
+}
+
+func (f *FakeDB) query(query string, args []driver.Value) (driver.Rows, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statements = append(f.statements, FakeStatement{SQL: query, Args: args})
+
+	rule, ok := f.rule(query)
+	if !ok {
+		return &fakeRows{}, nil
+	}
+	if rule.err != nil {
+		return nil, rule.err
+	}
+	return &fakeRows{columns: rule.columns, rows: rule.rows}, nil
+}
+
+type fakeConnector struct{ db *FakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn(c), nil }
+func (c fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("请通过 NewFakeDB 创建连接")
+}
+
+type fakeConn struct{ db *FakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.db, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return c.db.begin(), nil }
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.db.exec(query, namedValues(args))
+}
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.db.query(query, namedValues(args))
+}
+
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+type fakeStmt struct {
+	db    *FakeDB
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) { return s.db.exec(s.query, args) }
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error)  { return s.db.query(s.query, args) }
+
+// 事务的开始、提交和回滚分别记录为 BEGIN、COMMIT、ROLLBACK 语句，便于验证语句在同一事务中执行
+type fakeTx struct{ db *FakeDB }
+
+func (f *FakeDB) begin() fakeTx {
+	f.record("BEGIN")
+	return fakeTx{f}
+}
+
+func (tx fakeTx) Commit() error   { tx.db.record("COMMIT"); return nil }
+func (tx fakeTx) Rollback() error { tx.db.record("ROLLBACK"); return nil }
+
+func (f *FakeDB) record(query string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statements = append(f.statements, FakeStatement{SQL: query})
+}
+
+type fakeResult struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	next    int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,29 @@
+package utils
+
+// 批量操作中单项失败的原因，Index 为该项在请求列表中的下标
+type BulkItemError struct {
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+}
+
+// 批量创建、导入、批量分配等逐项处理的操作结果
+type BulkResult struct {
+	Succeeded int             `json:"succeeded"`
+	Failed    int             `json:"failed"`
+	Errors    []BulkItemError `json:"errors"`
+}
+
+func NewBulkResult() *BulkResult {
+	return &BulkResult{Errors: []BulkItemError{}}
+}
+
+// 记录一项成功
+func (r *BulkResult) Succeed() {
+	r.Succeeded++
+}
+
+// 记录一项失败及原因
+func (r *BulkResult) Fail(index int, reason string) {
+	r.Failed++
+	r.Errors = append(r.Errors, BulkItemError{Index: index, Reason: reason})
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// 时钟接口，便于在依赖当前时间的逻辑中替换为固定时间
+type Clock interface {
+	Now() time.Time
+}
+
+// 系统时钟，返回UTC时间
+type RealClock struct{}
+
+func NewRealClock() RealClock {
+	return RealClock{}
+}
+
+func (RealClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// 可手动设置的固定时钟，仅在调用 Set 或 Advance 时变化
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (f *FakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// 设置当前时间
+func (f *FakeClock) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
+
+// 将当前时间向后推移
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// 按IANA时区名称解析时区，为空时使用UTC
+func ParseTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// 返回 t 所在自然日的起止时间（左闭右开），按 t 的时区计算
+func DayRange(t time.Time) (time.Time, time.Time) {
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return start, start.AddDate(0, 0, 1)
+}
+
+// 返回 t 所在的"一天"的起止时间（左闭右开），一天从 startHour 点开始，startHour 为0时与 DayRange 相同
+// 例如 startHour 为4时，凌晨3点仍属于前一天
+func DayRangeFrom(t time.Time, startHour int) (time.Time, time.Time) {
+	start := time.Date(t.Year(), t.Month(), t.Day(), startHour, 0, 0, 0, t.Location())
+	if t.Before(start) {
+		start = time.Date(t.Year(), t.Month(), t.Day()-1, startHour, 0, 0, 0, t.Location())
+	}
+	return start, time.Date(start.Year(), start.Month(), start.Day()+1, startHour, 0, 0, 0, t.Location())
+}
+
+// 返回 t 所在周的第一天零点，weekStart 为每周的起始日（如 time.Monday 或 time.Sunday）
+func WeekStart(t time.Time, weekStart time.Weekday) time.Time {
+	day, _ := DayRange(t)
+	offset := (int(day.Weekday()) - int(weekStart) + 7) % 7
+	return day.AddDate(0, 0, -offset)
+}
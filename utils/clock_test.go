@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("加载时区 %s 失败: %v", name, err)
+	}
+	return loc
+}
+
+func TestFakeClockSetAndAdvance(t *testing.T) {
+	start := time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	if got := clock.Now(); !got.Equal(start) {
+		t.Fatalf("Now() = %v, want %v", got, start)
+	}
+	if got := clock.Now(); !got.Equal(start) {
+		t.Fatalf("未调用 Set/Advance 时时间不应变化，got %v", got)
+	}
+
+	clock.Advance(90 * time.Minute)
+	if want := start.Add(90 * time.Minute); !clock.Now().Equal(want) {
+		t.Fatalf("Advance 后 Now() = %v, want %v", clock.Now(), want)
+	}
+
+	later := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock.Set(later)
+	if !clock.Now().Equal(later) {
+		t.Fatalf("Set 后 Now() = %v, want %v", clock.Now(), later)
+	}
+}
+
+func TestDayRangeUsesLocation(t *testing.T) {
+	shanghai := mustLoadLocation(t, "Asia/Shanghai")
+	clock := NewFakeClock(time.Date(2024, 3, 10, 23, 30, 0, 0, time.UTC))
+
+	// UTC 3月10日23:30 在上海已是3月11日
+	start, end := DayRange(clock.Now().In(shanghai))
+	if want := time.Date(2024, 3, 10, 16, 0, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("start = %v, want %v", start, want)
+	}
+	if want := time.Date(2024, 3, 11, 16, 0, 0, 0, time.UTC); !end.Equal(want) {
+		t.Errorf("end = %v, want %v", end, want)
+	}
+}
+
+func TestDayRangeAcrossDST(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+
+	// 2024-03-10 纽约进入夏令时，当天只有23小时
+	start, end := DayRange(time.Date(2024, 3, 10, 12, 0, 0, 0, newYork))
+	if got := end.Sub(start); got != 23*time.Hour {
+		t.Errorf("夏令时切换当天长度 = %v, want 23h", got)
+	}
+	if end.Hour() != 0 || end.Day() != 11 {
+		t.Errorf("end = %v, want 次日零点", end)
+	}
+}
+
+func TestDayRangeFrom(t *testing.T) {
+	loc := mustLoadLocation(t, "Asia/Shanghai")
+	tests := []struct {
+		name      string
+		now       time.Time
+		startHour int
+		wantStart time.Time
+	}{
+		{"零点起始与 DayRange 相同", time.Date(2024, 3, 10, 3, 0, 0, 0, loc), 0, time.Date(2024, 3, 10, 0, 0, 0, 0, loc)},
+		{"起始小时之前属于前一天", time.Date(2024, 3, 10, 3, 59, 0, 0, loc), 4, time.Date(2024, 3, 9, 4, 0, 0, 0, loc)},
+		{"起始小时当刻属于当天", time.Date(2024, 3, 10, 4, 0, 0, 0, loc), 4, time.Date(2024, 3, 10, 4, 0, 0, 0, loc)},
+		{"跨月", time.Date(2024, 3, 1, 2, 0, 0, 0, loc), 4, time.Date(2024, 2, 29, 4, 0, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewFakeClock(tt.now)
+			start, end := DayRangeFrom(clock.Now(), tt.startHour)
+			if !start.Equal(tt.wantStart) {
+				t.Errorf("start = %v, want %v", start, tt.wantStart)
+			}
+			if want := tt.wantStart.AddDate(0, 0, 1); !end.Equal(want) {
+				t.Errorf("end = %v, want %v", end, want)
+			}
+		})
+	}
+}
+
+func TestWeekStart(t *testing.T) {
+	loc := mustLoadLocation(t, "Asia/Shanghai")
+	// UTC 周日 20:00，上海已是周一 04:00
+	clock := NewFakeClock(time.Date(2024, 3, 10, 20, 0, 0, 0, time.UTC))
+	now := clock.Now().In(loc)
+
+	if got, want := WeekStart(now, time.Monday), time.Date(2024, 3, 11, 0, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("WeekStart(monday) = %v, want %v", got, want)
+	}
+	if got, want := WeekStart(now, time.Sunday), time.Date(2024, 3, 10, 0, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("WeekStart(sunday) = %v, want %v", got, want)
+	}
+
+	// 同一时刻按UTC计算仍属于上一周
+	if got, want := WeekStart(clock.Now(), time.Monday), time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("WeekStart(UTC, monday) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 请求上下文中保存查询统计的键，需通过 db.WithContext(c) 传入请求上下文才会计数
+const DBQueryStatsKey = "db_query_stats"
+
+const dbQueryStartKey = "db_metrics:start"
+
+// 单个请求内的数据库查询次数和总耗时
+type DBQueryStats struct {
+	mu       sync.Mutex
+	count    int
+	duration time.Duration
+}
+
+func (s *DBQueryStats) add(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count++
+	s.duration += d
+}
+
+// 返回当前的查询次数和总耗时
+func (s *DBQueryStats) Snapshot() (int, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count, s.duration
+}
+
+// 统计请求内数据库查询的GORM插件，仅用于开发环境排查性能问题（如N+1查询）
+type DBMetricsPlugin struct{}
+
+func (DBMetricsPlugin) Name() string {
+	return "db_metrics"
+}
+
+func (DBMetricsPlugin) Initialize(db *gorm.DB) error {
+	type registerer interface {
+		Register(name string, fn func(*gorm.DB)) error
+	}
+	callback := db.Callback()
+	hooks := [][2]registerer{
+		{callback.Create().Before("*"), callback.Create().After("*")},
+		{callback.Query().Before("*"), callback.Query().After("*")},
+		{callback.Update().Before("*"), callback.Update().After("*")},
+		{callback.Delete().Before("*"), callback.Delete().After("*")},
+		{callback.Row().Before("*"), callback.Row().After("*")},
+		{callback.Raw().Before("*"), callback.Raw().After("*")},
+	}
+	for _, hook := range hooks {
+		if err := hook[0].Register("db_metrics:before", dbMetricsBefore); err != nil {
+			return err
+		}
+		if err := hook[1].Register("db_metrics:after", dbMetricsAfter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dbMetricsBefore(db *gorm.DB) {
+	db.InstanceSet(dbQueryStartKey, time.Now())
+}
+
+func dbMetricsAfter(db *gorm.DB) {
+	if db.Statement.Context == nil {
+		return
+	}
+	stats, ok := db.Statement.Context.Value(DBQueryStatsKey).(*DBQueryStats)
+	if !ok {
+		return
+	}
+	start, ok := db.InstanceGet(dbQueryStartKey)
+	if !ok {
+		return
+	}
+	stats.add(time.Since(start.(time.Time)))
+}
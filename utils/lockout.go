@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// 登录失败记录存储接口（可替换为内存或Redis实现）
+type LoginAttemptStore interface {
+	// 记录一次失败，返回窗口期内的累计失败次数
+	RecordFailure(key string, window time.Duration) (int, error)
+	// 锁定指定时长
+	Lock(key string, duration time.Duration) error
+	// 返回剩余锁定时长，未锁定时返回0
+	LockedFor(key string) (time.Duration, error)
+	// 清除失败记录和锁定状态
+	Reset(key string) error
+}
+
+// 内存版登录失败记录存储
+// 失败记录按用户名等任意键保存，定期清理窗口期和锁定均已结束的记录，避免内存无限增长
+type MemoryLoginAttemptStore struct {
+	mu        sync.Mutex
+	entries   map[string]*loginAttemptEntry
+	lastSweep time.Time
+}
+
+type loginAttemptEntry struct {
+	failures    int
+	windowStart time.Time
+	window      time.Duration
+	lockedUntil time.Time
+}
+
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{
+		entries:   make(map[string]*loginAttemptEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryLoginAttemptStore) RecordFailure(key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now, window)
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &loginAttemptEntry{windowStart: now}
+		s.entries[key] = entry
+	}
+	entry.window = window
+
+	// 超出窗口期则重新计数
+	if now.Sub(entry.windowStart) > window {
+		entry.failures = 0
+		entry.windowStart = now
+	}
+
+	entry.failures++
+	return entry.failures, nil
+}
+
+func (s *MemoryLoginAttemptStore) Lock(key string, duration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &loginAttemptEntry{windowStart: time.Now()}
+		s.entries[key] = entry
+	}
+	entry.failures = 0
+	entry.lockedUntil = time.Now().Add(duration)
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) LockedFor(key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return 0, nil
+	}
+
+	remaining := time.Until(entry.lockedUntil)
+	if remaining <= 0 {
+		return 0, nil
+	}
+	return remaining, nil
+}
+
+func (s *MemoryLoginAttemptStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// 每个窗口期清理一次窗口期已过且未处于锁定状态的记录
+func (s *MemoryLoginAttemptStore) sweep(now time.Time, window time.Duration) {
+	if now.Sub(s.lastSweep) < window {
+		return
+	}
+	for key, entry := range s.entries {
+		if now.Sub(entry.windowStart) > entry.window && !now.Before(entry.lockedUntil) {
+			delete(s.entries, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// 登录失败锁定器
+type LoginLimiter struct {
+	Store        LoginAttemptStore
+	MaxAttempts  int
+	Window       time.Duration
+	LockDuration time.Duration
+}
+
+func NewLoginLimiter(store LoginAttemptStore, maxAttempts int, window, lockDuration time.Duration) *LoginLimiter {
+	return &LoginLimiter{
+		Store:        store,
+		MaxAttempts:  maxAttempts,
+		Window:       window,
+		LockDuration: lockDuration,
+	}
+}
+
+// 检查是否处于锁定状态，返回剩余锁定时长
+func (l *LoginLimiter) Locked(key string) time.Duration {
+	if l.MaxAttempts <= 0 {
+		return 0
+	}
+	remaining, err := l.Store.LockedFor(key)
+	if err != nil {
+		return 0
+	}
+	return remaining
+}
+
+// 记录一次失败登录，达到上限时锁定并返回锁定时长
+func (l *LoginLimiter) Fail(key string) time.Duration {
+	if l.MaxAttempts <= 0 {
+		return 0
+	}
+	failures, err := l.Store.RecordFailure(key, l.Window)
+	if err != nil || failures < l.MaxAttempts {
+		return 0
+	}
+	if err := l.Store.Lock(key, l.LockDuration); err != nil {
+		return 0
+	}
+	return l.LockDuration
+}
+
+// 登录成功后清除失败记录
+func (l *LoginLimiter) Succeed(key string) {
+	l.Store.Reset(key)
+}
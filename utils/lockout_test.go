@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryLoginAttemptStoreSweepsExpiredEntries(t *testing.T) {
+	store := NewMemoryLoginAttemptStore()
+	window := time.Minute
+
+	for _, key := range []string{"expired", "locked", "active"} {
+		if _, err := store.RecordFailure(key, window); err != nil {
+			t.Fatalf("RecordFailure(%q): %v", key, err)
+		}
+	}
+	if err := store.Lock("locked", time.Hour); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+
+	// 模拟时间流逝：expired 和 locked 的窗口期已过，active 仍在窗口期内
+	past := time.Now().Add(-2 * window)
+	store.entries["expired"].windowStart = past
+	store.entries["locked"].windowStart = past
+	store.lastSweep = past
+
+	if _, err := store.RecordFailure("new", window); err != nil {
+		t.Fatalf("RecordFailure: %v", err)
+	}
+
+	if _, ok := store.entries["expired"]; ok {
+		t.Error("窗口期已过且未锁定的记录应被清理")
+	}
+	if _, ok := store.entries["locked"]; !ok {
+		t.Error("仍处于锁定状态的记录不应被清理")
+	}
+	if _, ok := store.entries["active"]; !ok {
+		t.Error("窗口期内的记录不应被清理")
+	}
+	if remaining, _ := store.LockedFor("locked"); remaining <= 0 {
+		t.Errorf("LockedFor(locked) = %v, want > 0", remaining)
+	}
+}
+
+func TestLoginLimiterLocksAfterMaxAttempts(t *testing.T) {
+	limiter := NewLoginLimiter(NewMemoryLoginAttemptStore(), 3, time.Minute, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if locked := limiter.Fail("alice"); locked != 0 {
+			t.Fatalf("第 %d 次失败后不应锁定，got %v", i+1, locked)
+		}
+	}
+	if locked := limiter.Fail("alice"); locked != time.Hour {
+		t.Fatalf("达到上限后应锁定 %v，got %v", time.Hour, locked)
+	}
+	if limiter.Locked("alice") <= 0 {
+		t.Fatal("锁定期内 Locked 应返回剩余时长")
+	}
+
+	limiter.Succeed("alice")
+	if limiter.Locked("alice") != 0 {
+		t.Fatal("登录成功后应清除锁定")
+	}
+}
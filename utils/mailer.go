@@ -0,0 +1,20 @@
+package utils
+
+import "log"
+
+// 邮件发送接口（可替换为SMTP或第三方邮件服务实现）
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// 日志版邮件发送器，只把邮件内容写入日志，仅适用于开发环境
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (m *LogMailer) Send(to, subject, body string) error {
+	log.Printf("发送邮件 to=%s subject=%q\n%s", to, subject, body)
+	return nil
+}
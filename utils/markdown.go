@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 轻量Markdown渲染，只支持标题、段落、列表、代码块及常用行内语法
+// 渲染前先整体转义HTML，原始文本中的标签（如 <script>）只会以文本形式输出，不会被执行
+
+var (
+	mdHeadingPattern     = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	mdUnorderedPattern   = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	mdOrderedPattern     = regexp.MustCompile(`^\d+[.)]\s+(.*)$`)
+	mdInlineCodePattern  = regexp.MustCompile("`([^`]+)`")
+	mdBoldPattern        = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	mdItalicPattern      = regexp.MustCompile(`\*([^*]+)\*`)
+	mdLinkPattern        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdAllowedLinkSchemes = []string{"http://", "https://", "mailto:"}
+)
+
+// 将Markdown渲染为安全的HTML
+func RenderMarkdown(source string) string {
+	var out strings.Builder
+	var paragraph []string
+	listTag := ""
+	inCode := false
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			out.WriteString("<p>" + renderInline(strings.Join(paragraph, " ")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if listTag != "" {
+			out.WriteString("</" + listTag + ">\n")
+			listTag = ""
+		}
+	}
+	openList := func(tag string) {
+		if listTag != tag {
+			closeList()
+			out.WriteString("<" + tag + ">\n")
+			listTag = tag
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		// 代码块内容原样转义输出
+		if strings.HasPrefix(trimmed, "```") {
+			if inCode {
+				out.WriteString("</code></pre>\n")
+			} else {
+				flushParagraph()
+				closeList()
+				out.WriteString("<pre><code>")
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			out.WriteString(html.EscapeString(line) + "\n")
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			flushParagraph()
+			closeList()
+		case mdHeadingPattern.MatchString(trimmed):
+			flushParagraph()
+			closeList()
+			m := mdHeadingPattern.FindStringSubmatch(trimmed)
+			level := string(rune('0' + len(m[1])))
+			out.WriteString("<h" + level + ">" + renderInline(m[2]) + "</h" + level + ">\n")
+		case mdUnorderedPattern.MatchString(trimmed):
+			flushParagraph()
+			openList("ul")
+			out.WriteString("<li>" + renderInline(mdUnorderedPattern.FindStringSubmatch(trimmed)[1]) + "</li>\n")
+		case mdOrderedPattern.MatchString(trimmed):
+			flushParagraph()
+			openList("ol")
+			out.WriteString("<li>" + renderInline(mdOrderedPattern.FindStringSubmatch(trimmed)[1]) + "</li>\n")
+		default:
+			closeList()
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+
+	if inCode {
+		out.WriteString("</code></pre>\n")
+	}
+	flushParagraph()
+	closeList()
+
+	return out.String()
+}
+
+// 渲染行内语法，输入先转义，链接只允许白名单协议
+func renderInline(text string) string {
+	text = html.EscapeString(text)
+
+	// 行内代码和链接先替换为占位符，避免其中内容被继续解析
+	var tokens []string
+	placeholder := func(rendered string) string {
+		tokens = append(tokens, rendered)
+		return "\x00" + strconv.Itoa(len(tokens)-1) + "\x00"
+	}
+
+	text = mdInlineCodePattern.ReplaceAllStringFunc(text, func(m string) string {
+		return placeholder("<code>" + mdInlineCodePattern.FindStringSubmatch(m)[1] + "</code>")
+	})
+	text = mdLinkPattern.ReplaceAllStringFunc(text, func(m string) string {
+		parts := mdLinkPattern.FindStringSubmatch(m)
+		href := html.UnescapeString(parts[2])
+		for _, scheme := range mdAllowedLinkSchemes {
+			if strings.HasPrefix(strings.ToLower(href), scheme) {
+				return placeholder(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener">` + parts[1] + "</a>")
+			}
+		}
+		return parts[1]
+	})
+	text = mdBoldPattern.ReplaceAllString(text, "<strong>$1</strong>")
+	text = mdItalicPattern.ReplaceAllString(text, "<em>$1</em>")
+
+	// 倒序还原，链接文本中可能包含行内代码的占位符
+	for i := len(tokens) - 1; i >= 0; i-- {
+		text = strings.Replace(text, "\x00"+strconv.Itoa(i)+"\x00", tokens[i], 1)
+	}
+	return text
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdownStripsScriptTags(t *testing.T) {
+	tests := []string{
+		"<script>alert(1)</script>",
+		"# 标题<script>alert(1)</script>",
+		"- <img src=x onerror=alert(1)>",
+		"**<script>alert(1)</script>**",
+		"```\n<script>alert(1)</script>\n```",
+	}
+	for _, source := range tests {
+		got := RenderMarkdown(source)
+		if strings.Contains(got, "<script") || strings.Contains(got, "<img") {
+			t.Errorf("RenderMarkdown(%q) = %q，包含未转义的标签", source, got)
+		}
+		if !strings.Contains(got, "&lt;") {
+			t.Errorf("RenderMarkdown(%q) = %q，标签应以文本形式输出", source, got)
+		}
+	}
+}
+
+func TestRenderMarkdownRejectsUnsafeLinks(t *testing.T) {
+	tests := map[string]string{
+		"[点我](javascript:alert%281%29)":              "<p>点我</p>\n",
+		"[点我](data:text/html,x)":                     "<p>点我</p>\n",
+		`[点我](https://x.com/"onmouseover="alert(1))`: `<p><a href="https://x.com/&#34;onmouseover=&#34;alert(1" rel="nofollow noopener">点我</a>)</p>` + "\n",
+	}
+	for source, want := range tests {
+		if got := RenderMarkdown(source); got != want {
+			t.Errorf("RenderMarkdown(%q) = %q, want %q", source, got, want)
+		}
+	}
+}
+
+func TestRenderMarkdownBasicSyntax(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"## 标题", "<h2>标题</h2>\n"},
+		{"第一行\n第二行\n\n第二段", "<p>第一行 第二行</p>\n<p>第二段</p>\n"},
+		{"**粗体** 和 *斜体* 和 `a*b*c`", "<p><strong>粗体</strong> 和 <em>斜体</em> 和 <code>a*b*c</code></p>\n"},
+		{"- a\n- b\n1. c", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n<ol>\n<li>c</li>\n</ol>\n"},
+		{"[文档](https://example.com/a?b=1&c=2)", `<p><a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener">文档</a></p>` + "\n"},
+		{"```\nif a < b {\n```", "<pre><code>if a &lt; b {\n</code></pre>\n"},
+	}
+	for _, tt := range tests {
+		if got := RenderMarkdown(tt.source); got != tt.want {
+			t.Errorf("RenderMarkdown(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+)
+
+// 密码策略，启动时由 SetPasswordPolicy 按配置覆盖
+type PasswordPolicy struct {
+	MinLength      int
+	RequireDigit   bool
+	RequireUpper   bool
+	RequireSpecial bool
+}
+
+// bcrypt 只使用前72字节，更长的密码直接拒绝
+const maxPasswordBytes = 72
+
+var passwordPolicy = PasswordPolicy{MinLength: 6}
+
+// 设置密码策略，最小长度小于1时按1处理
+func SetPasswordPolicy(policy PasswordPolicy) {
+	if policy.MinLength < 1 {
+		policy.MinLength = 1
+	}
+	passwordPolicy = policy
+}
+
+// 按密码策略校验密码，返回第一条不满足的规则
+func ValidatePassword(password string) error {
+	if len([]rune(password)) < passwordPolicy.MinLength {
+		return fmt.Errorf("密码长度不能少于%d个字符", passwordPolicy.MinLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("密码长度不能超过%d个字节", maxPasswordBytes)
+	}
+
+	var hasDigit, hasUpper, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			hasSpecial = true
+		}
+	}
+
+	if passwordPolicy.RequireDigit && !hasDigit {
+		return errors.New("密码必须包含数字")
+	}
+	if passwordPolicy.RequireUpper && !hasUpper {
+		return errors.New("密码必须包含大写字母")
+	}
+	if passwordPolicy.RequireSpecial && !hasSpecial {
+		return errors.New("密码必须包含特殊字符")
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidatePassword(t *testing.T) {
+	original := passwordPolicy
+	t.Cleanup(func() { passwordPolicy = original })
+
+	strict := PasswordPolicy{MinLength: 8, RequireDigit: true, RequireUpper: true, RequireSpecial: true}
+	tests := []struct {
+		name     string
+		policy   PasswordPolicy
+		password string
+		wantErr  string
+	}{
+		{"默认策略", PasswordPolicy{MinLength: 6}, "secret", ""},
+		{"长度不足", PasswordPolicy{MinLength: 6}, "short", "密码长度不能少于6个字符"},
+		{"按字符而非字节计算长度", PasswordPolicy{MinLength: 6}, "密码密码密码", ""},
+		{"超过bcrypt的72字节上限", PasswordPolicy{MinLength: 6}, strings.Repeat("a", 73), "密码长度不能超过72个字节"},
+		{"恰好72字节", PasswordPolicy{MinLength: 6}, strings.Repeat("a", 72), ""},
+		{"满足全部规则", strict, "Secret-123", ""},
+		{"缺少数字", strict, "Secret-abc", "密码必须包含数字"},
+		{"缺少大写字母", strict, "secret-123", "密码必须包含大写字母"},
+		{"缺少特殊字符", strict, "Secret1234", "密码必须包含特殊字符"},
+		{"空格不算特殊字符", strict, "Secret 123", "密码必须包含特殊字符"},
+		{"最小长度小于1时按1处理", PasswordPolicy{}, "", "密码长度不能少于1个字符"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetPasswordPolicy(tt.policy)
+			err := ValidatePassword(tt.password)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidatePassword(%q) = %v, want nil", tt.password, err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ValidatePassword(%q) = %v, want %q", tt.password, err, tt.wantErr)
+			}
+		})
+	}
+}
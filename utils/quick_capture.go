@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 快速记录解析出的任务信息，未出现的快捷标记对应字段为空
+type QuickTask struct {
+	Title    string
+	Category string     // #分类名
+	Priority string     // !low、!medium、!high、!urgent
+	DueDate  *time.Time // ^today、^tomorrow、^周几（如 ^mon）、^+3d、^2006-01-02，截止到当天结束
+}
+
+var quickWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// 解析快速记录的标题中的快捷标记，日期按 now 所在时区计算
+// 无法识别的标记按普通文字保留在标题中；同类标记出现多次时以最后一个为准
+func ParseQuickTask(input string, now time.Time) QuickTask {
+	var task QuickTask
+	var words []string
+	for _, word := range strings.Fields(input) {
+		switch {
+		case len(word) > 1 && word[0] == '#' && utf8.RuneCountInString(word[1:]) <= 50:
+			task.Category = word[1:]
+			continue
+		case len(word) > 1 && word[0] == '!' && IsValidTaskPriority(strings.ToLower(word[1:])):
+			task.Priority = strings.ToLower(word[1:])
+			continue
+		case len(word) > 1 && word[0] == '^':
+			if day, ok := parseQuickDate(strings.ToLower(word[1:]), now); ok {
+				_, dayEnd := DayRange(day)
+				due := dayEnd.Add(-time.Second)
+				task.DueDate = &due
+				continue
+			}
+		}
+		words = append(words, word)
+	}
+	task.Title = strings.Join(words, " ")
+	return task
+}
+
+// 解析 ^ 之后的日期，返回该日期所在的任意时刻
+func parseQuickDate(value string, now time.Time) (time.Time, bool) {
+	switch value {
+	case "today":
+		return now, true
+	case "tomorrow":
+		return now.AddDate(0, 0, 1), true
+	}
+
+	// 下一个指定的星期几（不含今天）
+	if weekday, ok := quickWeekdays[value]; ok {
+		days := (int(weekday) - int(now.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return now.AddDate(0, 0, days), true
+	}
+
+	// 相对天数，如 +3d
+	if strings.HasPrefix(value, "+") && strings.HasSuffix(value, "d") {
+		if n, err := strconv.Atoi(value[1 : len(value)-1]); err == nil && n >= 0 && n <= 3650 {
+			return now.AddDate(0, 0, n), true
+		}
+		return time.Time{}, false
+	}
+
+	if day, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return day, true
+	}
+	return time.Time{}, false
+}
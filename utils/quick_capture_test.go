@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseQuickTask(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	// 2024-03-13 是周三
+	now := time.Date(2024, 3, 13, 10, 0, 0, 0, shanghai)
+	endOf := func(year int, month time.Month, day int) *time.Time {
+		due := time.Date(year, month, day, 23, 59, 59, 0, shanghai)
+		return &due
+	}
+	tests := []struct {
+		input string
+		want  QuickTask
+	}{
+		{"写周报", QuickTask{Title: "写周报"}},
+		{"写周报 #工作 !high ^today", QuickTask{Title: "写周报", Category: "工作", Priority: "high", DueDate: endOf(2024, 3, 13)}},
+		{"!URGENT 修复线上问题", QuickTask{Title: "修复线上问题", Priority: "urgent"}},
+		{"买菜 ^tomorrow", QuickTask{Title: "买菜", DueDate: endOf(2024, 3, 14)}},
+		{"开会 ^fri", QuickTask{Title: "开会", DueDate: endOf(2024, 3, 15)}},
+		{"周会 ^wed", QuickTask{Title: "周会", DueDate: endOf(2024, 3, 20)}},
+		{"交房租 ^+3d", QuickTask{Title: "交房租", DueDate: endOf(2024, 3, 16)}},
+		{"续签合同 ^2024-04-01", QuickTask{Title: "续签合同", DueDate: endOf(2024, 4, 1)}},
+		{"同类标记以最后一个为准 #a #b !low !high", QuickTask{Title: "同类标记以最后一个为准", Category: "b", Priority: "high"}},
+		{"无法识别的标记保留 !later ^someday ^+3w # ^", QuickTask{Title: "无法识别的标记保留 !later ^someday ^+3w # ^"}},
+		{"超出范围的天数 ^+3651d", QuickTask{Title: "超出范围的天数 ^+3651d"}},
+		{"  多余的   空白  ", QuickTask{Title: "多余的 空白"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := ParseQuickTask(tt.input, now)
+			if got.Title != tt.want.Title || got.Category != tt.want.Category || got.Priority != tt.want.Priority {
+				t.Errorf("ParseQuickTask(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+			switch {
+			case got.DueDate == nil && tt.want.DueDate == nil:
+			case got.DueDate == nil || tt.want.DueDate == nil || !got.DueDate.Equal(*tt.want.DueDate):
+				t.Errorf("ParseQuickTask(%q).DueDate = %v, want %v", tt.input, got.DueDate, tt.want.DueDate)
+			}
+		})
+	}
+}
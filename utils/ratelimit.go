@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// 固定窗口限流器（内存实现）
+type RateLimiter struct {
+	mu        sync.Mutex
+	window    time.Duration
+	counters  map[string]*rateCounter
+	lastSweep time.Time
+}
+
+type rateCounter struct {
+	count   int
+	resetAt time.Time
+}
+
+func NewRateLimiter(window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		window:    window,
+		counters:  make(map[string]*rateCounter),
+		lastSweep: time.Now(),
+	}
+}
+
+// 记录一次请求，返回是否放行、剩余次数和窗口重置时间
+func (l *RateLimiter) Allow(key string, limit int) (bool, int, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	counter, ok := l.counters[key]
+	if !ok || !now.Before(counter.resetAt) {
+		counter = &rateCounter{resetAt: now.Add(l.window)}
+		l.counters[key] = counter
+	}
+
+	if counter.count >= limit {
+		return false, 0, counter.resetAt
+	}
+
+	counter.count++
+	return true, limit - counter.count, counter.resetAt
+}
+
+// 定期清理过期的计数器，避免内存无限增长
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	for key, counter := range l.counters {
+		if !now.Before(counter.resetAt) {
+			delete(l.counters, key)
+		}
+	}
+	l.lastSweep = now
+}
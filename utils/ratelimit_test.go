@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	limiter := NewRateLimiter(time.Minute)
+
+	for i, wantRemaining := range []int{1, 0} {
+		allowed, remaining, resetAt := limiter.Allow("user:1", 2)
+		if !allowed || remaining != wantRemaining {
+			t.Fatalf("第 %d 次请求 = %v, %d, want true, %d", i+1, allowed, remaining, wantRemaining)
+		}
+		if until := time.Until(resetAt); until <= 0 || until > time.Minute {
+			t.Errorf("resetAt 应在一个窗口期内，got %v", until)
+		}
+	}
+	if allowed, remaining, _ := limiter.Allow("user:1", 2); allowed || remaining != 0 {
+		t.Errorf("超出配额 = %v, %d, want false, 0", allowed, remaining)
+	}
+
+	// 不同的键分别计数
+	if allowed, _, _ := limiter.Allow("user:2", 2); !allowed {
+		t.Error("其他用户不应受影响")
+	}
+}
+
+func TestRateLimiterResetsAfterWindow(t *testing.T) {
+	limiter := NewRateLimiter(time.Minute)
+	limiter.Allow("ip:192.0.2.1", 1)
+	if allowed, _, _ := limiter.Allow("ip:192.0.2.1", 1); allowed {
+		t.Fatal("窗口期内超出配额应被拒绝")
+	}
+
+	// 模拟窗口期结束
+	limiter.counters["ip:192.0.2.1"].resetAt = time.Now().Add(-time.Second)
+
+	allowed, remaining, resetAt := limiter.Allow("ip:192.0.2.1", 1)
+	if !allowed || remaining != 0 {
+		t.Fatalf("新窗口的第一次请求 = %v, %d, want true, 0", allowed, remaining)
+	}
+	if !resetAt.After(time.Now()) {
+		t.Errorf("新窗口的重置时间应在当前时间之后，got %v", resetAt)
+	}
+}
+
+func TestRateLimiterSweepsExpiredCounters(t *testing.T) {
+	limiter := NewRateLimiter(time.Minute)
+	limiter.Allow("expired", 5)
+	limiter.Allow("active", 5)
+
+	// 模拟时间流逝：expired 的窗口期已过，且距上次清理已超过一个窗口期
+	limiter.counters["expired"].resetAt = time.Now().Add(-time.Second)
+	limiter.lastSweep = time.Now().Add(-2 * time.Minute)
+
+	limiter.Allow("new", 5)
+
+	if _, ok := limiter.counters["expired"]; ok {
+		t.Error("过期的计数器应被清理")
+	}
+	for _, key := range []string{"active", "new"} {
+		if _, ok := limiter.counters[key]; !ok {
+			t.Errorf("未过期的计数器 %s 不应被清理", key)
+		}
+	}
+}
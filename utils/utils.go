@@ -1,9 +1,16 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
 	"personaltask/models"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -33,9 +40,12 @@ func GenerateToken(userID uint, username, secretKey string, expiresIn int) (stri
 	return token.SignedString([]byte(secretKey))
 }
 
-// 密码加密
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// 密码加密，cost 超出 bcrypt 允许范围时返回错误
+func HashPassword(password string, cost int) (string, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return "", bcrypt.InvalidCostError(cost)
+	}
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), cost)
 	return string(bytes), err
 }
 
@@ -45,23 +55,129 @@ func CheckPassword(password, hash string) bool {
 	return err == nil
 }
 
+// 生成API密钥明文
+func GenerateAPIKey() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return "pt_" + hex.EncodeToString(bytes), nil
+}
+
+// 生成密码重置令牌明文
+func GenerateResetToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// 计算令牌哈希（API密钥、密码重置令牌等只保存哈希）
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// 计算API密钥哈希
+func HashAPIKey(key string) string {
+	return HashToken(key)
+}
+
+// 规范化用户名（去除首尾空白并转为小写），避免大小写不同的重复账户
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// 关键词搜索使用的排序规则，大小写和重音均不敏感（如 "cafe" 可匹配 "Café"）
+const SearchCollation = "utf8mb4_unicode_ci"
+
+// 生成 LIKE 包含匹配的模式，转义用户输入中的 %、_ 和反斜杠，使其按字面匹配
+func LikePattern(keyword string) string {
+	return "%" + escapeLike(keyword) + "%"
+}
+
+// 生成 LIKE 前缀匹配的模式（kw%），转义规则与 LikePattern 相同
+func LikePrefixPattern(keyword string) string {
+	return escapeLike(keyword) + "%"
+}
+
+func escapeLike(keyword string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(strings.TrimSpace(keyword))
+}
+
+// 关键词匹配方式：contains 标题或描述包含关键词（默认），prefix 标题以关键词开头，exact 标题与关键词完全相同
+var keywordMatchModes = []string{"contains", "prefix", "exact"}
+
+// 验证关键词匹配方式
+func IsValidKeywordMatch(match string) bool {
+	return Contains(keywordMatchModes, match)
+}
+
+// 规范化邮箱（去除首尾空白并转为小写）
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// 成功响应是否默认使用响应包装，启动时由 SetResponseEnvelope 按配置覆盖
+var responseEnvelope = true
+
+// 设置成功响应是否默认使用 {code, message, data, timestamp} 包装
+func SetResponseEnvelope(enabled bool) {
+	responseEnvelope = enabled
+}
+
+// 当前请求的成功响应是否使用包装：raw=true 时直接返回数据，raw=false 时强制包装，否则按默认设置
+// 错误响应始终使用包装
+func useResponseEnvelope(c *gin.Context) bool {
+	switch c.Query("raw") {
+	case "true":
+		return false
+	case "false":
+		return true
+	}
+	return responseEnvelope
+}
+
 // 成功响应
 func SuccessResponse(c *gin.Context, data interface{}) {
+	if !useResponseEnvelope(c) {
+		c.JSON(http.StatusOK, data)
+		return
+	}
+
 	response := models.Response{
 		Code:      http.StatusOK,
 		Message:   "success",
 		Data:      data,
-		Timestamp: time.Now(),
+		Timestamp: time.Now().UTC(),
 	}
 	c.JSON(http.StatusOK, response)
 }
 
+// 创建成功响应（201），Location 指向新建资源
+func CreatedResponse(c *gin.Context, location string, data interface{}) {
+	c.Header("Location", location)
+	if !useResponseEnvelope(c) {
+		c.JSON(http.StatusCreated, data)
+		return
+	}
+
+	response := models.Response{
+		Code:      http.StatusCreated,
+		Message:   "success",
+		Data:      data,
+		Timestamp: time.Now().UTC(),
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
 // 错误响应
 func ErrorResponse(c *gin.Context, code int, message string, err interface{}) {
 	response := models.Response{
 		Code:      code,
 		Message:   message,
-		Timestamp: time.Now(),
+		Timestamp: time.Now().UTC(),
 	}
 
 	if err != nil {
@@ -85,11 +201,41 @@ func PaginatedResponse(c *gin.Context, items interface{}, total int64, page, pag
 		Page:       page,
 		PageSize:   pageSize,
 		TotalPages: totalPages,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
+	}
+
+	if data.HasNext {
+		next := page + 1
+		data.NextPage = &next
+	}
+	if data.HasPrev {
+		prev := page - 1
+		data.PrevPage = &prev
 	}
 
 	SuccessResponse(c, data)
 }
 
+// 分页默认每页条数和上限，启动时由 SetPaginationLimits 按配置覆盖
+var (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// 设置分页默认每页条数和上限，非法值保留原设置
+func SetPaginationLimits(defaultSize, maxSize int) {
+	if maxSize > 0 {
+		maxPageSize = maxSize
+	}
+	if defaultSize > 0 {
+		defaultPageSize = defaultSize
+	}
+	if defaultPageSize > maxPageSize {
+		defaultPageSize = maxPageSize
+	}
+}
+
 // 获取分页参数
 func GetPaginationParams(c *gin.Context) (int, int, int) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -97,9 +243,11 @@ func GetPaginationParams(c *gin.Context) (int, int, int) {
 		page = 1
 	}
 
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10
+	pageSize, err := strconv.Atoi(c.Query("page_size"))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultPageSize
+	} else if pageSize > maxPageSize {
+		pageSize = maxPageSize
 	}
 
 	offset := (page - 1) * pageSize
@@ -107,6 +255,59 @@ func GetPaginationParams(c *gin.Context) (int, int, int) {
 	return page, pageSize, offset
 }
 
+// 解析路径中的资源ID，格式非法时返回400并中止请求
+func ParseID(c *gin.Context, param string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil || id == 0 {
+		ErrorResponse(c, http.StatusBadRequest, "无效的资源ID", err)
+		c.Abort()
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// 解析日期参数，支持 YYYY-MM-DD（按UTC零点）和 RFC3339 两种格式，dateOnly 表示是否只精确到日
+func ParseDate(value string) (t time.Time, dateOnly bool, err error) {
+	if t, err = time.ParseInLocation("2006-01-02", value, time.UTC); err == nil {
+		return t, true, nil
+	}
+	if t, err = time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("日期格式无效，应为 YYYY-MM-DD 或 RFC3339: %s", value)
+}
+
+// 解析查询参数中的日期，参数为空时返回 nil，格式非法时返回400并中止请求
+func ParseDateParam(c *gin.Context, key string) (*time.Time, bool) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, true
+	}
+	t, _, err := ParseDate(value)
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, key+" 日期格式无效，应为 YYYY-MM-DD 或 RFC3339", nil)
+		c.Abort()
+		return nil, false
+	}
+	return &t, true
+}
+
+// 校验排序参数：order_by 必须是 fields 之一，order_dir 必须是 asc 或 desc（为空时由调用方使用默认值）
+// 排序参数会拼接进SQL，不合法时返回400并中止请求
+func ValidateOrderParams(c *gin.Context, fields []string) bool {
+	if orderBy := c.Query("order_by"); orderBy != "" && !Contains(fields, orderBy) {
+		ErrorResponse(c, http.StatusBadRequest, "order_by 参数无效，可选值: "+strings.Join(fields, ", "), nil)
+		c.Abort()
+		return false
+	}
+	if orderDir := c.Query("order_dir"); orderDir != "" && orderDir != "asc" && orderDir != "desc" {
+		ErrorResponse(c, http.StatusBadRequest, "order_dir 参数无效，应为 asc 或 desc", nil)
+		c.Abort()
+		return false
+	}
+	return true
+}
+
 // 获取用户ID
 func GetUserID(c *gin.Context) uint {
 	userID, exists := c.Get("user_id")
@@ -116,6 +317,17 @@ func GetUserID(c *gin.Context) uint {
 	return userID.(uint)
 }
 
+// 获取已认证用户的ID，未认证时返回401并中止请求，避免以 user_id = 0 查询出空结果
+func RequireUserID(c *gin.Context) (uint, bool) {
+	userID := GetUserID(c)
+	if userID == 0 {
+		ErrorResponse(c, http.StatusUnauthorized, "用户未登录", nil)
+		c.Abort()
+		return 0, false
+	}
+	return userID, true
+}
+
 // 获取当前用户
 func GetCurrentUser(c *gin.Context) (models.User, bool) {
 	user, exists := c.Get("current_user")
@@ -125,15 +337,115 @@ func GetCurrentUser(c *gin.Context) (models.User, bool) {
 	return user.(models.User), true
 }
 
-// 验证任务状态
-func IsValidTaskStatus(status string) bool {
-	validStatuses := []string{"pending", "in_progress", "completed"}
-	for _, v := range validStatuses {
-		if v == status {
-			return true
+// 任务状态集合，第一个为新建任务的初始状态；启动时由 SetTaskStatuses 按配置覆盖
+var (
+	taskStatuses        = []string{"pending", "in_progress", "completed"}
+	completedTaskStatus = "completed"
+)
+
+var taskStatusPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,19}$`)
+
+// 校验任务状态集合：状态名为小写字母、数字和下划线（不超过20个字符），不能重复，且包含完成状态
+func ValidateTaskStatuses(statuses []string, completed string) error {
+	if len(statuses) < 2 {
+		return errors.New("任务状态至少需要两个")
+	}
+	seen := make(map[string]bool, len(statuses))
+	for _, status := range statuses {
+		if !taskStatusPattern.MatchString(status) {
+			return fmt.Errorf("任务状态 %q 格式无效", status)
+		}
+		if seen[status] {
+			return fmt.Errorf("任务状态 %q 重复", status)
 		}
+		seen[status] = true
 	}
-	return false
+	if !seen[completed] {
+		return fmt.Errorf("完成状态 %q 不在任务状态列表中", completed)
+	}
+	if statuses[0] == completed {
+		return errors.New("初始状态不能是完成状态")
+	}
+	return nil
+}
+
+// 设置任务状态集合及其中的完成状态，校验失败时保留原设置
+func SetTaskStatuses(statuses []string, completed string) error {
+	if err := ValidateTaskStatuses(statuses, completed); err != nil {
+		return err
+	}
+	taskStatuses = append([]string(nil), statuses...)
+	completedTaskStatus = completed
+	return nil
+}
+
+// 允许的任务状态
+func TaskStatuses() []string {
+	return taskStatuses
+}
+
+// 新建任务的初始状态
+func InitialTaskStatus() string {
+	return taskStatuses[0]
+}
+
+// 表示任务已完成的状态，completed_at 随该状态设置或清除
+func CompletedTaskStatus() string {
+	return completedTaskStatus
+}
+
+// 允许的任务状态流转（from -> to 集合），为nil时不限制；启动时由 SetTaskTransitions 按配置设置
+var taskTransitions map[string]map[string]bool
+
+// 解析任务状态流转配置，每项格式为 "from>to"，状态必须在 statuses 中；列表为空表示不限制
+func ParseTaskTransitions(entries []string, statuses []string) (map[string]map[string]bool, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	transitions := make(map[string]map[string]bool)
+	for _, entry := range entries {
+		parts := strings.Split(entry, ">")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("状态流转 %q 格式无效，应为 from>to", entry)
+		}
+		from, to := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if !Contains(statuses, from) || !Contains(statuses, to) {
+			return nil, fmt.Errorf("状态流转 %q 包含未知状态", entry)
+		}
+		if transitions[from] == nil {
+			transitions[from] = make(map[string]bool)
+		}
+		transitions[from][to] = true
+	}
+	return transitions, nil
+}
+
+// 按当前任务状态集合设置状态流转规则，校验失败时保留原设置
+func SetTaskTransitions(entries []string) error {
+	transitions, err := ParseTaskTransitions(entries, taskStatuses)
+	if err != nil {
+		return err
+	}
+	taskTransitions = transitions
+	return nil
+}
+
+// 判断任务能否从 from 变更为 to
+// 未配置流转规则时不限制；从完成状态重新打开任务需显式指定 reopen，此时不受流转规则限制
+func CanTransitionTask(from, to string, reopen bool) bool {
+	if from == to || taskTransitions == nil {
+		return true
+	}
+	if from == completedTaskStatus && reopen {
+		return true
+	}
+	return taskTransitions[from][to]
+}
+
+// 验证任务状态
+func IsValidTaskStatus(status string) bool {
+	return Contains(taskStatuses, status)
 }
 
 // 验证任务优先级
@@ -147,6 +459,52 @@ func IsValidTaskPriority(priority string) bool {
 	return false
 }
 
+// 分类默认颜色调色板，未指定颜色时按顺序分配
+var CategoryColorPalette = []string{
+	"#007bff", "#28a745", "#dc3545", "#ffc107", "#17a2b8",
+	"#6f42c1", "#fd7e14", "#20c997", "#e83e8c", "#6c757d",
+}
+
+// 从调色板中选出使用次数最少的颜色（次数相同时按调色板顺序），全部用过后循环分配
+func NextPaletteColor(usedColors []string) string {
+	counts := make(map[string]int, len(usedColors))
+	for _, color := range usedColors {
+		counts[strings.ToLower(color)]++
+	}
+
+	next := CategoryColorPalette[0]
+	for _, color := range CategoryColorPalette[1:] {
+		if counts[color] < counts[next] {
+			next = color
+		}
+	}
+	return next
+}
+
+// 任务优先级权重，数值越大越紧急（枚举按字母排序不能反映紧急程度）
+var TaskPriorityWeights = map[string]int{
+	"urgent": 4,
+	"high":   3,
+	"medium": 2,
+	"low":    1,
+}
+
+// 获取任务优先级权重，未知优先级返回0
+func PriorityWeight(priority string) int {
+	return TaskPriorityWeights[priority]
+}
+
+// 按优先级权重排序用的SQL表达式，用于 ORDER BY 时代替按字母排序的枚举值
+func PriorityWeightSQL() string {
+	var sb strings.Builder
+	sb.WriteString("CASE priority")
+	for _, priority := range []string{"urgent", "high", "medium", "low"} {
+		fmt.Fprintf(&sb, " WHEN '%s' THEN %d", priority, PriorityWeight(priority))
+	}
+	sb.WriteString(" ELSE 0 END")
+	return sb.String()
+}
+
 // 验证项目状态
 func IsValidProjectStatus(status string) bool {
 	validStatuses := []string{"active", "completed", "archived"}
@@ -195,6 +553,38 @@ func FormatDate(t *time.Time) string {
 	return t.Format("2006-01-02")
 }
 
+// 解析时长，在标准格式基础上支持以天为单位（如 "1d"、"2d12h"）
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+
+	var days time.Duration
+	if idx := strings.Index(value, "d"); idx >= 0 {
+		n, err := strconv.Atoi(value[:idx])
+		if err != nil {
+			return 0, fmt.Errorf("无效的时长: %s", value)
+		}
+		days = time.Duration(n) * 24 * time.Hour
+		value = value[idx+1:]
+	}
+
+	var rest time.Duration
+	if value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("无效的时长: %s", value)
+		}
+		rest = parsed
+	}
+
+	total := days + rest
+	if negative {
+		total = -total
+	}
+	return total, nil
+}
+
 // 安全的整数转换
 func SafeIntConvert(value string) (int, error) {
 	if value == "" {
@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"personaltask/models"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"12h", 12 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"1d", 24 * time.Hour, false},
+		{"2d12h", 60 * time.Hour, false},
+		{" 3d ", 72 * time.Hour, false},
+		{"-1d", -24 * time.Hour, false},
+		{"-1d6h", -30 * time.Hour, false},
+		{"d", 0, true},
+		{"xd", 0, true},
+		{"1w", 0, true},
+		{"1d2", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseDuration(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDuration(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDuration(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextPaletteColor(t *testing.T) {
+	tests := []struct {
+		name string
+		used []string
+		want string
+	}{
+		{"没有已用颜色", nil, "#007bff"},
+		{"跳过已用颜色", []string{"#007bff", "#28a745"}, "#dc3545"},
+		{"忽略大小写", []string{"#007BFF"}, "#28a745"},
+		{"忽略调色板外的颜色", []string{"#123456", "#007bff"}, "#28a745"},
+		{"全部用过后循环分配", CategoryColorPalette, "#007bff"},
+		{"选出使用次数最少的颜色", append(append([]string{}, CategoryColorPalette...), "#007bff", "#28a745", "#dc3545"), "#ffc107"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NextPaletteColor(tt.used); got != tt.want {
+				t.Errorf("NextPaletteColor(%v) = %s, want %s", tt.used, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginatedResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	intPtr := func(v int) *int { return &v }
+	tests := []struct {
+		name           string
+		total          int64
+		page, pageSize int
+		wantPages      int
+		wantNext       *int
+		wantPrev       *int
+	}{
+		{"没有数据", 0, 1, 10, 0, nil, nil},
+		{"只有一页", 10, 1, 10, 1, nil, nil},
+		{"第一页", 25, 1, 10, 3, intPtr(2), nil},
+		{"中间页", 25, 2, 10, 3, intPtr(3), intPtr(1)},
+		{"最后一页", 25, 3, 10, 3, nil, intPtr(2)},
+		{"恰好整页", 20, 2, 10, 2, nil, intPtr(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/tasks?raw=true", nil)
+			PaginatedResponse(c, []int{}, tt.total, tt.page, tt.pageSize)
+
+			var got models.PaginatedResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("解析响应失败: %v", err)
+			}
+			if got.Total != tt.total || got.Page != tt.page || got.PageSize != tt.pageSize || got.TotalPages != tt.wantPages {
+				t.Errorf("total/page/page_size/total_pages = %d/%d/%d/%d, want %d/%d/%d/%d",
+					got.Total, got.Page, got.PageSize, got.TotalPages, tt.total, tt.page, tt.pageSize, tt.wantPages)
+			}
+			if got.HasNext != (tt.wantNext != nil) || !reflect.DeepEqual(got.NextPage, tt.wantNext) {
+				t.Errorf("has_next = %v, next_page = %v, want %v", got.HasNext, got.NextPage, tt.wantNext)
+			}
+			if got.HasPrev != (tt.wantPrev != nil) || !reflect.DeepEqual(got.PrevPage, tt.wantPrev) {
+				t.Errorf("has_prev = %v, prev_page = %v, want %v", got.HasPrev, got.PrevPage, tt.wantPrev)
+			}
+		})
+	}
+}
+
+func TestLikePatterns(t *testing.T) {
+	tests := []struct {
+		keyword      string
+		wantContains string
+		wantPrefix   string
+	}{
+		{"报告", "%报告%", "报告%"},
+		{"  周报 ", "%周报%", "周报%"},
+		{"100%", `%100\%%`, `100\%%`},
+		{"a_b", `%a\_b%`, `a\_b%`},
+		{`C:\temp`, `%C:\\temp%`, `C:\\temp%`},
+		{`\%_`, `%\\\%\_%`, `\\\%\_%`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keyword, func(t *testing.T) {
+			if got := LikePattern(tt.keyword); got != tt.wantContains {
+				t.Errorf("LikePattern(%q) = %q, want %q", tt.keyword, got, tt.wantContains)
+			}
+			if got := LikePrefixPattern(tt.keyword); got != tt.wantPrefix {
+				t.Errorf("LikePrefixPattern(%q) = %q, want %q", tt.keyword, got, tt.wantPrefix)
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value        string
+		want         time.Time
+		wantDateOnly bool
+		wantErr      bool
+	}{
+		{"2024-03-15", time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), true, false},
+		{"2024-03-15T18:30:00Z", time.Date(2024, 3, 15, 18, 30, 0, 0, time.UTC), false, false},
+		{"2024-03-15T18:30:00+08:00", time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC), false, false},
+		{"2024-02-30", time.Time{}, false, true},
+		{"2024/03/15", time.Time{}, false, true},
+		{"2024-03-15 18:30:00", time.Time{}, false, true},
+		{"", time.Time{}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, dateOnly, err := ParseDate(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDate(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) || got.Location() != time.UTC || dateOnly != tt.wantDateOnly {
+				t.Errorf("ParseDate(%q) = %v, %v, want %v, %v", tt.value, got, dateOnly, tt.want, tt.wantDateOnly)
+			}
+		})
+	}
+}
+
+func TestParseDateParam(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		query    string
+		want     *time.Time
+		wantOK   bool
+		wantCode int
+	}{
+		{"", nil, true, http.StatusOK},
+		{"due_before=2024-03-15", func() *time.Time { d := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC); return &d }(), true, http.StatusOK},
+		{"due_before=tomorrow", nil, false, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/tasks?"+tt.query, nil)
+
+			got, ok := ParseDateParam(c, "due_before")
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDateParam = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+			if w.Code != tt.wantCode || c.IsAborted() == tt.wantOK {
+				t.Errorf("status = %d, aborted = %v, want %d, %v", w.Code, c.IsAborted(), tt.wantCode, !tt.wantOK)
+			}
+		})
+	}
+}
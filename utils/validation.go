@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"personaltask/models"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// 校验错误中使用json字段名而不是结构体字段名
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" || name == "" {
+				return field.Name
+			}
+			return name
+		})
+		// 任务状态集合可配置，不能写死在 oneof 中
+		v.RegisterValidation("task_status", func(fl validator.FieldLevel) bool {
+			return IsValidTaskStatus(fl.Field().String())
+		})
+	}
+}
+
+// 将校验错误转换为 字段 -> 可读提示 的映射，非校验错误返回nil
+func ValidationMessages(err error) map[string]string {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil
+	}
+
+	messages := make(map[string]string, len(validationErrors))
+	for _, fe := range validationErrors {
+		messages[fe.Field()] = validationMessage(fe)
+	}
+	return messages
+}
+
+// 单个字段的校验提示
+func validationMessage(fe validator.FieldError) string {
+	field := fe.Field()
+	isString := fe.Kind() == reflect.String
+
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s 为必填项", field)
+	case "min":
+		if isString {
+			return fmt.Sprintf("%s 长度不能少于%s个字符", field, fe.Param())
+		}
+		return fmt.Sprintf("%s 不能小于%s", field, fe.Param())
+	case "max":
+		if isString {
+			return fmt.Sprintf("%s 长度不能超过%s个字符", field, fe.Param())
+		}
+		return fmt.Sprintf("%s 不能大于%s", field, fe.Param())
+	case "len":
+		return fmt.Sprintf("%s 长度必须为%s个字符", field, fe.Param())
+	case "email":
+		return fmt.Sprintf("%s 必须是有效的邮箱地址", field)
+	case "oneof":
+		return fmt.Sprintf("%s 必须是以下值之一: %s", field, strings.Join(strings.Fields(fe.Param()), ", "))
+	case "hexcolor":
+		return fmt.Sprintf("%s 必须是 #RRGGBB 格式的颜色值", field)
+	case "task_status":
+		return fmt.Sprintf("%s 必须是以下值之一: %s", field, strings.Join(TaskStatuses(), ", "))
+	default:
+		return fmt.Sprintf("%s 校验失败（%s）", field, fe.Tag())
+	}
+}
+
+// 请求绑定失败响应，校验错误按字段返回可读提示
+func BindErrorResponse(c *gin.Context, err error) {
+	response := models.Response{
+		Code:      http.StatusBadRequest,
+		Message:   "请求参数错误",
+		Timestamp: time.Now().UTC(),
+	}
+
+	if messages := ValidationMessages(err); messages != nil {
+		response.Error = "参数校验失败"
+		response.Data = messages
+	} else if err != nil {
+		response.Error = err.Error()
+	}
+
+	c.JSON(http.StatusBadRequest, response)
+}
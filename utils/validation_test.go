@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+type validationTestRequest struct {
+	Title    string `json:"title" binding:"required,max=5"`
+	Priority string `json:"priority" binding:"omitempty,oneof=low high"`
+	Count    int    `json:"count" binding:"min=1"`
+	Code     string `json:"code" binding:"omitempty,len=4"`
+	Email    string `json:"email" binding:"omitempty,email"`
+	Color    string `json:"color" binding:"omitempty,hexcolor"`
+	Status   string `json:"status" binding:"omitempty,task_status"`
+	Untagged string `binding:"omitempty,min=2"`
+}
+
+func TestValidationMessages(t *testing.T) {
+	valid := validationTestRequest{Title: "标题", Count: 1}
+	tests := []struct {
+		name string
+		req  func(r *validationTestRequest)
+		want map[string]string
+	}{
+		{"必填", func(r *validationTestRequest) { r.Title = "" }, map[string]string{"title": "title 为必填项"}},
+		{"字符串最大长度", func(r *validationTestRequest) { r.Title = "超过五个字符了" }, map[string]string{"title": "title 长度不能超过5个字符"}},
+		{"数值最小值", func(r *validationTestRequest) { r.Count = 0 }, map[string]string{"count": "count 不能小于1"}},
+		{"固定长度", func(r *validationTestRequest) { r.Code = "123" }, map[string]string{"code": "code 长度必须为4个字符"}},
+		{"可选值", func(r *validationTestRequest) { r.Priority = "urgent" }, map[string]string{"priority": "priority 必须是以下值之一: low, high"}},
+		{"邮箱", func(r *validationTestRequest) { r.Email = "alice" }, map[string]string{"email": "email 必须是有效的邮箱地址"}},
+		{"颜色", func(r *validationTestRequest) { r.Color = "red" }, map[string]string{"color": "color 必须是 #RRGGBB 格式的颜色值"}},
+		{"任务状态", func(r *validationTestRequest) { r.Status = "done" }, map[string]string{"status": "status 必须是以下值之一: pending, in_progress, completed"}},
+		{"没有json标签时使用字段名", func(r *validationTestRequest) { r.Untagged = "a" }, map[string]string{"Untagged": "Untagged 长度不能少于2个字符"}},
+		{"多个字段", func(r *validationTestRequest) { r.Title, r.Count = "", 0 }, map[string]string{"title": "title 为必填项", "count": "count 不能小于1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.req(&req)
+			err := binding.Validator.ValidateStruct(&req)
+			if got := ValidationMessages(err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidationMessages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := ValidationMessages(errors.New("unexpected EOF")); got != nil {
+		t.Errorf("非校验错误应返回nil，got %v", got)
+	}
+}
+
+func TestBindErrorResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name      string
+		err       error
+		wantError string
+		wantData  map[string]string
+	}{
+		{"校验错误按字段返回", binding.Validator.ValidateStruct(&validationTestRequest{Count: 1}), "参数校验失败", map[string]string{"title": "title 为必填项"}},
+		{"其他错误返回错误信息", errors.New("unexpected EOF"), "unexpected EOF", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			BindErrorResponse(c, tt.err)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+			var resp struct {
+				Code  int               `json:"code"`
+				Error string            `json:"error"`
+				Data  map[string]string `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("解析响应失败: %v", err)
+			}
+			if resp.Code != http.StatusBadRequest || resp.Error != tt.wantError {
+				t.Errorf("code = %d, error = %q, want %d, %q", resp.Code, resp.Error, http.StatusBadRequest, tt.wantError)
+			}
+			if !reflect.DeepEqual(resp.Data, tt.wantData) {
+				t.Errorf("data = %v, want %v", resp.Data, tt.wantData)
+			}
+		})
+	}
+}